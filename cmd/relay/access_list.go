@@ -0,0 +1,199 @@
+package main
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/jmoiron/sqlx"
+
+	"github.com/connorslagle/nark-archival/internal/policies"
+)
+
+// PostgreSQLAccessList stores allowlist and blocklist entries in PostgreSQL.
+// Entries are read on every check, so edits apply without a restart.
+type PostgreSQLAccessList struct {
+	db *sqlx.DB
+}
+
+// NewPostgreSQLAccessList creates an access list store
+func NewPostgreSQLAccessList(db *sqlx.DB) *PostgreSQLAccessList {
+	return &PostgreSQLAccessList{db: db}
+}
+
+// Init creates the access list table
+func (al *PostgreSQLAccessList) Init(ctx context.Context) error {
+	_, err := al.db.ExecContext(ctx, `
+		CREATE TABLE IF NOT EXISTS access_list (
+			list TEXT NOT NULL CHECK (list IN ('allow', 'block')),
+			pubkey TEXT NOT NULL,
+			kind INTEGER NOT NULL DEFAULT 0,
+			reason TEXT NOT NULL DEFAULT '',
+			expires_at TIMESTAMPTZ,
+			created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+			PRIMARY KEY (list, pubkey, kind)
+		)
+	`)
+	if err != nil {
+		return err
+	}
+
+	_, err = al.db.ExecContext(ctx, `
+		CREATE INDEX IF NOT EXISTS idx_access_list_pubkey ON access_list(pubkey)
+	`)
+	return err
+}
+
+// Entries returns the unexpired entries for a pubkey
+func (al *PostgreSQLAccessList) Entries(ctx context.Context, pubkey string) ([]policies.AccessEntry, error) {
+	var entries []policies.AccessEntry
+	err := al.db.SelectContext(ctx, &entries, `
+		SELECT list, pubkey, kind, reason, expires_at, created_at FROM access_list
+		WHERE pubkey = $1 AND (expires_at IS NULL OR expires_at > now())
+	`, pubkey)
+	return entries, err
+}
+
+// List returns the unexpired entries on one list
+func (al *PostgreSQLAccessList) List(ctx context.Context, list string) ([]policies.AccessEntry, error) {
+	var entries []policies.AccessEntry
+	err := al.db.SelectContext(ctx, &entries, `
+		SELECT list, pubkey, kind, reason, expires_at, created_at FROM access_list
+		WHERE list = $1 AND (expires_at IS NULL OR expires_at > now())
+		ORDER BY created_at
+	`, list)
+	return entries, err
+}
+
+// Add creates or replaces an entry
+func (al *PostgreSQLAccessList) Add(ctx context.Context, entry policies.AccessEntry) error {
+	if err := entry.Validate(); err != nil {
+		return err
+	}
+	_, err := al.db.ExecContext(ctx, `
+		INSERT INTO access_list (list, pubkey, kind, reason, expires_at) VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (list, pubkey, kind) DO UPDATE
+		SET reason = EXCLUDED.reason, expires_at = EXCLUDED.expires_at, created_at = now()
+	`, entry.List, entry.Pubkey, entry.Kind, entry.Reason, entry.ExpiresAt)
+	return err
+}
+
+// Remove deletes an entry
+func (al *PostgreSQLAccessList) Remove(ctx context.Context, list, pubkey string, kind int) error {
+	_, err := al.db.ExecContext(ctx,
+		"DELETE FROM access_list WHERE list = $1 AND pubkey = $2 AND kind = $3",
+		list, pubkey, kind)
+	return err
+}
+
+// requireAdminToken only lets requests carrying the ADMIN_TOKEN bearer token
+// through; admin endpoints are disabled when no token is configured
+func requireAdminToken(token string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if token == "" {
+			http.Error(w, "admin endpoints are disabled: set ADMIN_TOKEN to enable them", http.StatusNotFound)
+			return
+		}
+		provided := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if subtle.ConstantTimeCompare([]byte(provided), []byte(token)) != 1 {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		next(w, r)
+	}
+}
+
+// adminTokenActor identifies changes made with ADMIN_TOKEN in the audit log,
+// where NIP-86 calls are identified by the admin's pubkey
+const adminTokenActor = "admin_token"
+
+// accessListHandler manages the access lists:
+// GET ?list=allow|block lists entries, POST adds the JSON entry in the body,
+// DELETE ?list=&pubkey=&kind= removes an entry. Changes are recorded in the
+// audit log like management API calls.
+func accessListHandler(store policies.AccessListStore, moderation *PostgreSQLModeration) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+
+		// audit records a change and its outcome under the request's method
+		audit := func(params interface{}, actionErr error) {
+			encoded, _ := json.Marshal(params)
+			if err := moderation.Audit(r.Context(), adminTokenActor, r.Method+" /admin/access-list", encoded, actionErr); err != nil {
+				log.Printf("Audit error: %v", err)
+			}
+		}
+
+		switch r.Method {
+		case http.MethodGet:
+			entries, err := store.List(r.Context(), query.Get("list"))
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			if entries == nil {
+				entries = []policies.AccessEntry{}
+			}
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(entries)
+
+		case http.MethodPost:
+			var entry policies.AccessEntry
+			if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 65536)).Decode(&entry); err != nil {
+				err = fmt.Errorf("invalid entry JSON: %w", err)
+				audit([]interface{}{}, err)
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			if pubkey, ok := normalizePubkey(entry.Pubkey); ok {
+				entry.Pubkey = pubkey
+			}
+			if err := entry.Validate(); err != nil {
+				audit([]interface{}{entry}, err)
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			err := store.Add(r.Context(), entry)
+			audit([]interface{}{entry}, err)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			w.WriteHeader(http.StatusNoContent)
+
+		case http.MethodDelete:
+			params := []interface{}{query.Get("list"), query.Get("pubkey"), query.Get("kind")}
+			pubkey, ok := normalizePubkey(query.Get("pubkey"))
+			if !ok {
+				audit(params, errors.New("pubkey must be hex or npub"))
+				http.Error(w, "pubkey must be hex or npub", http.StatusBadRequest)
+				return
+			}
+			kind := policies.AllKinds
+			if k := query.Get("kind"); k != "" {
+				var err error
+				if kind, err = strconv.Atoi(k); err != nil {
+					audit(params, errors.New("kind must be a number"))
+					http.Error(w, "kind must be a number", http.StatusBadRequest)
+					return
+				}
+			}
+			err := store.Remove(r.Context(), query.Get("list"), pubkey, kind)
+			audit(params, err)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			w.WriteHeader(http.StatusNoContent)
+
+		default:
+			w.Header().Set("Allow", "GET, POST, DELETE")
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		}
+	}
+}
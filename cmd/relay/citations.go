@@ -0,0 +1,107 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/nbd-wtf/go-nostr"
+
+	"github.com/connorslagle/nark-archival/internal/policies"
+)
+
+// PostgreSQLCitations records what each accepted citation resolved to
+type PostgreSQLCitations struct {
+	db *sqlx.DB
+}
+
+// NewPostgreSQLCitations creates a citation store
+func NewPostgreSQLCitations(db *sqlx.DB) *PostgreSQLCitations {
+	return &PostgreSQLCitations{db: db}
+}
+
+// Init creates the citations table
+func (cs *PostgreSQLCitations) Init(ctx context.Context) error {
+	_, err := cs.db.ExecContext(ctx, `
+		CREATE TABLE IF NOT EXISTS citations (
+			citation_id TEXT PRIMARY KEY,
+			status TEXT NOT NULL,
+			target_id TEXT NOT NULL DEFAULT '',
+			target_kind INTEGER NOT NULL DEFAULT 0,
+			doi TEXT NOT NULL DEFAULT '',
+			arxiv TEXT NOT NULL DEFAULT '',
+			resolved_at TIMESTAMPTZ NOT NULL
+		)
+	`)
+	if err != nil {
+		return err
+	}
+
+	_, err = cs.db.ExecContext(ctx, `
+		CREATE INDEX IF NOT EXISTS idx_citations_target ON citations(target_id)
+	`)
+	return err
+}
+
+// RecordCitation stores a resolution, replacing any earlier one
+func (cs *PostgreSQLCitations) RecordCitation(ctx context.Context, resolution policies.CitationResolution) error {
+	_, err := cs.db.ExecContext(ctx, `
+		INSERT INTO citations (citation_id, status, target_id, target_kind, doi, arxiv, resolved_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		ON CONFLICT (citation_id) DO UPDATE
+		SET status = EXCLUDED.status, target_id = EXCLUDED.target_id, target_kind = EXCLUDED.target_kind,
+			doi = EXCLUDED.doi, arxiv = EXCLUDED.arxiv, resolved_at = EXCLUDED.resolved_at
+	`, resolution.CitationID, resolution.Status, resolution.TargetID, resolution.TargetKind,
+		resolution.DOI, resolution.ArXiv, resolution.ResolvedAt)
+	return err
+}
+
+// GetCitation returns the resolution recorded for a citation, or nil
+func (cs *PostgreSQLCitations) GetCitation(ctx context.Context, citationID string) (*policies.CitationResolution, error) {
+	var resolution policies.CitationResolution
+	err := cs.db.GetContext(ctx, &resolution, `
+		SELECT citation_id, status, target_id, target_kind, doi, arxiv, resolved_at FROM citations
+		WHERE citation_id = $1
+	`, citationID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &resolution, nil
+}
+
+// citationsHandler reports what a citation resolved to when it was accepted:
+// an archived paper, another archived event, an external work or nothing known
+func citationsHandler(citations *PostgreSQLCitations) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			w.Header().Set("Allow", http.MethodGet)
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		id := strings.ToLower(strings.TrimPrefix(r.URL.Path, "/citations/"))
+		if !nostr.IsValid32ByteHex(id) {
+			http.Error(w, "expected /citations/{event id} with a hex event id", http.StatusBadRequest)
+			return
+		}
+
+		resolution, err := citations.GetCitation(r.Context(), id)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if resolution == nil {
+			http.Error(w, "citation not found", http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(resolution)
+	}
+}
@@ -0,0 +1,180 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"github.com/nbd-wtf/go-nostr"
+
+	"github.com/connorslagle/nark-archival/internal/policies"
+)
+
+// PostgreSQLFingerprints stores paper fingerprints for near-duplicate
+// detection, with each fingerprint's bands indexed so that only papers
+// sharing a band are compared
+type PostgreSQLFingerprints struct {
+	db *sqlx.DB
+}
+
+// NewPostgreSQLFingerprints creates a fingerprint store
+func NewPostgreSQLFingerprints(db *sqlx.DB) *PostgreSQLFingerprints {
+	return &PostgreSQLFingerprints{db: db}
+}
+
+// Init creates the fingerprint tables and fingerprints archived papers that
+// have none yet, such as papers stored before near-duplicate detection was
+// enabled, or whose fingerprint was made by another normalization version
+func (fs *PostgreSQLFingerprints) Init(ctx context.Context) error {
+	_, err := fs.db.ExecContext(ctx, `
+		CREATE TABLE IF NOT EXISTS paper_fingerprints (
+			event_id TEXT PRIMARY KEY,
+			address TEXT NOT NULL,
+			fingerprint BYTEA NOT NULL,
+			created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+		)
+	`)
+	if err != nil {
+		return err
+	}
+
+	_, err = fs.db.ExecContext(ctx, `
+		CREATE TABLE IF NOT EXISTS paper_fingerprint_bands (
+			event_id TEXT NOT NULL,
+			band SMALLINT NOT NULL,
+			bucket BIGINT NOT NULL,
+			PRIMARY KEY (event_id, band)
+		)
+	`)
+	if err != nil {
+		return err
+	}
+
+	_, err = fs.db.ExecContext(ctx, `
+		CREATE INDEX IF NOT EXISTS idx_paper_fingerprint_bands ON paper_fingerprint_bands(band, bucket)
+	`)
+	if err != nil {
+		return err
+	}
+
+	// Fingerprints made before the version was recorded are stale
+	_, err = fs.db.ExecContext(ctx, `
+		ALTER TABLE paper_fingerprints ADD COLUMN IF NOT EXISTS normalization_version INTEGER NOT NULL DEFAULT 0
+	`)
+	if err != nil {
+		return err
+	}
+
+	// Stale fingerprints are dropped and remade below
+	_, err = fs.db.ExecContext(ctx, `
+		WITH stale AS (
+			DELETE FROM paper_fingerprints WHERE normalization_version <> $1
+			RETURNING event_id
+		)
+		DELETE FROM paper_fingerprint_bands b USING stale s WHERE b.event_id = s.event_id
+	`, policies.NormalizationVersion)
+	if err != nil {
+		return err
+	}
+
+	rows, err := fs.db.QueryContext(ctx, `
+		SELECT e.id, e.pubkey, e.created_at, e.kind, e.tags, e.content, e.sig FROM event e
+		WHERE e.kind = $1 AND NOT EXISTS (SELECT 1 FROM paper_fingerprints f WHERE f.event_id = e.id)
+	`, AcademicPaperKind)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	papers, err := scanMatching(rows, nostr.Filter{})
+	if err != nil {
+		return err
+	}
+
+	for _, paper := range papers {
+		fingerprint := policies.FingerprintPaper(paper)
+		if fingerprint == nil {
+			continue
+		}
+		if err := fs.StoreFingerprint(ctx, policies.StoredFingerprint{
+			EventID:     paper.ID,
+			Address:     policies.EventAddress(paper),
+			Fingerprint: fingerprint,
+			StoredAt:    paper.CreatedAt.Time(),
+		}); err != nil {
+			return fmt.Errorf("failed to fingerprint paper %s: %w", paper.ID, err)
+		}
+	}
+	return nil
+}
+
+// StoreFingerprint stores a paper's fingerprint and its band index
+func (fs *PostgreSQLFingerprints) StoreFingerprint(ctx context.Context, stored policies.StoredFingerprint) error {
+	tx, err := fs.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(ctx, `
+		INSERT INTO paper_fingerprints (event_id, address, fingerprint, created_at, normalization_version) VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT DO NOTHING
+	`, stored.EventID, stored.Address, stored.Fingerprint.Bytes(), stored.StoredAt, policies.NormalizationVersion)
+	if err != nil {
+		return err
+	}
+	if inserted, err := result.RowsAffected(); err != nil || inserted == 0 {
+		return err
+	}
+
+	for band, bucket := range stored.Fingerprint.Bands() {
+		if _, err := tx.ExecContext(ctx,
+			"INSERT INTO paper_fingerprint_bands (event_id, band, bucket) VALUES ($1, $2, $3)",
+			stored.EventID, band, int64(bucket)); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// FingerprintCandidates returns the stored fingerprints sharing a band
+func (fs *PostgreSQLFingerprints) FingerprintCandidates(ctx context.Context, fingerprint policies.Fingerprint) ([]policies.StoredFingerprint, error) {
+	bands := fingerprint.Bands()
+	buckets := make([]int64, len(bands))
+	for i, bucket := range bands {
+		buckets[i] = int64(bucket)
+	}
+
+	rows, err := fs.db.QueryContext(ctx, `
+		SELECT f.event_id, f.address, f.fingerprint, f.created_at FROM paper_fingerprints f
+		WHERE f.event_id IN (
+			SELECT b.event_id FROM paper_fingerprint_bands b
+			JOIN unnest($1::bigint[]) WITH ORDINALITY AS q(bucket, band)
+			ON b.band = q.band - 1 AND b.bucket = q.bucket
+		)
+	`, pq.Array(buckets))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	return scanFingerprints(rows)
+}
+
+func scanFingerprints(rows *sql.Rows) ([]policies.StoredFingerprint, error) {
+	var candidates []policies.StoredFingerprint
+	for rows.Next() {
+		var stored policies.StoredFingerprint
+		var data []byte
+		if err := rows.Scan(&stored.EventID, &stored.Address, &data, &stored.StoredAt); err != nil {
+			return nil, err
+		}
+		fingerprint, err := policies.ParseFingerprint(data)
+		if err != nil {
+			return nil, fmt.Errorf("fingerprint of %s: %w", stored.EventID, err)
+		}
+		stored.Fingerprint = fingerprint
+		candidates = append(candidates, stored)
+	}
+	return candidates, rows.Err()
+}
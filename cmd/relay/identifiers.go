@@ -0,0 +1,182 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"github.com/nbd-wtf/go-nostr"
+
+	"github.com/connorslagle/nark-archival/internal/policies"
+)
+
+// PostgreSQLIdentifiers stores the DOIs, arXiv IDs, ISBNs, handles and
+// PubMed IDs claimed by archived papers and datasets. The primary key makes
+// each identifier belong to a single address.
+type PostgreSQLIdentifiers struct {
+	db *sqlx.DB
+}
+
+// NewPostgreSQLIdentifiers creates an identifier store
+func NewPostgreSQLIdentifiers(db *sqlx.DB) *PostgreSQLIdentifiers {
+	return &PostgreSQLIdentifiers{db: db}
+}
+
+// Init creates the event_identifiers table next to event_hashes. When the
+// table is new, the identifiers of archived papers and datasets are claimed,
+// oldest first so the original keeps its identifiers. Claims left by events
+// that were never stored, for example after a crash between validation and
+// storage, are removed.
+func (is *PostgreSQLIdentifiers) Init(ctx context.Context) error {
+	var exists bool
+	if err := is.db.GetContext(ctx, &exists, "SELECT to_regclass('event_identifiers') IS NOT NULL"); err != nil {
+		return err
+	}
+
+	_, err := is.db.ExecContext(ctx, `
+		CREATE TABLE IF NOT EXISTS event_identifiers (
+			scheme TEXT NOT NULL,
+			value TEXT NOT NULL,
+			event_id TEXT NOT NULL,
+			address TEXT NOT NULL,
+			created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+			PRIMARY KEY (scheme, value)
+		)
+	`)
+	if err != nil {
+		return err
+	}
+
+	_, err = is.db.ExecContext(ctx, `
+		CREATE INDEX IF NOT EXISTS idx_event_identifiers_event ON event_identifiers(event_id)
+	`)
+	if err != nil {
+		return err
+	}
+
+	// Events are claimed for while they are validated, so an hour leaves
+	// events being stored by other relay instances alone
+	_, err = is.db.ExecContext(ctx, `
+		DELETE FROM event_identifiers i
+		WHERE i.created_at < now() - interval '1 hour'
+		AND NOT EXISTS (SELECT 1 FROM event e WHERE e.id = i.event_id)
+	`)
+	if err != nil {
+		return err
+	}
+
+	if exists {
+		return nil
+	}
+
+	rows, err := is.db.QueryContext(ctx, `
+		SELECT e.id, e.pubkey, e.created_at, e.kind, e.tags, e.content, e.sig FROM event e
+		WHERE e.kind IN ($1, $2)
+		AND EXISTS (SELECT 1 FROM jsonb_array_elements(e.tags) t WHERE t->>0 = ANY($3))
+		ORDER BY e.created_at, e.id
+	`, AcademicPaperKind, AcademicDataKind, pq.Array(policies.IdentifierSchemes))
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	events, err := scanMatching(rows, nostr.Filter{})
+	if err != nil {
+		return err
+	}
+
+	// Archived events keep the identifiers no earlier event claimed
+	for _, event := range events {
+		address := policies.EventAddress(event)
+		for _, identifier := range policies.EventIdentifiers(event) {
+			if _, err := is.db.ExecContext(ctx, `
+				INSERT INTO event_identifiers (scheme, value, event_id, address) VALUES ($1, $2, $3, $4)
+				ON CONFLICT DO NOTHING
+			`, identifier.Scheme, identifier.Value, event.ID, address); err != nil {
+				return fmt.Errorf("failed to record identifiers of %s: %w", event.ID, err)
+			}
+		}
+	}
+	return nil
+}
+
+// IdentifierClaims returns the claims on any of the identifiers
+func (is *PostgreSQLIdentifiers) IdentifierClaims(ctx context.Context, identifiers []policies.Identifier) ([]policies.IdentifierClaim, error) {
+	schemes, values := identifierColumns(identifiers)
+	var claims []policies.IdentifierClaim
+	err := is.db.SelectContext(ctx, &claims, `
+		SELECT i.scheme, i.value, i.event_id, i.address, i.created_at AS stored_at FROM event_identifiers i
+		JOIN unnest($1::text[], $2::text[]) AS q(scheme, value) ON i.scheme = q.scheme AND i.value = q.value
+		ORDER BY i.scheme, i.value
+	`, pq.Array(schemes), pq.Array(values))
+	if err != nil {
+		return nil, err
+	}
+	return claims, nil
+}
+
+// ClaimIdentifiers claims an event's unclaimed identifiers for its address
+// in a transaction, which is rolled back when another address holds one of
+// them. An event claiming an identifier another transaction is claiming
+// waits for it to finish.
+func (is *PostgreSQLIdentifiers) ClaimIdentifiers(ctx context.Context, event *nostr.Event, identifiers []policies.Identifier) ([]policies.Identifier, []policies.IdentifierClaim, error) {
+	tx, err := is.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer tx.Rollback()
+
+	address := policies.EventAddress(event)
+	var claimed []policies.Identifier
+	for _, identifier := range identifiers {
+		result, err := tx.ExecContext(ctx, `
+			INSERT INTO event_identifiers (scheme, value, event_id, address) VALUES ($1, $2, $3, $4)
+			ON CONFLICT DO NOTHING
+		`, identifier.Scheme, identifier.Value, event.ID, address)
+		if err != nil {
+			return nil, nil, err
+		}
+		if n, err := result.RowsAffected(); err != nil {
+			return nil, nil, err
+		} else if n > 0 {
+			claimed = append(claimed, identifier)
+		}
+	}
+
+	schemes, values := identifierColumns(identifiers)
+	var claims []policies.IdentifierClaim
+	err = tx.SelectContext(ctx, &claims, `
+		SELECT i.scheme, i.value, i.event_id, i.address, i.created_at AS stored_at FROM event_identifiers i
+		JOIN unnest($1::text[], $2::text[]) AS q(scheme, value) ON i.scheme = q.scheme AND i.value = q.value
+		WHERE i.event_id <> $3 AND ($4 = '' OR i.address <> $4)
+		ORDER BY i.scheme, i.value
+	`, pq.Array(schemes), pq.Array(values), event.ID, address)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(claims) > 0 {
+		return nil, claims, nil
+	}
+	return claimed, nil, tx.Commit()
+}
+
+// ReleaseIdentifiers removes the event's claims on the identifiers
+func (is *PostgreSQLIdentifiers) ReleaseIdentifiers(ctx context.Context, event *nostr.Event, identifiers []policies.Identifier) error {
+	schemes, values := identifierColumns(identifiers)
+	_, err := is.db.ExecContext(ctx, `
+		DELETE FROM event_identifiers i
+		USING unnest($1::text[], $2::text[]) AS q(scheme, value)
+		WHERE i.scheme = q.scheme AND i.value = q.value AND i.event_id = $3
+	`, pq.Array(schemes), pq.Array(values), event.ID)
+	return err
+}
+
+// identifierColumns splits identifiers into their schemes and values
+func identifierColumns(identifiers []policies.Identifier) ([]string, []string) {
+	schemes := make([]string, len(identifiers))
+	values := make([]string, len(identifiers))
+	for i, identifier := range identifiers {
+		schemes[i], values[i] = identifier.Scheme, identifier.Value
+	}
+	return schemes, values
+}
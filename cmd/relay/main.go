@@ -2,13 +2,16 @@ package main
 
 import (
 	"context"
+	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
 	"os"
 	"os/signal"
 	"strconv"
+	"strings"
 	"syscall"
 	"time"
 
@@ -16,6 +19,8 @@ import (
 	"github.com/fiatjaf/khatru"
 	"github.com/jmoiron/sqlx"
 	"github.com/nbd-wtf/go-nostr"
+	"github.com/nbd-wtf/go-nostr/nip11"
+	"github.com/nbd-wtf/go-nostr/nip19"
 	_ "github.com/lib/pq"
 	
 	"github.com/connorslagle/nark-archival/internal/policies"
@@ -28,6 +33,9 @@ const (
 	AcademicReviewKind      = 31430
 	AcademicDataKind        = 31431
 	AcademicDiscussionKind  = 31432
+	AcademicRetractionKind  = 31433
+	AcademicErratumKind     = 31434
+	AcademicConcernKind     = 31435
 )
 
 var academicKinds = []int{
@@ -36,8 +44,15 @@ var academicKinds = []int{
 	AcademicReviewKind,
 	AcademicDataKind,
 	AcademicDiscussionKind,
+	AcademicRetractionKind,
+	AcademicErratumKind,
+	AcademicConcernKind,
 }
 
+// queryableKinds are the kinds served to clients: academic events and the
+// NIP-09 deletion requests recorded for them
+var queryableKinds = append(append([]int{}, academicKinds...), deletionKind)
+
 // PostgreSQLPaperStore adapts PostgreSQL backend for policy checks
 type PostgreSQLPaperStore struct {
 	store *postgresql.PostgresBackend
@@ -53,14 +68,77 @@ func (ps *PostgreSQLPaperStore) GetEvent(ctx context.Context, id string) (*nostr
 		return nil, err
 	}
 	
-	// Get first event from channel
-	select {
-	case event := <-events:
-		return event, nil
-	case <-ctx.Done():
-		return nil, ctx.Err()
-	default:
-		return nil, nil
+	return firstEvent(ctx, events)
+}
+
+// GetVersions returns every event archived at a NIP-33 address
+func (ps *PostgreSQLPaperStore) GetVersions(ctx context.Context, kind int, pubkey, d string) ([]*nostr.Event, error) {
+	events, err := ps.store.QueryEvents(ctx, nostr.Filter{
+		Kinds:   []int{kind},
+		Authors: []string{pubkey},
+		Tags:    nostr.TagMap{"d": []string{d}},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// The tag match ignores the tag name, so check the d tag itself
+	var versions []*nostr.Event
+	for event := range events {
+		if tag := event.Tags.GetFirst([]string{"d", ""}); tag != nil && (*tag)[1] == d {
+			versions = append(versions, event)
+		}
+	}
+	return versions, nil
+}
+
+// GetNotices returns the retractions, errata and expressions of concern
+// referencing a paper by id or address, oldest first
+func (ps *PostgreSQLPaperStore) GetNotices(ctx context.Context, paper *nostr.Event) ([]*nostr.Event, error) {
+	references := []string{paper.ID}
+	address := policies.EventAddress(paper)
+	if address != "" {
+		references = append(references, address)
+	}
+
+	events, err := ps.store.QueryEvents(ctx, nostr.Filter{
+		Kinds: policies.NoticeKinds,
+		Tags:  nostr.TagMap{"e": references},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var notices []*nostr.Event
+	for event := range events {
+		id, a := policies.PaperReference(event)
+		if id == paper.ID || (address != "" && a == address) {
+			notices = append([]*nostr.Event{event}, notices...)
+		}
+	}
+	return notices, nil
+}
+
+// firstEvent returns the first event of a query result and drains the rest
+// so the store can finish its query
+func firstEvent(ctx context.Context, events chan *nostr.Event) (*nostr.Event, error) {
+	var first *nostr.Event
+	for {
+		select {
+		case event, ok := <-events:
+			if !ok {
+				return first, nil
+			}
+			if first == nil {
+				first = event
+			}
+		case <-ctx.Done():
+			go func() {
+				for range events {
+				}
+			}()
+			return nil, ctx.Err()
+		}
 	}
 }
 
@@ -98,18 +176,24 @@ func NewPostgreSQLDuplicateChecker(db *sqlx.DB) *PostgreSQLDuplicateChecker {
 	}
 }
 
-func (dc *PostgreSQLDuplicateChecker) IsDuplicate(ctx context.Context, event *nostr.Event) (bool, error) {
+func (dc *PostgreSQLDuplicateChecker) IsDuplicate(ctx context.Context, event *nostr.Event) ([]policies.DuplicateMatch, error) {
 	hash := dc.hasher.GenerateHash(event)
 	
-	// Check if hash exists in database
-	var count int
-	err := dc.db.GetContext(ctx, &count, 
-		"SELECT COUNT(*) FROM event_hashes WHERE content_hash = $1", hash)
+	// Find events stored with the same hash, other than earlier versions at
+	// the same address, oldest first
+	var matches []policies.DuplicateMatch
+	err := dc.db.SelectContext(ctx, &matches, `
+		SELECT h.event_id, COALESCE(v.address, '') AS address, h.content_hash AS hash, 1.0 AS score, h.created_at AS stored_at
+		FROM event_hashes h
+		LEFT JOIN event_versions v ON v.event_id = h.event_id
+		WHERE h.content_hash = $1 AND ($2 = '' OR v.address IS DISTINCT FROM $2)
+		ORDER BY h.created_at
+	`, hash, policies.EventAddress(event))
 	if err != nil {
-		return false, err
+		return nil, err
 	}
 	
-	return count > 0, nil
+	return matches, nil
 }
 
 func (dc *PostgreSQLDuplicateChecker) StoreHash(ctx context.Context, event *nostr.Event, hash string) error {
@@ -118,12 +202,55 @@ func (dc *PostgreSQLDuplicateChecker) StoreHash(ctx context.Context, event *nost
 	}
 	
 	_, err := dc.db.ExecContext(ctx,
-		"INSERT INTO event_hashes (event_id, content_hash, created_at) VALUES ($1, $2, $3) ON CONFLICT DO NOTHING",
-		event.ID, hash, time.Now())
+		"INSERT INTO event_hashes (event_id, content_hash, created_at, normalization_version) VALUES ($1, $2, $3, $4) ON CONFLICT DO NOTHING",
+		event.ID, hash, time.Now(), policies.NormalizationVersion)
 	
 	return err
 }
 
+// StaleHashes counts the hashes made by a different normalization version
+func (dc *PostgreSQLDuplicateChecker) StaleHashes(ctx context.Context) (int, error) {
+	var stale int
+	err := dc.db.GetContext(ctx, &stale,
+		"SELECT COUNT(*) FROM event_hashes WHERE normalization_version <> $1", policies.NormalizationVersion)
+	return stale, err
+}
+
+// Rehash recomputes the hashes made by a different normalization version,
+// in batches, and returns how many were recomputed
+func (dc *PostgreSQLDuplicateChecker) Rehash(ctx context.Context) (int, error) {
+	rehashed := 0
+	for {
+		rows, err := dc.db.QueryContext(ctx, `
+			SELECT e.id, e.pubkey, e.created_at, e.kind, e.tags, e.content, e.sig
+			FROM event_hashes h JOIN event e ON e.id = h.event_id
+			WHERE h.normalization_version <> $1
+			ORDER BY h.created_at, h.event_id
+			LIMIT 500
+		`, policies.NormalizationVersion)
+		if err != nil {
+			return rehashed, err
+		}
+		events, err := scanMatching(rows, nostr.Filter{})
+		rows.Close()
+		if err != nil {
+			return rehashed, err
+		}
+		if len(events) == 0 {
+			return rehashed, nil
+		}
+		
+		for _, event := range events {
+			if _, err := dc.db.ExecContext(ctx,
+				"UPDATE event_hashes SET content_hash = $2, normalization_version = $3 WHERE event_id = $1",
+				event.ID, dc.hasher.GenerateHash(event), policies.NormalizationVersion); err != nil {
+				return rehashed, fmt.Errorf("failed to rehash %s: %w", event.ID, err)
+			}
+			rehashed++
+		}
+	}
+}
+
 func (dc *PostgreSQLDuplicateChecker) Init(ctx context.Context) error {
 	// Create table for content hashes
 	_, err := dc.db.ExecContext(ctx, `
@@ -141,6 +268,16 @@ func (dc *PostgreSQLDuplicateChecker) Init(ctx context.Context) error {
 	_, err = dc.db.ExecContext(ctx, `
 		CREATE INDEX IF NOT EXISTS idx_content_hash ON event_hashes(content_hash)
 	`)
+	if err != nil {
+		return err
+	}
+	
+	// Hashes stored before normalization versions were recorded were made by
+	// the first version, which hashed datasets by description. relay rehash
+	// gives those datasets their file hash.
+	_, err = dc.db.ExecContext(ctx, `
+		ALTER TABLE event_hashes ADD COLUMN IF NOT EXISTS normalization_version INTEGER NOT NULL DEFAULT 1
+	`)
 	return err
 }
 
@@ -184,54 +321,228 @@ func main() {
 		log.Fatalf("Failed to initialize event store: %v", err)
 	}
 
+	// Every version published at an address is kept
+	versions := NewPostgreSQLVersions(db)
+	if err := versions.Init(ctx); err != nil {
+		log.Fatalf("Failed to initialize versions: %v", err)
+	}
+
 	// Initialize duplicate checker
 	duplicateChecker := NewPostgreSQLDuplicateChecker(db)
 	if err := duplicateChecker.Init(ctx); err != nil {
 		log.Fatalf("Failed to initialize duplicate checker: %v", err)
 	}
 
+	// "relay rehash" recomputes hashes made by an older normalization version
+	if len(os.Args) > 1 && os.Args[1] == "rehash" {
+		rehashed, err := duplicateChecker.Rehash(ctx)
+		if err != nil {
+			log.Fatalf("Failed to rehash events: %v", err)
+		}
+		log.Printf("Rehashed %d events with normalization version %d", rehashed, policies.NormalizationVersion)
+		return
+	}
+	if stale, err := duplicateChecker.StaleHashes(ctx); err != nil {
+		log.Fatalf("Failed to count stale content hashes: %v", err)
+	} else if stale > 0 {
+		log.Printf("Warning: %d content hashes were made by an older normalization version and may miss duplicates; run 'relay rehash' to recompute them", stale)
+	}
+
+	// Load policy configuration
+	policyConfig := policies.DefaultConfig()
+	if path := os.Getenv("POLICY_CONFIG"); path != "" {
+		policyConfig, err = policies.LoadConfig(path)
+		if err != nil {
+			log.Fatalf("Failed to load policy config: %v", err)
+		}
+		log.Printf("Loaded policy configuration from %s", path)
+	}
+
+	// Near-duplicate papers are found by fingerprints stored in PostgreSQL
+	var duplicates policies.DuplicateChecker = duplicateChecker
+	if threshold := policyConfig.Duplicates.SimilarityThreshold; threshold > 0 {
+		fingerprints := NewPostgreSQLFingerprints(db)
+		if err := fingerprints.Init(ctx); err != nil {
+			log.Fatalf("Failed to initialize paper fingerprints: %v", err)
+		}
+		duplicates = policies.NewNearDuplicateChecker(duplicateChecker, fingerprints, threshold)
+		log.Printf("Rejecting papers at least %.0f%% similar to an archived paper", 100*threshold)
+	}
+
+	// Reputation-weighted limits scale kind limits by each pubkey's history
+	var reputation *policies.ReputationLimits
+	if policyConfig.Reputation.Enabled {
+		reputation = policies.NewReputationLimits(NewPostgreSQLReputationProvider(db), policyConfig.Reputation)
+	}
+
+	// Initialize rate limiter
+	var rateLimiter policies.RateLimiter
+	switch policyConfig.RateLimits.Backend {
+	case policies.RateLimitBackendPostgres:
+		pgLimiter := NewPostgreSQLRateLimiter(db, policyConfig.RateLimitConfig())
+		if err := pgLimiter.Init(ctx); err != nil {
+			log.Fatalf("Failed to initialize rate limiter: %v", err)
+		}
+		pgLimiter.SetReputation(reputation)
+		rateLimiter = pgLimiter
+	default:
+		memLimiter := policies.NewMemoryRateLimiter(policyConfig.RateLimitConfig())
+		memLimiter.SetReputation(reputation)
+		rateLimiter = memLimiter
+	}
+	log.Printf("Using %s rate limiter", policyConfig.RateLimits.Backend)
+
 	// Create policy engine
-	policyEngine := policies.NewPolicyEngine(
-		policies.NewMemoryRateLimiter(policies.DefaultRateLimitConfig()),
-		duplicateChecker,
-		&PostgreSQLPaperStore{store: store},
+	paperStore := &PostgreSQLPaperStore{store: store}
+	policyEngine := policies.NewPolicyEngineWithConfig(
+		policyConfig,
+		rateLimiter,
+		duplicates,
+		paperStore,
 	)
 
+	// Back the access list stage with PostgreSQL so lists can be edited at runtime
+	accessList := NewPostgreSQLAccessList(db)
+	if err := accessList.Init(ctx); err != nil {
+		log.Fatalf("Failed to initialize access list: %v", err)
+	}
+	if err := policyEngine.ReplacePolicy(policies.NewAccessListPolicy(accessList, policyConfig.AccessList)); err != nil {
+		log.Fatalf("Failed to register access list: %v", err)
+	}
+
+	// Identifier claims live next to content hashes so a DOI or arXiv ID
+	// belongs to one address
+	identifiers := NewPostgreSQLIdentifiers(db)
+	if err := identifiers.Init(ctx); err != nil {
+		log.Fatalf("Failed to initialize identifiers: %v", err)
+	}
+	if err := policyEngine.ReplacePolicy(policies.NewDuplicatePolicy(duplicates, identifiers)); err != nil {
+		log.Fatalf("Failed to register duplicate policy: %v", err)
+	}
+
+	// Record what each citation resolves to in PostgreSQL
+	citations := NewPostgreSQLCitations(db)
+	if err := citations.Init(ctx); err != nil {
+		log.Fatalf("Failed to initialize citations: %v", err)
+	}
+	if err := policyEngine.ReplacePolicy(policies.NewCitationPolicy(paperStore, citations, policyConfig.Citations)); err != nil {
+		log.Fatalf("Failed to register citation policy: %v", err)
+	}
+
+	// Moderation state managed through the NIP-86 management API
+	moderation := NewPostgreSQLModeration(db)
+	if err := moderation.Init(ctx); err != nil {
+		log.Fatalf("Failed to initialize moderation store: %v", err)
+	}
+	// Deletion requests are kept as provenance rather than deleting anything
+	withdrawals := NewPostgreSQLWithdrawals(db, store)
+	if err := withdrawals.Init(ctx); err != nil {
+		log.Fatalf("Failed to initialize withdrawals: %v", err)
+	}
+
+	adminPubkeys, err := parseAdminPubkeys(os.Getenv("ADMIN_PUBKEYS"))
+	if err != nil {
+		log.Fatalf("Invalid ADMIN_PUBKEYS: %v", err)
+	}
+	// NIP-98 events name the relay URL, which must not be guessed from a
+	// request's Host header
+	if len(adminPubkeys) > 0 && os.Getenv("SERVICE_URL") == "" {
+		log.Fatalf("SERVICE_URL must be set when ADMIN_PUBKEYS is set")
+	}
+
+	// Institution-specific stages are registered with policyEngine.RegisterPolicy
+	// before the configured order is applied, so they can be listed in "checks"
+	if err := policyEngine.SetOrder(policyConfig.Checks); err != nil {
+		log.Fatalf("Invalid policy configuration: %v", err)
+	}
+
 	// Create Khatru relay
 	relay := khatru.NewRelay()
 
+	// NIP-42 challenges must name the public URL clients connect to; khatru
+	// guesses it from the first request when unset
+	relay.ServiceURL = os.Getenv("SERVICE_URL")
+
 	// Set relay information (NIP-11)
 	relay.Info.Name = "NARK Academic Archive"
 	relay.Info.Description = "A permanent archival relay for academic content on NOSTR"
 	relay.Info.PubKey = ""
 	relay.Info.Contact = "admin@nark-archive.org"
-	relay.Info.SupportedNIPs = []int{1, 11, 78}
+	relay.Info.SupportedNIPs = []int{1, 11, 42, 78}
 	relay.Info.Software = "https://github.com/connorslagle/nark-archival"
 	relay.Info.Version = "0.1.0"
 
+	// Name, description and icon changed through the management API survive
+	// restarts and are applied to every NIP-11 response
+	if err := moderation.LoadRelaySettings(ctx); err != nil {
+		log.Fatalf("Failed to load relay settings: %v", err)
+	}
+	relay.OverwriteRelayInformation = append(relay.OverwriteRelayInformation, moderation.OverwriteRelayInformation)
+
+	// Deletion requests are recorded as withdrawals and archived events can
+	// be reported for moderation with NIP-56 reports
+	relay.Info.AddSupportedNIP(9)
+	relay.Info.AddSupportedNIP(56)
+	if len(adminPubkeys) > 0 {
+		relay.Info.AddSupportedNIP(86)
+	}
+
+	// Advertise that writes require authentication
+	if policyConfig.Auth.Mode == policies.AuthModeRequired {
+		if relay.Info.Limitation == nil {
+			relay.Info.Limitation = &nip11.RelayLimitationDocument{}
+		}
+		relay.Info.Limitation.RestrictedWrites = true
+	}
+
+	// Advertise NIP-13 when proof-of-work earns a rate limit exemption. The
+	// thresholds are listed under /policies: limitation.min_pow_difficulty
+	// would tell clients that every event needs proof-of-work.
+	if policyConfig.RateLimitConfig().MinPowDifficulty() > 0 {
+		relay.Info.AddSupportedNIP(13)
+	}
+
 	// Configure storage backend with policy enforcement
 	relay.StoreEvent = append(relay.StoreEvent, func(ctx context.Context, event *nostr.Event) error {
+		// Expose the NIP-42 identity of the connection to the policy stages
+		ctx = policies.WithAuthedPubkey(ctx, khatru.GetAuthed(ctx))
+
+		// Reports of archived events go to the moderation queue
+		if event.Kind == reportKind {
+			return storeReport(ctx, event, moderation, rateLimiter)
+		}
+
 		// Only accept academic event kinds
 		if !isAcademicEvent(event) {
-			return fmt.Errorf("only academic events (kinds %v) are accepted", academicKinds)
+			return fmt.Errorf("blocked: only academic events (kinds %v) are accepted", academicKinds)
 		}
 
 		// Validate event signature
 		if ok, err := event.CheckSignature(); !ok || err != nil {
-			return fmt.Errorf("invalid event signature")
+			return fmt.Errorf("invalid: invalid event signature")
 		}
 
-		// Run policy validation
+		// Run policy validation, reporting violations with a stable prefix and code
 		if err := policyEngine.ValidateEvent(ctx, event); err != nil {
-			return err
+			return errors.New(policies.OKMessage(err))
 		}
 
 		// Store in PostgreSQL
 		if err := store.SaveEvent(ctx, event); err != nil {
+			// Events that were not stored must not consume rate limit quota
+			if rbErr := policyEngine.RollbackEvent(ctx, event); rbErr != nil {
+				log.Printf("Rollback error for event %s: %v", event.ID, rbErr)
+			}
 			return fmt.Errorf("storage error: %w", err)
 		}
 
-		// Post-process (store hashes, update indexes)
+		// Index the event as a new version of its address; Init adds any
+		// event missed here on the next start
+		if err := versions.Record(ctx, event); err != nil {
+			log.Printf("Version index error for event %s: %v", event.ID, err)
+		}
+
+		// Post-process (commit rate limits, store hashes, update indexes)
 		if err := policyEngine.PostProcessEvent(ctx, event); err != nil {
 			log.Printf("Post-process error for event %s: %v", event.ID, err)
 		}
@@ -241,44 +552,50 @@ func main() {
 
 	// Configure event queries
 	relay.QueryEvents = append(relay.QueryEvents, func(ctx context.Context, filter nostr.Filter) (chan *nostr.Event, error) {
-		// Filter to only return academic events
-		if len(filter.Kinds) == 0 {
-			filter.Kinds = academicKinds
-		} else {
-			// Ensure only academic kinds are queried
-			filteredKinds := []int{}
-			for _, kind := range filter.Kinds {
-				if isAcademicKind(kind) {
-					filteredKinds = append(filteredKinds, kind)
-				}
-			}
-			filter.Kinds = filteredKinds
+		query, err := newArchiveQuery(filter)
+		if err != nil {
+			return nil, err
 		}
-
-		return store.QueryEvents(ctx, filter)
+		return query.Events(ctx, db, store)
 	})
 
-	// Implement retention policy - never delete academic events
+	// Implement retention policy - never delete academic events, including
+	// versions replaced by a newer event at the same address. Deletion
+	// requests from authors are recorded as withdrawals instead. khatru hands
+	// deletion requests to this hook for each e tag naming a stored event,
+	// without the RejectEvent or StoreEvent hooks, so the write access stages
+	// are run here. The first call checks and records the whole request.
+	relay.OverwriteDeletionOutcome = append(relay.OverwriteDeletionOutcome, func(ctx context.Context, target *nostr.Event, deletion *nostr.Event) (bool, string) {
+		if err := acceptDeletion(ctx, deletion, policyEngine, withdrawals); err != nil {
+			reason, authRequired := deletionReason(err)
+			if authRequired {
+				khatru.RequestAuth(ctx)
+			}
+			return false, reason
+		}
+		return true, ""
+	})
+	// khatru only resolves e tags and answers OK to requests naming addresses
+	// alone without calling the hook above, before they can be checked, so
+	// those are not recorded
+	relay.OverwriteResponseEvent = append(relay.OverwriteResponseEvent, func(ctx context.Context, event *nostr.Event) {
+		if event.Kind != deletionKind || hasEventTargets(event) || !hasAddressTargets(event) {
+			return
+		}
+		khatru.GetConnection(ctx).WriteJSON(nostr.NoticeEnvelope(
+			"deletion request " + event.ID + " was not recorded: include an e tag for the event to withdraw"))
+	})
 	relay.DeleteEvent = append(relay.DeleteEvent, func(ctx context.Context, event *nostr.Event) error {
 		return fmt.Errorf("deletion not allowed: this is a permanent archival relay for academic content")
 	})
 
 	// Count events handler
 	relay.CountEvents = append(relay.CountEvents, func(ctx context.Context, filter nostr.Filter) (int64, error) {
-		// Filter to only count academic events
-		if len(filter.Kinds) == 0 {
-			filter.Kinds = academicKinds
-		} else {
-			filteredKinds := []int{}
-			for _, kind := range filter.Kinds {
-				if isAcademicKind(kind) {
-					filteredKinds = append(filteredKinds, kind)
-				}
-			}
-			filter.Kinds = filteredKinds
+		query, err := newArchiveQuery(filter)
+		if err != nil {
+			return 0, err
 		}
-
-		return store.CountEvents(ctx, filter)
+		return query.Count(ctx, db, store)
 	})
 
 	// Add health check endpoint
@@ -290,6 +607,39 @@ func main() {
 		json.NewEncoder(w).Encode(policyEngine.GetPolicyInfo())
 	})
 
+	// Add dry-run validation endpoint
+	relay.Router().HandleFunc("/validate", validateHandler(policyEngine))
+
+	// Add rate limit status endpoint
+	relay.Router().HandleFunc("/ratelimit/", rateLimitHandler(rateLimiter))
+
+	// Add access list management endpoint
+	relay.Router().HandleFunc("/admin/access-list", requireAdminToken(os.Getenv("ADMIN_TOKEN"), accessListHandler(accessList, moderation)))
+
+	// Add reputation endpoint
+	relay.Router().HandleFunc("/reputation/", reputationHandler(reputation, policyConfig.RateLimitConfig()))
+
+	// Add NIP-86 management API, posted to the relay URL itself
+	relay.Router().HandleFunc("/", NewManagementAPI(relay, adminPubkeys, accessList, moderation).Handler())
+
+	// Add paper retraction status endpoint
+	relay.Router().HandleFunc("/papers/", paperStatusHandler(policyEngine))
+
+	// Add citation resolution endpoint
+	relay.Router().HandleFunc("/citations/", citationsHandler(citations))
+
+	// Add version history endpoint
+	relay.Router().HandleFunc("/versions/", versionsHandler(policyEngine))
+
+	// Add withdrawal lookup endpoint
+	relay.Router().HandleFunc("/withdrawals/", withdrawalsHandler(withdrawals))
+
+	// Add quarantine log endpoint
+	relay.Router().HandleFunc("/admin/quarantine", requireAdminToken(os.Getenv("ADMIN_TOKEN"), quarantineHandler(moderation)))
+
+	// Add management audit log endpoint
+	relay.Router().HandleFunc("/admin/audit", requireAdminToken(os.Getenv("ADMIN_TOKEN"), auditHandler(moderation)))
+
 	// Get port from environment
 	portStr := os.Getenv("PORT")
 	if portStr == "" {
@@ -324,6 +674,23 @@ func main() {
 	log.Println("Shutdown complete")
 }
 
+// restrictKinds limits a filter to academic kinds and deletion requests,
+// which is everything the relay stores
+func restrictKinds(filter *nostr.Filter) {
+	if len(filter.Kinds) == 0 {
+		filter.Kinds = queryableKinds
+		return
+	}
+
+	filteredKinds := []int{}
+	for _, kind := range filter.Kinds {
+		if isAcademicKind(kind) || kind == deletionKind {
+			filteredKinds = append(filteredKinds, kind)
+		}
+	}
+	filter.Kinds = filteredKinds
+}
+
 // isAcademicEvent checks if an event is an academic event kind
 func isAcademicEvent(event *nostr.Event) bool {
 	return isAcademicKind(event.Kind)
@@ -344,4 +711,186 @@ func healthCheckHandler(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
 	fmt.Fprintf(w, `{"status":"healthy","service":"nark-archival-relay","timestamp":"%s"}`, time.Now().Format(time.RFC3339))
-}
\ No newline at end of file
+}
+
+// validateHandler reports whether the relay would accept an event, without
+// storing it or consuming rate limits. The event may be signed or unsigned.
+func validateHandler(policyEngine *policies.PolicyEngine) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", http.MethodPost)
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		var event nostr.Event
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 512000)).Decode(&event); err != nil {
+			http.Error(w, fmt.Sprintf("invalid event JSON: %v", err), http.StatusBadRequest)
+			return
+		}
+
+		// Unsigned events are checked as drafts; signed events must verify
+		var signatureErr error
+		if event.Sig != "" {
+			if event.ID != event.GetID() {
+				signatureErr = policies.NewPolicyError(policies.CodeInvalidSignature, "id", "event id does not match its content")
+			} else if ok, err := event.CheckSignature(); !ok || err != nil {
+				signatureErr = policies.NewPolicyError(policies.CodeInvalidSignature, "sig", "invalid event signature")
+			}
+		} else if event.ID == "" {
+			event.ID = event.GetID()
+		}
+
+		report := policyEngine.DryRun(r.Context(), &event)
+		report.Add("signature", signatureErr)
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(report)
+	}
+}
+
+// storeReport queues a NIP-56 report of an archived event for moderation.
+// Reports count against the general rate limit but are not stored as events.
+func storeReport(ctx context.Context, event *nostr.Event, moderation *PostgreSQLModeration, limiter policies.RateLimiter) error {
+	if ok, err := event.CheckSignature(); !ok || err != nil {
+		return fmt.Errorf("invalid: invalid event signature")
+	}
+	if err := policies.CheckRateLimit(ctx, event, limiter); err != nil {
+		return errors.New(policies.OKMessage(err))
+	}
+
+	queued, err := moderation.AddReport(ctx, event)
+	if err != nil {
+		return fmt.Errorf("error: failed to queue report: %w", err)
+	}
+	if !queued {
+		return fmt.Errorf("invalid: reports must reference an archived event in an e tag")
+	}
+	return nil
+}
+
+// acceptDeletion stores a NIP-09 deletion request the first time it is
+// received and links it to the events and address versions it names. Every
+// target and the write access stages are checked before anything is
+// recorded. Rejections are returned as OK messages.
+func acceptDeletion(ctx context.Context, deletion *nostr.Event, policyEngine *policies.PolicyEngine, withdrawals *PostgreSQLWithdrawals) error {
+	// A request naming several events is checked and saved once
+	stored, err := withdrawals.Stored(ctx, deletion.ID)
+	if err != nil {
+		return fmt.Errorf("error: failed to look up deletion request: %w", err)
+	}
+	if stored {
+		return nil
+	}
+
+	targets, err := withdrawals.Targets(ctx, deletion)
+	if err != nil {
+		return fmt.Errorf("error: failed to look up deletion targets: %w", err)
+	}
+	if err := checkDeletionTargets(deletion, targets); err != nil {
+		return fmt.Errorf("blocked: %w", err)
+	}
+
+	ctx = policies.WithAuthedPubkey(ctx, khatru.GetAuthed(ctx))
+	if err := policyEngine.ValidateStages(ctx, deletion, policies.WriteAccessStages...); err != nil {
+		return errors.New(policies.OKMessage(err))
+	}
+
+	saved, err := withdrawals.Save(ctx, deletion)
+	if err != nil || !saved {
+		// Requests that were not stored must not consume rate limit quota
+		if rbErr := policyEngine.RollbackEvent(ctx, deletion); rbErr != nil {
+			log.Printf("Rollback error for deletion request %s: %v", deletion.ID, rbErr)
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("error: failed to store deletion request: %w", err)
+	}
+	if saved {
+		if err := policyEngine.PostProcessStages(ctx, deletion, policies.WriteAccessStages...); err != nil {
+			log.Printf("Post-process error for deletion request %s: %v", deletion.ID, err)
+		}
+	}
+
+	for _, target := range targets {
+		if err := withdrawals.Link(ctx, deletion, target.ID); err != nil {
+			return fmt.Errorf("error: failed to record the deletion request: %w", err)
+		}
+	}
+	if err := withdrawals.LinkAddresses(ctx, deletion); err != nil {
+		return fmt.Errorf("error: failed to record the deletion request: %w", err)
+	}
+	return nil
+}
+
+// scanMatching returns the scanned events that match a filter
+func scanMatching(rows *sql.Rows, filter nostr.Filter) ([]*nostr.Event, error) {
+	var events []*nostr.Event
+	for rows.Next() {
+		var event nostr.Event
+		var createdAt int64
+		if err := rows.Scan(&event.ID, &event.PubKey, &createdAt, &event.Kind, &event.Tags, &event.Content, &event.Sig); err != nil {
+			return nil, err
+		}
+		event.CreatedAt = nostr.Timestamp(createdAt)
+		if filter.Matches(&event) {
+			events = append(events, &event)
+		}
+	}
+	return events, rows.Err()
+}
+
+// paperStatusHandler reports whether a paper has been retracted and lists
+// its retractions, errata and expressions of concern
+func paperStatusHandler(policyEngine *policies.PolicyEngine) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			w.Header().Set("Allow", http.MethodGet)
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		id := strings.ToLower(strings.TrimPrefix(r.URL.Path, "/papers/"))
+		if !nostr.IsValid32ByteHex(id) {
+			http.Error(w, "expected /papers/{event id} with a hex event id", http.StatusBadRequest)
+			return
+		}
+
+		status, err := policyEngine.PaperStatus(r.Context(), id)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if status == nil {
+			http.Error(w, "paper not found", http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(status)
+	}
+}
+
+// pubkeyFromPath extracts a hex or npub pubkey following prefix in path
+func pubkeyFromPath(path, prefix string) (string, bool) {
+	pubkey := strings.TrimPrefix(path, prefix)
+	if pubkey == path || pubkey == "" || strings.Contains(pubkey, "/") {
+		return "", false
+	}
+	return normalizePubkey(pubkey)
+}
+
+// normalizePubkey accepts a hex pubkey or npub and returns the hex form
+func normalizePubkey(pubkey string) (string, bool) {
+	if strings.HasPrefix(pubkey, "npub1") {
+		prefix, value, err := nip19.Decode(pubkey)
+		if err != nil || prefix != "npub" {
+			return "", false
+		}
+		pubkey, _ = value.(string)
+	}
+	pubkey = strings.ToLower(pubkey)
+	if !nostr.IsValid32ByteHex(pubkey) {
+		return "", false
+	}
+	return pubkey, true
+}
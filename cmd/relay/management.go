@@ -0,0 +1,398 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/fiatjaf/khatru"
+	"github.com/nbd-wtf/go-nostr"
+
+	"github.com/connorslagle/nark-archival/internal/policies"
+)
+
+const (
+	// managementContentType marks NIP-86 requests sent to the relay URL
+	managementContentType = "application/nostr+json+rpc"
+	// httpAuthKind is the NIP-98 HTTP auth event kind
+	httpAuthKind = 27235
+	// httpAuthWindow is how far an auth event's created_at may be from now
+	httpAuthWindow = time.Minute
+)
+
+// managementMethods are the NIP-86 methods the relay supports
+var managementMethods = []string{
+	"supportedmethods",
+	"banpubkey",
+	"allowpubkey",
+	"listbannedpubkeys",
+	"listallowedpubkeys",
+	"banevent",
+	"allowevent",
+	"listbannedevents",
+	"listeventsneedingmoderation",
+	"changerelayname",
+	"changerelaydescription",
+	"changerelayicon",
+}
+
+type managementRequest struct {
+	Method string            `json:"method"`
+	Params []json.RawMessage `json:"params"`
+}
+
+type managementResponse struct {
+	Result interface{} `json:"result,omitempty"`
+	Error  string      `json:"error,omitempty"`
+}
+
+// pubkeyReason is how NIP-86 lists banned and allowed pubkeys
+type pubkeyReason struct {
+	Pubkey string `json:"pubkey"`
+	Reason string `json:"reason,omitempty"`
+}
+
+// ManagementAPI serves the NIP-86 relay management API. Calls must carry a
+// NIP-98 Authorization header signed by one of the admin pubkeys, and every
+// authorized call is written to the audit log.
+type ManagementAPI struct {
+	relay      *khatru.Relay
+	admins     map[string]bool
+	accessList policies.AccessListStore
+	moderation *PostgreSQLModeration
+}
+
+// NewManagementAPI creates the management API for a relay
+func NewManagementAPI(relay *khatru.Relay, admins []string, accessList policies.AccessListStore, moderation *PostgreSQLModeration) *ManagementAPI {
+	adminSet := make(map[string]bool, len(admins))
+	for _, admin := range admins {
+		adminSet[admin] = true
+	}
+	return &ManagementAPI{
+		relay:      relay,
+		admins:     adminSet,
+		accessList: accessList,
+		moderation: moderation,
+	}
+}
+
+// parseAdminPubkeys reads a comma-separated list of hex or npub pubkeys
+func parseAdminPubkeys(value string) ([]string, error) {
+	var admins []string
+	for _, field := range strings.Split(value, ",") {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+		pubkey, ok := normalizePubkey(field)
+		if !ok {
+			return nil, fmt.Errorf("invalid admin pubkey %q: must be hex or npub", field)
+		}
+		admins = append(admins, pubkey)
+	}
+	return admins, nil
+}
+
+// Handler answers NIP-86 requests posted to the relay URL. It is mounted at
+// "/", so anything else gets the not found response the router would give.
+func (m *ManagementAPI) Handler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/" || !strings.HasPrefix(r.Header.Get("Content-Type"), managementContentType) {
+			http.NotFound(w, r)
+			return
+		}
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", http.MethodPost)
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		if len(m.admins) == 0 {
+			http.Error(w, "management API is disabled: set ADMIN_PUBKEYS to enable it", http.StatusNotFound)
+			return
+		}
+
+		body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, 65536))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		admin, err := m.authorize(r, body)
+		if err != nil {
+			writeManagementResponse(w, http.StatusUnauthorized, managementResponse{Error: err.Error()})
+			return
+		}
+
+		var req managementRequest
+		if err := json.Unmarshal(body, &req); err != nil {
+			writeManagementResponse(w, http.StatusBadRequest, managementResponse{Error: fmt.Sprintf("invalid request JSON: %v", err)})
+			return
+		}
+
+		result, err := m.call(r.Context(), admin, req)
+
+		params, _ := json.Marshal(req.Params)
+		if auditErr := m.moderation.Audit(r.Context(), admin, req.Method, params, err); auditErr != nil {
+			log.Printf("Audit error: %v", auditErr)
+		}
+
+		if err != nil {
+			writeManagementResponse(w, http.StatusOK, managementResponse{Error: err.Error()})
+			return
+		}
+		writeManagementResponse(w, http.StatusOK, managementResponse{Result: result})
+	}
+}
+
+func writeManagementResponse(w http.ResponseWriter, status int, response managementResponse) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(response)
+}
+
+// authorize checks the NIP-98 Authorization header and returns the admin
+// pubkey that signed it
+func (m *ManagementAPI) authorize(r *http.Request, body []byte) (string, error) {
+	header := r.Header.Get("Authorization")
+	if !strings.HasPrefix(header, "Nostr ") {
+		return "", errors.New("missing NIP-98 Authorization header")
+	}
+	raw, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(header, "Nostr "))
+	if err != nil {
+		return "", errors.New("authorization event is not valid base64")
+	}
+
+	var event nostr.Event
+	if err := json.Unmarshal(raw, &event); err != nil {
+		return "", errors.New("authorization event is not valid JSON")
+	}
+	if event.Kind != httpAuthKind {
+		return "", fmt.Errorf("authorization event must be kind %d", httpAuthKind)
+	}
+	if ok, err := event.CheckSignature(); !ok || err != nil {
+		return "", errors.New("authorization event has an invalid signature")
+	}
+	if age := time.Since(event.CreatedAt.Time()); age > httpAuthWindow || age < -httpAuthWindow {
+		return "", errors.New("authorization event is too old or too far in the future")
+	}
+
+	if u := event.Tags.GetFirst([]string{"u", ""}); u == nil || m.requestURL() == "" || !sameURL((*u)[1], m.requestURL()) {
+		return "", errors.New("authorization event u tag does not match the relay URL")
+	}
+	if method := event.Tags.GetFirst([]string{"method", ""}); method == nil || !strings.EqualFold((*method)[1], r.Method) {
+		return "", errors.New("authorization event method tag does not match the request")
+	}
+	sum := sha256.Sum256(body)
+	if payload := event.Tags.GetFirst([]string{"payload", ""}); payload == nil || !strings.EqualFold((*payload)[1], hex.EncodeToString(sum[:])) {
+		return "", errors.New("authorization event payload tag does not match the request body")
+	}
+
+	if !m.admins[event.PubKey] {
+		return "", errors.New("pubkey is not a relay admin")
+	}
+	return event.PubKey, nil
+}
+
+// requestURL is the URL NIP-98 events must name: the service URL with an
+// HTTP scheme. It is never taken from the request, whose Host header the
+// client controls.
+func (m *ManagementAPI) requestURL() string {
+	serviceURL := m.relay.ServiceURL
+	switch {
+	case strings.HasPrefix(serviceURL, "wss://"):
+		return "https://" + strings.TrimPrefix(serviceURL, "wss://")
+	case strings.HasPrefix(serviceURL, "ws://"):
+		return "http://" + strings.TrimPrefix(serviceURL, "ws://")
+	}
+	return serviceURL
+}
+
+func sameURL(a, b string) bool {
+	return strings.TrimSuffix(a, "/") == strings.TrimSuffix(b, "/")
+}
+
+// call runs one management method
+func (m *ManagementAPI) call(ctx context.Context, admin string, req managementRequest) (interface{}, error) {
+	switch req.Method {
+	case "supportedmethods":
+		return managementMethods, nil
+
+	case "banpubkey":
+		pubkey, reason, err := pubkeyParams(req.Params)
+		if err != nil {
+			return nil, err
+		}
+		return true, m.accessList.Add(ctx, policies.AccessEntry{
+			List: policies.AccessBlock, Pubkey: pubkey, Kind: policies.AllKinds, Reason: reason,
+		})
+
+	case "allowpubkey":
+		pubkey, reason, err := pubkeyParams(req.Params)
+		if err != nil {
+			return nil, err
+		}
+		if err := m.accessList.Remove(ctx, policies.AccessBlock, pubkey, policies.AllKinds); err != nil {
+			return nil, err
+		}
+		return true, m.accessList.Add(ctx, policies.AccessEntry{
+			List: policies.AccessAllow, Pubkey: pubkey, Kind: policies.AllKinds, Reason: reason,
+		})
+
+	case "listbannedpubkeys":
+		return m.listPubkeys(ctx, policies.AccessBlock)
+
+	case "listallowedpubkeys":
+		return m.listPubkeys(ctx, policies.AccessAllow)
+
+	case "banevent":
+		id, reason, err := eventParams(req.Params)
+		if err != nil {
+			return nil, err
+		}
+		if reason == "" {
+			return nil, errors.New("a reason is required to quarantine an event")
+		}
+		return true, m.moderation.Quarantine(ctx, id, reason, admin)
+
+	case "allowevent":
+		id, reason, err := eventParams(req.Params)
+		if err != nil {
+			return nil, err
+		}
+		return true, m.moderation.Release(ctx, id, reason, admin)
+
+	case "listbannedevents":
+		return m.moderation.QuarantinedEvents(ctx)
+
+	case "listeventsneedingmoderation":
+		return m.moderation.NeedingModeration(ctx)
+
+	case "changerelayname":
+		return m.changeRelayInfo(ctx, settingRelayName, req.Params)
+
+	case "changerelaydescription":
+		return m.changeRelayInfo(ctx, settingRelayDescription, req.Params)
+
+	case "changerelayicon":
+		return m.changeRelayInfo(ctx, settingRelayIcon, req.Params)
+
+	default:
+		return nil, fmt.Errorf("method %q is not supported", req.Method)
+	}
+}
+
+// listPubkeys lists an access list in the NIP-86 format. Entries limited to
+// one kind say so in their reason.
+func (m *ManagementAPI) listPubkeys(ctx context.Context, list string) ([]pubkeyReason, error) {
+	entries, err := m.accessList.List(ctx, list)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]pubkeyReason, 0, len(entries))
+	for _, entry := range entries {
+		reason := entry.Reason
+		if entry.Kind != policies.AllKinds {
+			reason = strings.TrimSuffix(fmt.Sprintf("kind %d only: %s", entry.Kind, reason), ": ")
+		}
+		result = append(result, pubkeyReason{Pubkey: entry.Pubkey, Reason: reason})
+	}
+	return result, nil
+}
+
+// changeRelayInfo updates a NIP-11 field and persists it across restarts
+func (m *ManagementAPI) changeRelayInfo(ctx context.Context, key string, params []json.RawMessage) (interface{}, error) {
+	value, err := stringParam(params, 0, key)
+	if err != nil {
+		return nil, err
+	}
+	if err := m.moderation.SetRelaySetting(ctx, key, value); err != nil {
+		return nil, err
+	}
+	return true, nil
+}
+
+// pubkeyParams reads the [pubkey, reason] params of the pubkey methods
+func pubkeyParams(params []json.RawMessage) (string, string, error) {
+	value, err := stringParam(params, 0, "pubkey")
+	if err != nil {
+		return "", "", err
+	}
+	pubkey, ok := normalizePubkey(value)
+	if !ok {
+		return "", "", errors.New("pubkey must be hex or npub")
+	}
+	reason, err := optionalStringParam(params, 1, "reason")
+	return pubkey, reason, err
+}
+
+// eventParams reads the [id, reason] params of the event methods
+func eventParams(params []json.RawMessage) (string, string, error) {
+	id, err := stringParam(params, 0, "event id")
+	if err != nil {
+		return "", "", err
+	}
+	id = strings.ToLower(id)
+	if !nostr.IsValid32ByteHex(id) {
+		return "", "", errors.New("event id must be 64 hex characters")
+	}
+	reason, err := optionalStringParam(params, 1, "reason")
+	return id, reason, err
+}
+
+func stringParam(params []json.RawMessage, i int, name string) (string, error) {
+	if i >= len(params) {
+		return "", fmt.Errorf("missing %s parameter", name)
+	}
+	var value string
+	if err := json.Unmarshal(params[i], &value); err != nil {
+		return "", fmt.Errorf("%s parameter must be a string", name)
+	}
+	return value, nil
+}
+
+func optionalStringParam(params []json.RawMessage, i int, name string) (string, error) {
+	if i >= len(params) || bytes.Equal(params[i], []byte("null")) {
+		return "", nil
+	}
+	return stringParam(params, i, name)
+}
+
+// auditHandler lists recent management API calls, newest first, up to
+// ?limit= entries (default 100)
+func auditHandler(moderation *PostgreSQLModeration) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			w.Header().Set("Allow", http.MethodGet)
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		limit := 100
+		if l := r.URL.Query().Get("limit"); l != "" {
+			var err error
+			if limit, err = strconv.Atoi(l); err != nil || limit <= 0 {
+				http.Error(w, "limit must be a positive number", http.StatusBadRequest)
+				return
+			}
+		}
+
+		entries, err := moderation.AuditLog(r.Context(), limit)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(entries)
+	}
+}
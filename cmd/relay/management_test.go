@@ -0,0 +1,191 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/fiatjaf/khatru"
+	"github.com/nbd-wtf/go-nostr"
+)
+
+func TestManagementAuthorize(t *testing.T) {
+	adminKey := nostr.GeneratePrivateKey()
+	admin, _ := nostr.GetPublicKey(adminKey)
+	otherKey := nostr.GeneratePrivateKey()
+
+	relay := khatru.NewRelay()
+	relay.ServiceURL = "wss://relay.example.org"
+	api := NewManagementAPI(relay, []string{admin}, nil, nil)
+
+	body := []byte(`{"method":"listbannedpubkeys","params":[]}`)
+	sum := sha256.Sum256(body)
+	payload := hex.EncodeToString(sum[:])
+
+	// authEvent builds a valid NIP-98 event for the request, signed by key
+	// after edit has changed it
+	authEvent := func(key string, edit func(*nostr.Event)) *nostr.Event {
+		event := &nostr.Event{
+			Kind:      httpAuthKind,
+			CreatedAt: nostr.Now(),
+			Tags: nostr.Tags{
+				{"u", "https://relay.example.org/"},
+				{"method", "POST"},
+				{"payload", payload},
+			},
+		}
+		if edit != nil {
+			edit(event)
+		}
+		event.Sign(key)
+		return event
+	}
+
+	tests := []struct {
+		name    string
+		event   *nostr.Event
+		header  string
+		method  string
+		wantErr string
+	}{
+		{
+			name:  "valid admin request",
+			event: authEvent(adminKey, nil),
+		},
+		{
+			name:    "missing header",
+			header:  "",
+			wantErr: "missing NIP-98 Authorization header",
+		},
+		{
+			name:    "not base64",
+			header:  "Nostr !!!",
+			wantErr: "not valid base64",
+		},
+		{
+			name:    "wrong kind",
+			event:   authEvent(adminKey, func(e *nostr.Event) { e.Kind = 1 }),
+			wantErr: "must be kind 27235",
+		},
+		{
+			name: "bad signature",
+			event: func() *nostr.Event {
+				event := authEvent(adminKey, nil)
+				event.Content = "tampered"
+				return event
+			}(),
+			wantErr: "invalid signature",
+		},
+		{
+			name: "stale created_at",
+			event: authEvent(adminKey, func(e *nostr.Event) {
+				e.CreatedAt = nostr.Timestamp(time.Now().Add(-2 * httpAuthWindow).Unix())
+			}),
+			wantErr: "too old or too far in the future",
+		},
+		{
+			name: "future created_at",
+			event: authEvent(adminKey, func(e *nostr.Event) {
+				e.CreatedAt = nostr.Timestamp(time.Now().Add(2 * httpAuthWindow).Unix())
+			}),
+			wantErr: "too old or too far in the future",
+		},
+		{
+			name:    "u tag for another relay",
+			event:   authEvent(adminKey, func(e *nostr.Event) { e.Tags[0][1] = "https://evil.example.org/" }),
+			wantErr: "u tag does not match",
+		},
+		{
+			name:    "missing u tag",
+			event:   authEvent(adminKey, func(e *nostr.Event) { e.Tags = e.Tags[1:] }),
+			wantErr: "u tag does not match",
+		},
+		{
+			name:    "method tag mismatch",
+			event:   authEvent(adminKey, func(e *nostr.Event) { e.Tags[1][1] = "GET" }),
+			wantErr: "method tag does not match",
+		},
+		{
+			name:    "request method mismatch",
+			event:   authEvent(adminKey, nil),
+			method:  http.MethodPut,
+			wantErr: "method tag does not match",
+		},
+		{
+			name:    "payload mismatch",
+			event:   authEvent(adminKey, func(e *nostr.Event) { e.Tags[2][1] = strings.Repeat("0", 64) }),
+			wantErr: "payload tag does not match",
+		},
+		{
+			name:    "non-admin pubkey",
+			event:   authEvent(otherKey, nil),
+			wantErr: "not a relay admin",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			method := tt.method
+			if method == "" {
+				method = http.MethodPost
+			}
+			r := httptest.NewRequest(method, "https://relay.example.org/", nil)
+			header := tt.header
+			if tt.event != nil {
+				raw, _ := json.Marshal(tt.event)
+				header = "Nostr " + base64.StdEncoding.EncodeToString(raw)
+			}
+			if header != "" {
+				r.Header.Set("Authorization", header)
+			}
+
+			pubkey, err := api.authorize(r, body)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("Expected the request to be authorized, got: %v", err)
+				}
+				if pubkey != admin {
+					t.Errorf("Expected admin pubkey %s, got %s", admin, pubkey)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Expected error containing %q, got: %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestManagementAuthorizeIgnoresHost(t *testing.T) {
+	adminKey := nostr.GeneratePrivateKey()
+	admin, _ := nostr.GetPublicKey(adminKey)
+
+	// Without a service URL no request can be authorized, whatever Host it names
+	api := NewManagementAPI(khatru.NewRelay(), []string{admin}, nil, nil)
+
+	body := []byte(`{"method":"supportedmethods","params":[]}`)
+	sum := sha256.Sum256(body)
+	event := &nostr.Event{
+		Kind:      httpAuthKind,
+		CreatedAt: nostr.Now(),
+		Tags: nostr.Tags{
+			{"u", "https://attacker.example.org/"},
+			{"method", "POST"},
+			{"payload", hex.EncodeToString(sum[:])},
+		},
+	}
+	event.Sign(adminKey)
+	raw, _ := json.Marshal(event)
+
+	r := httptest.NewRequest(http.MethodPost, "https://attacker.example.org/", nil)
+	r.Header.Set("Authorization", "Nostr "+base64.StdEncoding.EncodeToString(raw))
+	if _, err := api.authorize(r, body); err == nil {
+		t.Fatal("Expected the request to be rejected without a service URL")
+	}
+}
@@ -0,0 +1,242 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/nbd-wtf/go-nostr"
+	"github.com/nbd-wtf/go-nostr/nip11"
+)
+
+// reportKind is the NIP-56 report kind; reports of archived events feed the
+// moderation queue instead of being stored as events
+const reportKind = 1984
+
+// ModerationItem is an event ID with the reason it was quarantined or reported
+type ModerationItem struct {
+	ID     string `json:"id" db:"id"`
+	Reason string `json:"reason,omitempty" db:"reason"`
+}
+
+// PostgreSQLModeration keeps the state managed through the NIP-86 API:
+// reports awaiting review, quarantined events, relay information overrides and
+// the audit log of admin actions
+type PostgreSQLModeration struct {
+	db *sqlx.DB
+
+	// settings caches the relay information overrides. khatru reads
+	// relay.Info without a lock, so overrides are applied to each NIP-11
+	// response instead of being written to it.
+	settingsMu sync.RWMutex
+	settings   map[string]string
+}
+
+// NewPostgreSQLModeration creates a moderation store
+func NewPostgreSQLModeration(db *sqlx.DB) *PostgreSQLModeration {
+	return &PostgreSQLModeration{db: db, settings: make(map[string]string)}
+}
+
+// Init creates the moderation tables
+func (m *PostgreSQLModeration) Init(ctx context.Context) error {
+	statements := []string{
+		`CREATE TABLE IF NOT EXISTS moderation_reports (
+			id TEXT PRIMARY KEY,
+			event_id TEXT NOT NULL,
+			reporter TEXT NOT NULL,
+			report_type TEXT NOT NULL DEFAULT '',
+			reason TEXT NOT NULL DEFAULT '',
+			created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+			resolved_at TIMESTAMPTZ
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_moderation_reports_open ON moderation_reports(event_id) WHERE resolved_at IS NULL`,
+		`CREATE TABLE IF NOT EXISTS quarantine (
+			id BIGSERIAL PRIMARY KEY,
+			event_id TEXT NOT NULL,
+			reason TEXT NOT NULL,
+			quarantined_by TEXT NOT NULL,
+			quarantined_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+			released_by TEXT NOT NULL DEFAULT '',
+			release_reason TEXT NOT NULL DEFAULT '',
+			released_at TIMESTAMPTZ
+		)`,
+		`CREATE UNIQUE INDEX IF NOT EXISTS idx_quarantine_active ON quarantine(event_id) WHERE released_at IS NULL`,
+		`CREATE TABLE IF NOT EXISTS relay_settings (
+			key TEXT PRIMARY KEY,
+			value TEXT NOT NULL
+		)`,
+		`CREATE TABLE IF NOT EXISTS admin_audit (
+			id BIGSERIAL PRIMARY KEY,
+			pubkey TEXT NOT NULL,
+			method TEXT NOT NULL,
+			params JSONB NOT NULL DEFAULT '[]',
+			error TEXT NOT NULL DEFAULT '',
+			created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+		)`,
+	}
+	for _, statement := range statements {
+		if _, err := m.db.ExecContext(ctx, statement); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// reportedEventID returns the archived event a NIP-56 report refers to and
+// the report type given in its e tag
+func reportedEventID(report *nostr.Event) (string, string, bool) {
+	tag := report.Tags.GetFirst([]string{"e", ""})
+	if tag == nil || !nostr.IsValid32ByteHex((*tag)[1]) {
+		return "", "", false
+	}
+	reportType := ""
+	if len(*tag) >= 3 {
+		reportType = (*tag)[2]
+	}
+	return (*tag)[1], reportType, true
+}
+
+// AddReport queues a NIP-56 report for moderation. It returns false when the
+// reported event is not in the archive.
+func (m *PostgreSQLModeration) AddReport(ctx context.Context, report *nostr.Event) (bool, error) {
+	eventID, reportType, ok := reportedEventID(report)
+	if !ok {
+		return false, nil
+	}
+
+	var archived bool
+	if err := m.db.GetContext(ctx, &archived, "SELECT EXISTS (SELECT 1 FROM event e WHERE e.id = $1)", eventID); err != nil {
+		return false, err
+	}
+	if !archived {
+		return false, nil
+	}
+
+	_, err := m.db.ExecContext(ctx, `
+		INSERT INTO moderation_reports (id, event_id, reporter, report_type, reason, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (id) DO NOTHING
+	`, report.ID, eventID, report.PubKey, reportType, report.Content, report.CreatedAt.Time())
+	return true, err
+}
+
+// NeedingModeration lists reported events that have not been quarantined or
+// allowed, oldest report first, with the reports' types and reasons
+func (m *PostgreSQLModeration) NeedingModeration(ctx context.Context) ([]ModerationItem, error) {
+	items := []ModerationItem{}
+	err := m.db.SelectContext(ctx, &items, `
+		SELECT event_id AS id,
+			string_agg(DISTINCT CASE WHEN reason = '' THEN report_type ELSE report_type || ': ' || reason END, '; ') AS reason
+		FROM moderation_reports
+		WHERE resolved_at IS NULL
+		GROUP BY event_id
+		ORDER BY MIN(created_at)
+	`)
+	return items, err
+}
+
+// Relay information fields that can be changed through the management API
+const (
+	settingRelayName        = "name"
+	settingRelayDescription = "description"
+	settingRelayIcon        = "icon"
+)
+
+// SetRelaySetting persists a relay information override and applies it to
+// NIP-11 responses
+func (m *PostgreSQLModeration) SetRelaySetting(ctx context.Context, key, value string) error {
+	_, err := m.db.ExecContext(ctx, `
+		INSERT INTO relay_settings (key, value) VALUES ($1, $2)
+		ON CONFLICT (key) DO UPDATE SET value = EXCLUDED.value
+	`, key, value)
+	if err != nil {
+		return err
+	}
+
+	m.settingsMu.Lock()
+	m.settings[key] = value
+	m.settingsMu.Unlock()
+	return nil
+}
+
+// LoadRelaySettings reads the persisted relay information overrides
+func (m *PostgreSQLModeration) LoadRelaySettings(ctx context.Context) error {
+	var settings []struct {
+		Key   string `db:"key"`
+		Value string `db:"value"`
+	}
+	if err := m.db.SelectContext(ctx, &settings, "SELECT key, value FROM relay_settings"); err != nil {
+		return err
+	}
+
+	m.settingsMu.Lock()
+	defer m.settingsMu.Unlock()
+	for _, setting := range settings {
+		m.settings[setting.Key] = setting.Value
+	}
+	return nil
+}
+
+// OverwriteRelayInformation overlays the relay information overrides on a
+// NIP-11 response
+func (m *PostgreSQLModeration) OverwriteRelayInformation(ctx context.Context, r *http.Request, info nip11.RelayInformationDocument) nip11.RelayInformationDocument {
+	m.settingsMu.RLock()
+	defer m.settingsMu.RUnlock()
+	for key, value := range m.settings {
+		applyRelaySetting(&info, key, value)
+	}
+	return info
+}
+
+func applyRelaySetting(info *nip11.RelayInformationDocument, key, value string) {
+	switch key {
+	case settingRelayName:
+		info.Name = value
+	case settingRelayDescription:
+		info.Description = value
+	case settingRelayIcon:
+		info.Icon = value
+	}
+}
+
+// AuditEntry records one management API call
+type AuditEntry struct {
+	ID        int64           `json:"id" db:"id"`
+	Pubkey    string          `json:"pubkey" db:"pubkey"`
+	Method    string          `json:"method" db:"method"`
+	Params    json.RawMessage `json:"params" db:"params"`
+	Error     string          `json:"error,omitempty" db:"error"`
+	CreatedAt time.Time       `json:"created_at" db:"created_at"`
+}
+
+// Audit records an admin action and its outcome
+func (m *PostgreSQLModeration) Audit(ctx context.Context, pubkey, method string, params json.RawMessage, actionErr error) error {
+	if len(params) == 0 {
+		params = json.RawMessage("[]")
+	}
+	errMessage := ""
+	if actionErr != nil {
+		errMessage = actionErr.Error()
+	}
+	_, err := m.db.ExecContext(ctx,
+		"INSERT INTO admin_audit (pubkey, method, params, error) VALUES ($1, $2, $3, $4)",
+		pubkey, method, string(params), errMessage)
+	if err != nil {
+		return fmt.Errorf("failed to record %s by %s: %w", method, pubkey, err)
+	}
+	return nil
+}
+
+// AuditLog returns the most recent admin actions, newest first
+func (m *PostgreSQLModeration) AuditLog(ctx context.Context, limit int) ([]AuditEntry, error) {
+	entries := []AuditEntry{}
+	err := m.db.SelectContext(ctx, &entries, `
+		SELECT id, pubkey, method, params, error, created_at FROM admin_audit
+		ORDER BY id DESC LIMIT $1
+	`, limit)
+	return entries, err
+}
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// errNotArchived is returned when quarantining an event the archive does not hold
+var errNotArchived = errors.New("event is not in the archive")
+
+// QuarantineRecord records one quarantine of an archived event. Quarantined
+// events keep their row, signature and content hash but are not served; a
+// release ends the quarantine without erasing the record.
+type QuarantineRecord struct {
+	ID            int64      `json:"id" db:"id"`
+	EventID       string     `json:"event_id" db:"event_id"`
+	Reason        string     `json:"reason" db:"reason"`
+	QuarantinedBy string     `json:"quarantined_by" db:"quarantined_by"`
+	QuarantinedAt time.Time  `json:"quarantined_at" db:"quarantined_at"`
+	ReleasedBy    string     `json:"released_by,omitempty" db:"released_by"`
+	ReleaseReason string     `json:"release_reason,omitempty" db:"release_reason"`
+	ReleasedAt    *time.Time `json:"released_at,omitempty" db:"released_at"`
+}
+
+// Quarantine hides an archived event from queries and counts, for example
+// after a copyright or privacy takedown notice, and resolves its reports.
+// Quarantining an event that is already quarantined keeps the first record.
+func (m *PostgreSQLModeration) Quarantine(ctx context.Context, eventID, reason, admin string) error {
+	tx, err := m.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var archived bool
+	if err := tx.GetContext(ctx, &archived, "SELECT EXISTS (SELECT 1 FROM event e WHERE e.id = $1)", eventID); err != nil {
+		return err
+	}
+	if !archived {
+		return errNotArchived
+	}
+
+	if _, err := tx.ExecContext(ctx, `
+		INSERT INTO quarantine (event_id, reason, quarantined_by) VALUES ($1, $2, $3)
+		ON CONFLICT (event_id) WHERE released_at IS NULL DO NOTHING
+	`, eventID, reason, admin); err != nil {
+		return err
+	}
+	if err := resolveReports(ctx, tx, eventID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// Release ends an event's quarantine, if any, and resolves its reports
+func (m *PostgreSQLModeration) Release(ctx context.Context, eventID, reason, admin string) error {
+	tx, err := m.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE quarantine SET released_at = now(), released_by = $2, release_reason = $3
+		WHERE event_id = $1 AND released_at IS NULL
+	`, eventID, admin, reason); err != nil {
+		return err
+	}
+	if err := resolveReports(ctx, tx, eventID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func resolveReports(ctx context.Context, tx *sqlx.Tx, eventID string) error {
+	_, err := tx.ExecContext(ctx,
+		"UPDATE moderation_reports SET resolved_at = now() WHERE event_id = $1 AND resolved_at IS NULL", eventID)
+	return err
+}
+
+// QuarantinedEvents lists events currently in quarantine, most recent first
+func (m *PostgreSQLModeration) QuarantinedEvents(ctx context.Context) ([]ModerationItem, error) {
+	items := []ModerationItem{}
+	err := m.db.SelectContext(ctx, &items, `
+		SELECT event_id AS id, reason FROM quarantine
+		WHERE released_at IS NULL
+		ORDER BY quarantined_at DESC
+	`)
+	return items, err
+}
+
+// QuarantineLog lists quarantine records, most recent first. Released
+// quarantines are included when all is set.
+func (m *PostgreSQLModeration) QuarantineLog(ctx context.Context, all bool) ([]QuarantineRecord, error) {
+	records := []QuarantineRecord{}
+	err := m.db.SelectContext(ctx, &records, `
+		SELECT id, event_id, reason, quarantined_by, quarantined_at, released_by, release_reason, released_at
+		FROM quarantine
+		WHERE $1 OR released_at IS NULL
+		ORDER BY quarantined_at DESC
+	`, all)
+	return records, err
+}
+
+// quarantineHandler lists quarantined events with who quarantined them, when
+// and why; ?all=true includes released quarantines
+func quarantineHandler(moderation *PostgreSQLModeration) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			w.Header().Set("Allow", http.MethodGet)
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		all := false
+		if a := r.URL.Query().Get("all"); a != "" {
+			var err error
+			if all, err = strconv.ParseBool(a); err != nil {
+				http.Error(w, "all must be true or false", http.StatusBadRequest)
+				return
+			}
+		}
+
+		records, err := moderation.QuarantineLog(r.Context(), all)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(records)
+	}
+}
@@ -0,0 +1,196 @@
+package main
+
+import (
+	"context"
+	"strings"
+
+	"github.com/fiatjaf/eventstore/postgresql"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"github.com/nbd-wtf/go-nostr"
+)
+
+// archiveQuery selects the archived events a client asked for. The rules the
+// archive adds to a filter, such as leaving out superseded versions, are part
+// of the SQL so that they apply before the limit and a REQ with a limit gets
+// as many events as match, up to that limit.
+type archiveQuery struct {
+	filter nostr.Filter
+	// latest leaves out versions replaced by a newer event at their address
+	latest bool
+	// withdrawn is a withdrawn: mode
+	withdrawn string
+}
+
+// newArchiveQuery reads a client's filter, restricted to the kinds the archive
+// serves, and the versions: and withdrawn: extensions in its search.
+// Quarantined, superseded and withdrawn events are left out in SQL, so a
+// limit counts only the events that are served.
+func newArchiveQuery(filter nostr.Filter) (archiveQuery, error) {
+	// Only academic events and the deletion requests made for them
+	restrictKinds(&filter)
+
+	withdrawn, err := withdrawnMode(&filter)
+	if err != nil {
+		return archiveQuery{}, err
+	}
+	versions, err := versionsMode(&filter)
+	if err != nil {
+		return archiveQuery{}, err
+	}
+	return archiveQuery{
+		filter:    filter,
+		latest:    versions == versionsLatest,
+		withdrawn: withdrawn,
+	}, nil
+}
+
+// where translates the query into SQL conditions on events aliased e, with ?
+// placeholders. Filters match as in the event store, including its limits on
+// the number of ids, authors, kinds and tag values; tag filters match any of
+// their values in any single-letter tag. It returns false when the filter
+// cannot match anything.
+func (q archiveQuery) where(store *postgresql.PostgresBackend) (string, []any, bool) {
+	filter := q.filter
+	var conditions []string
+	var params []any
+
+	if filter.IDs != nil {
+		ids := validHex(filter.IDs)
+		if len(filter.IDs) > store.QueryIDsLimit || len(ids) == 0 {
+			return "", nil, false
+		}
+		conditions = append(conditions, "e.id = ANY(?)")
+		params = append(params, pq.Array(ids))
+	}
+
+	if filter.Authors != nil {
+		authors := validHex(filter.Authors)
+		if len(filter.Authors) > store.QueryAuthorsLimit || len(authors) == 0 {
+			return "", nil, false
+		}
+		conditions = append(conditions, "e.pubkey = ANY(?)")
+		params = append(params, pq.Array(authors))
+	}
+
+	if filter.Kinds != nil {
+		if len(filter.Kinds) == 0 || len(filter.Kinds) > store.QueryKindsLimit {
+			return "", nil, false
+		}
+		kinds := make([]int64, len(filter.Kinds))
+		for i, kind := range filter.Kinds {
+			kinds[i] = int64(kind)
+		}
+		conditions = append(conditions, "e.kind = ANY(?)")
+		params = append(params, pq.Array(kinds))
+	}
+
+	var tagValues []string
+	for _, values := range filter.Tags {
+		if len(values) == 0 {
+			return "", nil, false
+		}
+		tagValues = append(tagValues, values...)
+	}
+	if len(tagValues) > store.QueryTagsLimit {
+		return "", nil, false
+	}
+	if len(tagValues) > 0 {
+		conditions = append(conditions, "e.tagvalues && ?")
+		params = append(params, pq.Array(tagValues))
+	}
+
+	if filter.Since != nil {
+		conditions = append(conditions, "e.created_at >= ?")
+		params = append(params, int64(*filter.Since))
+	}
+	if filter.Until != nil {
+		conditions = append(conditions, "e.created_at <= ?")
+		params = append(params, int64(*filter.Until))
+	}
+
+	// Quarantined events stay archived but are not served
+	conditions = append(conditions, "NOT EXISTS (SELECT 1 FROM quarantine q WHERE q.event_id = e.id AND q.released_at IS NULL)")
+
+	// Earlier versions are served on request, as NIP-33 clients expect one
+	// event per address
+	if q.latest {
+		conditions = append(conditions, "NOT "+isSuperseded)
+	}
+
+	switch q.withdrawn {
+	case withdrawnExclude:
+		conditions = append(conditions, "NOT EXISTS (SELECT 1 FROM withdrawals w WHERE w.event_id = e.id)")
+	case withdrawnOnly:
+		conditions = append(conditions, "EXISTS (SELECT 1 FROM withdrawals w WHERE w.event_id = e.id)")
+	}
+
+	return strings.Join(conditions, " AND "), params, true
+}
+
+// Events runs the query, newest first, up to the filter's limit or the
+// store's query limit
+func (q archiveQuery) Events(ctx context.Context, db *sqlx.DB, store *postgresql.PostgresBackend) (chan *nostr.Event, error) {
+	where, params, ok := q.where(store)
+	if !ok {
+		return sliceEvents(nil), nil
+	}
+
+	limit := q.filter.Limit
+	if limit < 1 || limit > store.QueryLimit {
+		limit = store.QueryLimit
+	}
+
+	rows, err := db.QueryContext(ctx, sqlx.Rebind(sqlx.DOLLAR, `
+		SELECT e.id, e.pubkey, e.created_at, e.kind, e.tags, e.content, e.sig FROM event e
+		WHERE `+where+`
+		ORDER BY e.created_at DESC, e.id
+		LIMIT ?
+	`), append(params, limit)...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	events, err := scanMatching(rows, nostr.Filter{})
+	if err != nil {
+		return nil, err
+	}
+	return sliceEvents(events), nil
+}
+
+// Count counts the events the query matches, ignoring the filter's limit
+func (q archiveQuery) Count(ctx context.Context, db *sqlx.DB, store *postgresql.PostgresBackend) (int64, error) {
+	where, params, ok := q.where(store)
+	if !ok {
+		return 0, nil
+	}
+
+	var count int64
+	err := db.GetContext(ctx, &count, sqlx.Rebind(sqlx.DOLLAR, `
+		SELECT COUNT(*) FROM event e
+		WHERE `+where), params...)
+	return count, err
+}
+
+// validHex keeps the lowercase 32-byte hex values, as the event store does
+// for ids and authors
+func validHex(values []string) []string {
+	valid := make([]string, 0, len(values))
+	for _, value := range values {
+		if value = strings.ToLower(value); nostr.IsValid32ByteHex(value) {
+			valid = append(valid, value)
+		}
+	}
+	return valid
+}
+
+// sliceEvents sends events on a closed channel
+func sliceEvents(events []*nostr.Event) chan *nostr.Event {
+	ch := make(chan *nostr.Event, len(events))
+	for _, event := range events {
+		ch <- event
+	}
+	close(ch)
+	return ch
+}
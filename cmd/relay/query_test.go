@@ -0,0 +1,138 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/fiatjaf/eventstore/postgresql"
+	"github.com/nbd-wtf/go-nostr"
+)
+
+func TestArchiveQueryConditions(t *testing.T) {
+	store := &postgresql.PostgresBackend{
+		QueryLimit:        500,
+		QueryIDsLimit:     2,
+		QueryAuthorsLimit: 2,
+		QueryKindsLimit:   2,
+		QueryTagsLimit:    2,
+	}
+	id := strings.Repeat("ab", 32)
+	since := nostr.Timestamp(1700000000)
+
+	tests := []struct {
+		name    string
+		query   archiveQuery
+		match   bool
+		want    []string
+		exclude []string
+		params  int
+	}{
+		{
+			name:    "latest versions leave out superseded events before the limit",
+			query:   archiveQuery{filter: nostr.Filter{Kinds: []int{30023}}, latest: true, withdrawn: withdrawnInclude},
+			match:   true,
+			want:    []string{"e.kind = ANY(?)", "NOT " + isSuperseded, "FROM quarantine q"},
+			exclude: []string{"withdrawals"},
+			params:  1,
+		},
+		{
+			name:    "every version keeps superseded events",
+			query:   archiveQuery{filter: nostr.Filter{IDs: []string{strings.ToUpper(id)}}, withdrawn: withdrawnInclude},
+			match:   true,
+			want:    []string{"e.id = ANY(?)"},
+			exclude: []string{isSuperseded},
+			params:  1,
+		},
+		{
+			name:   "withdrawn content can be left out",
+			query:  archiveQuery{filter: nostr.Filter{Since: &since}, withdrawn: withdrawnExclude},
+			match:  true,
+			want:   []string{"e.created_at >= ?", "NOT EXISTS (SELECT 1 FROM withdrawals"},
+			params: 1,
+		},
+		{
+			name:    "only withdrawn content",
+			query:   archiveQuery{filter: nostr.Filter{Tags: nostr.TagMap{"e": {id}}}, withdrawn: withdrawnOnly},
+			match:   true,
+			want:    []string{"e.tagvalues && ?", "AND EXISTS (SELECT 1 FROM withdrawals"},
+			exclude: []string{"NOT EXISTS (SELECT 1 FROM withdrawals"},
+			params:  1,
+		},
+		{
+			name:  "invalid ids match nothing",
+			query: archiveQuery{filter: nostr.Filter{IDs: []string{"not-hex"}}},
+		},
+		{
+			name:  "too many authors match nothing",
+			query: archiveQuery{filter: nostr.Filter{Authors: []string{id, id, id}}},
+		},
+		{
+			name:  "empty kinds match nothing",
+			query: archiveQuery{filter: nostr.Filter{Kinds: []int{}}},
+		},
+		{
+			name:  "too many tag values match nothing",
+			query: archiveQuery{filter: nostr.Filter{Tags: nostr.TagMap{"e": {"a", "b"}, "p": {"c"}}}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			where, params, ok := tt.query.where(store)
+			if ok != tt.match {
+				t.Fatalf("Expected match=%v, got %v", tt.match, ok)
+			}
+			if !ok {
+				return
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(where, want) {
+					t.Errorf("Expected conditions to contain %q, got:\n%s", want, where)
+				}
+			}
+			for _, exclude := range tt.exclude {
+				if strings.Contains(where, exclude) {
+					t.Errorf("Expected conditions not to contain %q, got:\n%s", exclude, where)
+				}
+			}
+			if len(params) != tt.params || strings.Count(where, "?") != tt.params {
+				t.Errorf("Expected %d params, got %d params and %d placeholders", tt.params, len(params), strings.Count(where, "?"))
+			}
+		})
+	}
+}
+
+func TestNewArchiveQuery(t *testing.T) {
+	tests := []struct {
+		name      string
+		search    string
+		ids       []string
+		latest    bool
+		withdrawn string
+	}{
+		{"latest versions by default", "", nil, true, withdrawnInclude},
+		{"ids ask for every version", "", []string{strings.Repeat("ab", 32)}, false, withdrawnInclude},
+		{"every version", "versions:all", nil, false, withdrawnInclude},
+		{"every version without withdrawn content", "versions:all withdrawn:exclude", nil, false, withdrawnExclude},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			query, err := newArchiveQuery(nostr.Filter{IDs: tt.ids, Search: tt.search})
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if query.latest != tt.latest || query.withdrawn != tt.withdrawn {
+				t.Errorf("Expected latest=%v withdrawn=%s, got latest=%v withdrawn=%s",
+					tt.latest, tt.withdrawn, query.latest, query.withdrawn)
+			}
+			if query.filter.Search != "" {
+				t.Errorf("Expected the extensions to be removed from the search, got %q", query.filter.Search)
+			}
+		})
+	}
+
+	if _, err := newArchiveQuery(nostr.Filter{Search: "versions:some"}); err == nil {
+		t.Error("Expected an invalid versions: value to be rejected")
+	}
+}
@@ -0,0 +1,340 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+
+	"github.com/connorslagle/nark-archival/internal/policies"
+)
+
+// rateLimitLockSpace namespaces the advisory locks taken per pubkey
+const rateLimitLockSpace = 31428
+
+// reservationTimeout is how long an uncommitted reservation counts against
+// the limits; reservations left behind by a crashed relay expire after it
+const reservationTimeout = 5 * time.Minute
+
+// PostgreSQLRateLimiter keeps sliding-window and token-bucket rate limit
+// state in PostgreSQL so limits survive restarts and are shared by every
+// relay instance
+type PostgreSQLRateLimiter struct {
+	db         *sqlx.DB
+	config     *policies.RateLimitConfig
+	reputation *policies.ReputationLimits
+}
+
+// NewPostgreSQLRateLimiter creates a rate limiter backed by PostgreSQL
+func NewPostgreSQLRateLimiter(db *sqlx.DB, config *policies.RateLimitConfig) *PostgreSQLRateLimiter {
+	if config == nil {
+		config = policies.DefaultRateLimitConfig()
+	}
+	return &PostgreSQLRateLimiter{db: db, config: config}
+}
+
+// Init creates the rate limit table
+func (rl *PostgreSQLRateLimiter) Init(ctx context.Context) error {
+	_, err := rl.db.ExecContext(ctx, `
+		CREATE TABLE IF NOT EXISTS rate_limit_events (
+			id BIGSERIAL PRIMARY KEY,
+			pubkey TEXT NOT NULL,
+			kind INTEGER NOT NULL,
+			created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+			committed BOOLEAN NOT NULL DEFAULT FALSE
+		)
+	`)
+	if err != nil {
+		return err
+	}
+
+	_, err = rl.db.ExecContext(ctx, `
+		CREATE INDEX IF NOT EXISTS idx_rate_limit_pubkey ON rate_limit_events(pubkey, kind, created_at)
+	`)
+	if err != nil {
+		return err
+	}
+
+	// One row per pubkey and kind for kinds limited in token-bucket mode
+	_, err = rl.db.ExecContext(ctx, `
+		CREATE TABLE IF NOT EXISTS rate_limit_buckets (
+			pubkey TEXT NOT NULL,
+			kind INTEGER NOT NULL,
+			tokens DOUBLE PRECISION NOT NULL,
+			refilled_at TIMESTAMPTZ NOT NULL,
+			PRIMARY KEY (pubkey, kind)
+		)
+	`)
+	return err
+}
+
+// SetReputation scales kind-specific limits by each pubkey's reputation tier
+func (rl *PostgreSQLRateLimiter) SetReputation(reputation *policies.ReputationLimits) {
+	rl.reputation = reputation
+}
+
+// AllowRequest checks the limits and consumes quota immediately
+func (rl *PostgreSQLRateLimiter) AllowRequest(ctx context.Context, pubkey string, eventKind int) error {
+	reservation, err := rl.Reserve(ctx, pubkey, eventKind)
+	if err != nil {
+		return err
+	}
+	return reservation.Commit(ctx)
+}
+
+// Reserve checks the general and kind-specific windows and records a pending
+// entry. Requests for the same pubkey are serialized with an advisory lock so
+// concurrent instances cannot both take the last slot.
+func (rl *PostgreSQLRateLimiter) Reserve(ctx context.Context, pubkey string, eventKind int) (policies.Reservation, error) {
+	kindLimits, err := rl.reputation.Apply(ctx, pubkey, rl.config)
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := rl.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check rate limit: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, "SELECT pg_advisory_xact_lock($1, hashtext($2))", rateLimitLockSpace, pubkey); err != nil {
+		return nil, fmt.Errorf("failed to lock rate limit state: %w", err)
+	}
+
+	// Entries older than the longest window no longer affect any limit
+	if _, err := tx.ExecContext(ctx,
+		"DELETE FROM rate_limit_events WHERE pubkey = $1 AND created_at < now() - $2::float8 * interval '1 second'",
+		pubkey, rl.longestWindow().Seconds()); err != nil {
+		return nil, fmt.Errorf("failed to expire rate limit entries: %w", err)
+	}
+
+	count, err := rl.countSince(ctx, tx, pubkey, nil, rl.config.WindowDuration)
+	if err != nil {
+		return nil, err
+	}
+	if count >= rl.config.EventsPerWindow {
+		return nil, policies.RateLimitError(rl.config.EventsPerWindow, rl.config.WindowDuration, count)
+	}
+
+	reservation := &postgresReservation{db: rl.db, pubkey: pubkey, kind: eventKind}
+
+	if kindLimit, hasLimit := kindLimits[eventKind]; hasLimit && !kindLimit.ExemptByProofOfWork(policies.ProofOfWork(ctx)) {
+		if kindLimit.IsTokenBucket() {
+			if err := rl.takeToken(ctx, tx, pubkey, eventKind, kindLimit); err != nil {
+				return nil, err
+			}
+			reservation.bucket = &kindLimit
+		} else {
+			count, err := rl.countSince(ctx, tx, pubkey, &eventKind, kindLimit.WindowDuration)
+			if err != nil {
+				return nil, err
+			}
+			if count >= kindLimit.EventsPerWindow {
+				return nil, policies.KindRateLimitError(eventKind, kindLimit.EventsPerWindow, kindLimit.WindowDuration, count)
+			}
+		}
+	}
+
+	if err := tx.GetContext(ctx, &reservation.id,
+		"INSERT INTO rate_limit_events (pubkey, kind) VALUES ($1, $2) RETURNING id",
+		pubkey, eventKind); err != nil {
+		return nil, fmt.Errorf("failed to reserve rate limit: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to reserve rate limit: %w", err)
+	}
+
+	return reservation, nil
+}
+
+// takeToken removes one token from the pubkey's bucket for a kind, creating
+// a full bucket on first use. The database clock is used so every instance
+// refills buckets at the same rate.
+func (rl *PostgreSQLRateLimiter) takeToken(ctx context.Context, tx *sqlx.Tx, pubkey string, kind int, limit policies.KindLimit) error {
+	var now time.Time
+	if err := tx.GetContext(ctx, &now, "SELECT now()"); err != nil {
+		return fmt.Errorf("failed to read token bucket: %w", err)
+	}
+
+	bucket := policies.NewTokenBucket(limit, now)
+	err := tx.QueryRowxContext(ctx,
+		"SELECT tokens, refilled_at FROM rate_limit_buckets WHERE pubkey = $1 AND kind = $2",
+		pubkey, kind).Scan(&bucket.Tokens, &bucket.Refilled)
+	if err != nil && err != sql.ErrNoRows {
+		return fmt.Errorf("failed to read token bucket: %w", err)
+	}
+
+	ok, retryAfter := bucket.Take(limit, now)
+	if !ok {
+		return policies.KindTokenBucketError(kind, limit, bucket, retryAfter)
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO rate_limit_buckets (pubkey, kind, tokens, refilled_at) VALUES ($1, $2, $3, $4)
+		ON CONFLICT (pubkey, kind) DO UPDATE SET tokens = EXCLUDED.tokens, refilled_at = EXCLUDED.refilled_at
+	`, pubkey, kind, bucket.Tokens, bucket.Refilled)
+	if err != nil {
+		return fmt.Errorf("failed to update token bucket: %w", err)
+	}
+	return nil
+}
+
+// countSince counts committed and recently reserved entries within window,
+// optionally restricted to one kind
+func (rl *PostgreSQLRateLimiter) countSince(ctx context.Context, q sqlx.QueryerContext, pubkey string, kind *int, window time.Duration) (int, error) {
+	count, _, err := rl.windowUsage(ctx, q, pubkey, kind, window)
+	return count, err
+}
+
+// windowUsage returns the number of entries counted within window and when
+// the oldest of them was recorded
+func (rl *PostgreSQLRateLimiter) windowUsage(ctx context.Context, q sqlx.QueryerContext, pubkey string, kind *int, window time.Duration) (int, time.Time, error) {
+	var usage struct {
+		Count  int          `db:"count"`
+		Oldest sql.NullTime `db:"oldest"`
+	}
+	err := sqlx.GetContext(ctx, q, &usage, `
+		SELECT COUNT(*) AS count, MIN(created_at) AS oldest FROM rate_limit_events
+		WHERE pubkey = $1
+		  AND ($2::integer IS NULL OR kind = $2)
+		  AND created_at > now() - $3::float8 * interval '1 second'
+		  AND (committed OR created_at > now() - $4::float8 * interval '1 second')
+	`, pubkey, kind, window.Seconds(), reservationTimeout.Seconds())
+	if err != nil {
+		return 0, time.Time{}, fmt.Errorf("failed to count rate limit entries: %w", err)
+	}
+	return usage.Count, usage.Oldest.Time, nil
+}
+
+// Status reports the pubkey's usage without consuming quota
+func (rl *PostgreSQLRateLimiter) Status(ctx context.Context, pubkey string) (*policies.RateLimitStatus, error) {
+	kindLimits, err := rl.reputation.Apply(ctx, pubkey, rl.config)
+	if err != nil {
+		return nil, err
+	}
+
+	status := policies.NewRateLimitStatus(pubkey)
+	used, oldest, err := rl.windowUsage(ctx, rl.db, pubkey, nil, rl.config.WindowDuration)
+	if err != nil {
+		return nil, err
+	}
+	status.General = policies.WindowStatus("general", rl.config.GeneralLimit(), used, oldest)
+
+	for _, kind := range policies.AcademicKinds {
+		key := policies.EventTypeKey(kind)
+		limit, hasLimit := kindLimits[kind]
+		switch {
+		case !hasLimit:
+			status.Kinds[key] = status.General
+		case limit.IsTokenBucket():
+			var now time.Time
+			if err := rl.db.GetContext(ctx, &now, "SELECT now()"); err != nil {
+				return nil, fmt.Errorf("failed to read token bucket: %w", err)
+			}
+			bucket := policies.NewTokenBucket(limit, now)
+			err := rl.db.QueryRowxContext(ctx,
+				"SELECT tokens, refilled_at FROM rate_limit_buckets WHERE pubkey = $1 AND kind = $2",
+				pubkey, kind).Scan(&bucket.Tokens, &bucket.Refilled)
+			if err != nil && err != sql.ErrNoRows {
+				return nil, fmt.Errorf("failed to read token bucket: %w", err)
+			}
+			status.Kinds[key] = policies.BucketStatus(limit, bucket, now)
+		default:
+			kind := kind
+			used, oldest, err := rl.windowUsage(ctx, rl.db, pubkey, &kind, limit.WindowDuration)
+			if err != nil {
+				return nil, err
+			}
+			status.Kinds[key] = policies.WindowStatus("kind", limit, used, oldest)
+		}
+	}
+
+	return status, nil
+}
+
+// longestWindow returns the longest configured window
+func (rl *PostgreSQLRateLimiter) longestWindow() time.Duration {
+	longest := rl.config.WindowDuration
+	for _, limit := range rl.config.KindLimits {
+		if limit.WindowDuration > longest {
+			longest = limit.WindowDuration
+		}
+	}
+	return longest
+}
+
+// Reset clears rate limit data for a pubkey
+func (rl *PostgreSQLRateLimiter) Reset(pubkey string) {
+	if _, err := rl.db.Exec("DELETE FROM rate_limit_events WHERE pubkey = $1", pubkey); err != nil {
+		log.Printf("Failed to reset rate limits for %s: %v", pubkey, err)
+	}
+	if _, err := rl.db.Exec("DELETE FROM rate_limit_buckets WHERE pubkey = $1", pubkey); err != nil {
+		log.Printf("Failed to reset token buckets for %s: %v", pubkey, err)
+	}
+}
+
+// postgresReservation is a pending rate limit entry, plus the token taken
+// from a bucket when the kind uses token-bucket mode
+type postgresReservation struct {
+	db     *sqlx.DB
+	id     int64
+	pubkey string
+	kind   int
+	bucket *policies.KindLimit
+}
+
+// Commit marks the entry as consumed
+func (r *postgresReservation) Commit(ctx context.Context) error {
+	_, err := r.db.ExecContext(ctx, "UPDATE rate_limit_events SET committed = TRUE WHERE id = $1", r.id)
+	return err
+}
+
+// Rollback removes the entry and returns the bucket token unless the
+// reservation has already been committed or rolled back
+func (r *postgresReservation) Rollback(ctx context.Context) error {
+	result, err := r.db.ExecContext(ctx, "DELETE FROM rate_limit_events WHERE id = $1 AND NOT committed", r.id)
+	if err != nil {
+		return err
+	}
+	if removed, err := result.RowsAffected(); err != nil || removed == 0 || r.bucket == nil {
+		return err
+	}
+
+	_, err = r.db.ExecContext(ctx,
+		"UPDATE rate_limit_buckets SET tokens = LEAST(tokens + 1, $3) WHERE pubkey = $1 AND kind = $2",
+		r.pubkey, r.kind, r.bucket.BurstSize())
+	return err
+}
+
+// rateLimitHandler serves GET /ratelimit/{pubkey} with the pubkey's usage of
+// each limit and when the next slot frees up
+func rateLimitHandler(limiter policies.RateLimiter) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			w.Header().Set("Allow", http.MethodGet)
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		pubkey, ok := pubkeyFromPath(r.URL.Path, "/ratelimit/")
+		if !ok {
+			http.Error(w, "expected /ratelimit/{pubkey} with a hex or npub pubkey", http.StatusBadRequest)
+			return
+		}
+
+		status, err := limiter.Status(r.Context(), pubkey)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(status)
+	}
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+
+	"github.com/connorslagle/nark-archival/internal/policies"
+)
+
+// PostgreSQLReputationProvider computes reputation from the events already
+// stored in the archive
+type PostgreSQLReputationProvider struct {
+	db *sqlx.DB
+}
+
+// NewPostgreSQLReputationProvider creates a reputation provider reading the event table
+func NewPostgreSQLReputationProvider(db *sqlx.DB) *PostgreSQLReputationProvider {
+	return &PostgreSQLReputationProvider{db: db}
+}
+
+// Reputation counts accepted papers, reviews of those papers by other
+// pubkeys, and finds the pubkey's first stored event. A paper or review
+// counts once however many versions it has.
+func (rp *PostgreSQLReputationProvider) Reputation(ctx context.Context, pubkey string) (*policies.Reputation, error) {
+	var row struct {
+		Papers          int           `db:"papers"`
+		ReviewsReceived int           `db:"reviews_received"`
+		FirstSeen       sql.NullInt64 `db:"first_seen"`
+	}
+
+	err := rp.db.GetContext(ctx, &row, `
+		SELECT
+			(SELECT COUNT(*) FROM event
+				WHERE pubkey = $1 AND kind = $2 AND id NOT IN (`+supersededEvents+`)
+			) AS papers,
+			(SELECT COUNT(*) FROM event r
+				WHERE r.kind = $3 AND r.pubkey <> $1 AND r.id NOT IN (`+supersededEvents+`)
+				  AND r.tagvalues && (SELECT array_agg(p.id) FROM event p WHERE p.pubkey = $1 AND p.kind = $2)
+			) AS reviews_received,
+			(SELECT MIN(created_at) FROM event e WHERE e.pubkey = $1) AS first_seen
+	`, pubkey, AcademicPaperKind, AcademicReviewKind)
+	if err != nil {
+		return nil, err
+	}
+
+	rep := &policies.Reputation{
+		Papers:          row.Papers,
+		ReviewsReceived: row.ReviewsReceived,
+	}
+	if row.FirstSeen.Valid {
+		rep.FirstSeen = time.Unix(row.FirstSeen.Int64, 0).UTC()
+	}
+	return rep, nil
+}
+
+// reputationHandler serves GET /reputation/{pubkey} with the pubkey's
+// reputation, tier and effective kind limits
+func reputationHandler(reputation *policies.ReputationLimits, config *policies.RateLimitConfig) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			w.Header().Set("Allow", http.MethodGet)
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		if reputation == nil {
+			http.Error(w, "reputation-weighted rate limits are not enabled", http.StatusNotFound)
+			return
+		}
+
+		pubkey, ok := pubkeyFromPath(r.URL.Path, "/reputation/")
+		if !ok {
+			http.Error(w, "expected /reputation/{pubkey} with a hex or npub pubkey", http.StatusBadRequest)
+			return
+		}
+
+		status, err := reputation.Status(r.Context(), pubkey, config)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(status)
+	}
+}
@@ -0,0 +1,230 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/nbd-wtf/go-nostr"
+	"github.com/nbd-wtf/go-nostr/nip19"
+
+	"github.com/connorslagle/nark-archival/internal/policies"
+)
+
+// Values of the versions: search extension, which selects whether queries
+// return only the current version at each address or every archived version
+const (
+	versionsLatest = "latest"
+	versionsAll    = "all"
+)
+
+// supersededEvents selects the versions replaced by a newer event at the same
+// address. As in NIP-01, the lowest id wins between equal timestamps.
+const supersededEvents = `
+	SELECT v.event_id FROM event_versions v
+	WHERE EXISTS (
+		SELECT 1 FROM event_versions n
+		WHERE n.address = v.address
+		AND (n.created_at > v.created_at OR (n.created_at = v.created_at AND n.event_id < v.event_id))
+	)`
+
+// isSuperseded is true for an event aliased e that has been replaced by a
+// newer event at the same address
+const isSuperseded = `EXISTS (
+	SELECT 1 FROM event_versions v
+	JOIN event_versions n ON n.address = v.address
+	AND (n.created_at > v.created_at OR (n.created_at = v.created_at AND n.event_id < v.event_id))
+	WHERE v.event_id = e.id
+)`
+
+// PostgreSQLVersions indexes academic events by NIP-33 address. The event
+// store never replaces a version, so the archive keeps every version published
+// at an address in the event table and serves the latest one unless a client
+// asks for all of them.
+type PostgreSQLVersions struct {
+	db *sqlx.DB
+}
+
+// NewPostgreSQLVersions creates a version index
+func NewPostgreSQLVersions(db *sqlx.DB) *PostgreSQLVersions {
+	return &PostgreSQLVersions{db: db}
+}
+
+// Init creates the version index and the event_history view of superseded
+// versions, and adds any archived event missing from the index
+func (vs *PostgreSQLVersions) Init(ctx context.Context) error {
+	_, err := vs.db.ExecContext(ctx, `
+		CREATE TABLE IF NOT EXISTS event_versions (
+			event_id TEXT PRIMARY KEY,
+			address TEXT NOT NULL,
+			created_at INTEGER NOT NULL
+		)
+	`)
+	if err != nil {
+		return err
+	}
+
+	_, err = vs.db.ExecContext(ctx, `
+		CREATE INDEX IF NOT EXISTS idx_event_versions_address ON event_versions(address, created_at DESC)
+	`)
+	if err != nil {
+		return err
+	}
+
+	// Events stored before versioning, or whose version failed to record
+	_, err = vs.db.ExecContext(ctx, `
+		INSERT INTO event_versions (event_id, address, created_at)
+		SELECT e.id, e.kind || ':' || e.pubkey || ':' || d.value, e.created_at
+		FROM event e,
+		LATERAL (SELECT t->>1 AS value FROM jsonb_array_elements(e.tags) t WHERE t->>0 = 'd' LIMIT 1) d
+		WHERE e.kind >= 30000 AND e.kind < 40000
+		ON CONFLICT DO NOTHING
+	`)
+	if err != nil {
+		return err
+	}
+
+	// Superseded versions stay in the event table; the view lists them
+	_, err = vs.db.ExecContext(ctx, `
+		CREATE OR REPLACE VIEW event_history AS
+		SELECT e.id, e.pubkey, e.created_at, e.kind, e.tags, e.content, e.sig, v.address
+		FROM event e JOIN event_versions v ON v.event_id = e.id
+		WHERE `+isSuperseded+`
+	`)
+	return err
+}
+
+// Record adds a stored event to the index under its address
+func (vs *PostgreSQLVersions) Record(ctx context.Context, event *nostr.Event) error {
+	address := policies.EventAddress(event)
+	if address == "" {
+		return nil
+	}
+	_, err := vs.db.ExecContext(ctx, `
+		INSERT INTO event_versions (event_id, address, created_at) VALUES ($1, $2, $3)
+		ON CONFLICT DO NOTHING
+	`, event.ID, address, int64(event.CreatedAt))
+	return err
+}
+
+// versionsMode removes a versions:latest or versions:all extension from the
+// filter's search and returns it. Filters naming event ids get every version
+// they ask for; otherwise only the latest version is served by default.
+func versionsMode(filter *nostr.Filter) (string, error) {
+	mode := versionsLatest
+	if len(filter.IDs) > 0 {
+		mode = versionsAll
+	}
+	value, err := searchExtension(filter, "versions", versionsLatest, versionsAll)
+	if value != "" {
+		mode = value
+	}
+	return mode, err
+}
+
+// searchExtension removes every name:value term (in the style of NIP-50)
+// from the filter's search and returns the last value, or "" when there is
+// none. Values not in allowed are an error.
+func searchExtension(filter *nostr.Filter, name string, allowed ...string) (string, error) {
+	var value string
+	var rest []string
+	for _, term := range strings.Fields(filter.Search) {
+		v, ok := strings.CutPrefix(term, name+":")
+		if !ok {
+			rest = append(rest, term)
+			continue
+		}
+		valid := false
+		for _, a := range allowed {
+			valid = valid || v == a
+		}
+		if !valid {
+			return "", fmt.Errorf("%s: must be %s", name, strings.Join(allowed, " or "))
+		}
+		value = v
+	}
+	filter.Search = strings.Join(rest, " ")
+	return value, nil
+}
+
+// versionsHandler lists the versions archived at a NIP-33 address, given as
+// <kind>:<pubkey>:<d> or as an naddr. With ?at= (a unix timestamp) it returns
+// the version that was current at that time instead.
+func versionsHandler(policyEngine *policies.PolicyEngine) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			w.Header().Set("Allow", http.MethodGet)
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		address, ok := addressFromPath(r.URL.Path, "/versions/")
+		if !ok {
+			http.Error(w, "expected /versions/{kind:pubkey:d} or /versions/{naddr}", http.StatusBadRequest)
+			return
+		}
+
+		if at := r.URL.Query().Get("at"); at != "" {
+			timestamp, err := strconv.ParseInt(at, 10, 64)
+			if err != nil {
+				http.Error(w, "at must be a unix timestamp", http.StatusBadRequest)
+				return
+			}
+			event, err := policyEngine.VersionAt(r.Context(), address, nostr.Timestamp(timestamp))
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			if event == nil {
+				http.Error(w, "no version archived at this address at that time", http.StatusNotFound)
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(event)
+			return
+		}
+
+		history, err := policyEngine.VersionHistory(r.Context(), address)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if history == nil {
+			http.Error(w, "no versions archived at this address", http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(history)
+	}
+}
+
+// addressFromPath extracts a NIP-33 address or naddr following prefix in path
+// and returns it as <kind>:<pubkey>:<d>
+func addressFromPath(path, prefix string) (string, bool) {
+	address := strings.TrimPrefix(path, prefix)
+	if address == path || address == "" {
+		return "", false
+	}
+
+	if strings.HasPrefix(address, "naddr1") {
+		kind, value, err := nip19.Decode(address)
+		if err != nil || kind != "naddr" {
+			return "", false
+		}
+		pointer, ok := value.(nostr.EntityPointer)
+		if !ok {
+			return "", false
+		}
+		address = fmt.Sprintf("%d:%s:%s", pointer.Kind, pointer.PublicKey, pointer.Identifier)
+	}
+
+	kind, pubkey, d, ok := policies.ParseAddress(address)
+	if !ok {
+		return "", false
+	}
+	return fmt.Sprintf("%d:%s:%s", kind, strings.ToLower(pubkey), d), true
+}
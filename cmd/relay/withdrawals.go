@@ -0,0 +1,251 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/fiatjaf/eventstore"
+	"github.com/fiatjaf/eventstore/postgresql"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"github.com/nbd-wtf/go-nostr"
+
+	"github.com/connorslagle/nark-archival/internal/policies"
+)
+
+// deletionKind is the NIP-09 deletion request kind
+const deletionKind = 5
+
+// Values of the withdrawn: search extension, which selects how content the
+// author asked to withdraw is treated in queries
+const (
+	withdrawnInclude = "include"
+	withdrawnExclude = "exclude"
+	withdrawnOnly    = "only"
+)
+
+// Withdrawal links a NIP-09 deletion request to one of the events it targets.
+// The archive keeps the event; the withdrawal records that its author asked
+// for it to be removed.
+type Withdrawal struct {
+	DeletionID  string    `json:"deletion_id" db:"deletion_id"`
+	EventID     string    `json:"event_id" db:"event_id"`
+	Pubkey      string    `json:"pubkey" db:"pubkey"`
+	Reason      string    `json:"reason,omitempty" db:"reason"`
+	RequestedAt time.Time `json:"requested_at" db:"requested_at"`
+}
+
+// PostgreSQLWithdrawals stores deletion requests as provenance
+type PostgreSQLWithdrawals struct {
+	db    *sqlx.DB
+	store *postgresql.PostgresBackend
+}
+
+// NewPostgreSQLWithdrawals creates a withdrawal store. Deletion requests are
+// saved in the event store so clients can fetch them like any other event.
+func NewPostgreSQLWithdrawals(db *sqlx.DB, store *postgresql.PostgresBackend) *PostgreSQLWithdrawals {
+	return &PostgreSQLWithdrawals{db: db, store: store}
+}
+
+// Init creates the withdrawals table
+func (ws *PostgreSQLWithdrawals) Init(ctx context.Context) error {
+	_, err := ws.db.ExecContext(ctx, `
+		CREATE TABLE IF NOT EXISTS withdrawals (
+			deletion_id TEXT NOT NULL,
+			event_id TEXT NOT NULL,
+			pubkey TEXT NOT NULL,
+			reason TEXT NOT NULL DEFAULT '',
+			requested_at TIMESTAMPTZ NOT NULL,
+			PRIMARY KEY (deletion_id, event_id)
+		)
+	`)
+	if err != nil {
+		return err
+	}
+
+	_, err = ws.db.ExecContext(ctx, `
+		CREATE INDEX IF NOT EXISTS idx_withdrawals_event ON withdrawals(event_id)
+	`)
+	return err
+}
+
+// Stored reports whether a deletion request has already been saved
+func (ws *PostgreSQLWithdrawals) Stored(ctx context.Context, deletionID string) (bool, error) {
+	var stored bool
+	err := ws.db.GetContext(ctx, &stored, "SELECT EXISTS (SELECT 1 FROM event WHERE id = $1)", deletionID)
+	return stored, err
+}
+
+// Save stores a deletion request in the event store. It returns false when
+// the request was already saved, for example by a concurrent submission.
+func (ws *PostgreSQLWithdrawals) Save(ctx context.Context, deletion *nostr.Event) (bool, error) {
+	if err := ws.store.SaveEvent(ctx, deletion); err != nil {
+		if errors.Is(err, eventstore.ErrDupEvent) {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+// Link records that a deletion request targets an event
+func (ws *PostgreSQLWithdrawals) Link(ctx context.Context, deletion *nostr.Event, eventID string) error {
+	_, err := ws.db.ExecContext(ctx, `
+		INSERT INTO withdrawals (deletion_id, event_id, pubkey, reason, requested_at)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT DO NOTHING
+	`, deletion.ID, eventID, deletion.PubKey, deletion.Content, deletion.CreatedAt.Time())
+	return err
+}
+
+// Targets returns the archived events a deletion request names in e tags
+func (ws *PostgreSQLWithdrawals) Targets(ctx context.Context, deletion *nostr.Event) ([]*nostr.Event, error) {
+	var ids []string
+	for _, tag := range deletion.Tags {
+		if len(tag) >= 2 && tag[0] == "e" {
+			ids = append(ids, strings.ToLower(tag[1]))
+		}
+	}
+	if len(ids) == 0 {
+		return nil, nil
+	}
+
+	rows, err := ws.db.QueryContext(ctx, `
+		SELECT e.id, e.pubkey, e.created_at, e.kind, e.tags, e.content, e.sig FROM event e
+		WHERE e.id = ANY($1)
+	`, pq.Array(ids))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	return scanMatching(rows, nostr.Filter{})
+}
+
+// LinkAddresses links a deletion request to the versions at the addresses
+// it names in a tags, up to the request. Addresses are checked by
+// checkDeletionTargets first, so they are the author's own.
+func (ws *PostgreSQLWithdrawals) LinkAddresses(ctx context.Context, deletion *nostr.Event) error {
+	for _, tag := range deletion.Tags {
+		if len(tag) < 2 || tag[0] != "a" {
+			continue
+		}
+		kind, pubkey, d, ok := policies.ParseAddress(tag[1])
+		if !ok || !strings.EqualFold(pubkey, deletion.PubKey) {
+			continue
+		}
+		address := fmt.Sprintf("%d:%s:%s", kind, strings.ToLower(pubkey), d)
+
+		_, err := ws.db.ExecContext(ctx, `
+			INSERT INTO withdrawals (deletion_id, event_id, pubkey, reason, requested_at)
+			SELECT $1, v.event_id, $2, $3, $4 FROM event_versions v
+			WHERE v.address = $5 AND v.created_at <= $6
+			ON CONFLICT DO NOTHING
+		`, deletion.ID, deletion.PubKey, deletion.Content, deletion.CreatedAt.Time(), address, int64(deletion.CreatedAt))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkDeletionTargets rejects a deletion request naming an event or address
+// of another author, or another deletion request, so that a request is
+// accepted or rejected as a whole before any of it is recorded
+func checkDeletionTargets(deletion *nostr.Event, targets []*nostr.Event) error {
+	for _, target := range targets {
+		if target.PubKey != deletion.PubKey {
+			return fmt.Errorf("you are not the author of event %s", target.ID)
+		}
+		if target.Kind == deletionKind {
+			return errors.New("deletion requests cannot be withdrawn")
+		}
+	}
+	for _, tag := range deletion.Tags {
+		if len(tag) < 2 || tag[0] != "a" {
+			continue
+		}
+		if _, pubkey, _, ok := policies.ParseAddress(tag[1]); ok && !strings.EqualFold(pubkey, deletion.PubKey) {
+			return fmt.Errorf("you are not the author of address %s", tag[1])
+		}
+	}
+	return nil
+}
+
+// hasEventTargets reports whether a deletion request names an event
+func hasEventTargets(deletion *nostr.Event) bool {
+	return deletion.Tags.GetFirst([]string{"e", ""}) != nil
+}
+
+// hasAddressTargets reports whether a deletion request names an address
+func hasAddressTargets(deletion *nostr.Event) bool {
+	return deletion.Tags.GetFirst([]string{"a", ""}) != nil
+}
+
+// deletionReason turns an acceptDeletion error into the message for
+// OverwriteDeletionOutcome. khatru prefixes it with "blocked: ", so the
+// error's own NIP-01 prefix is removed; the second result reports whether it
+// was auth-required, in which case the caller asks the client to authenticate.
+func deletionReason(err error) (string, bool) {
+	reason := err.Error()
+	prefix, message, ok := strings.Cut(reason, ": ")
+	if !ok || strings.ContainsAny(prefix, " []") {
+		return reason, false
+	}
+	return message, prefix == "auth-required"
+}
+
+// Withdrawals returns the deletion requests made for an event, oldest first
+func (ws *PostgreSQLWithdrawals) Withdrawals(ctx context.Context, eventID string) ([]Withdrawal, error) {
+	withdrawals := []Withdrawal{}
+	err := ws.db.SelectContext(ctx, &withdrawals, `
+		SELECT deletion_id, event_id, pubkey, reason, requested_at FROM withdrawals
+		WHERE event_id = $1
+		ORDER BY requested_at
+	`, eventID)
+	return withdrawals, err
+}
+
+// withdrawnMode removes a withdrawn:include, withdrawn:exclude or
+// withdrawn:only extension from the filter's search and returns it.
+// Withdrawn content is included by default.
+func withdrawnMode(filter *nostr.Filter) (string, error) {
+	mode, err := searchExtension(filter, "withdrawn", withdrawnInclude, withdrawnExclude, withdrawnOnly)
+	if mode == "" {
+		mode = withdrawnInclude
+	}
+	return mode, err
+}
+
+// withdrawalsHandler lists the deletion requests made for an event
+func withdrawalsHandler(withdrawals *PostgreSQLWithdrawals) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			w.Header().Set("Allow", http.MethodGet)
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		id := strings.ToLower(strings.TrimPrefix(r.URL.Path, "/withdrawals/"))
+		if !nostr.IsValid32ByteHex(id) {
+			http.Error(w, "expected /withdrawals/{event id} with a hex event id", http.StatusBadRequest)
+			return
+		}
+
+		records, err := withdrawals.Withdrawals(r.Context(), id)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"event_id":    id,
+			"withdrawn":   len(records) > 0,
+			"withdrawals": records,
+		})
+	}
+}
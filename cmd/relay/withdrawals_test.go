@@ -0,0 +1,90 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/nbd-wtf/go-nostr"
+
+	"github.com/connorslagle/nark-archival/internal/policies"
+)
+
+func TestDeletionReason(t *testing.T) {
+	ctx := context.Background()
+	pubkey := strings.Repeat("a", 64)
+	deletion := &nostr.Event{ID: strings.Repeat("d", 64), PubKey: pubkey, Kind: deletionKind, Tags: nostr.Tags{{"e", strings.Repeat("b", 64)}}}
+
+	config, err := policies.ParseConfig([]byte(`{"auth": {"mode": "required"}}`))
+	if err != nil {
+		t.Fatalf("Unexpected config error: %v", err)
+	}
+	engine := policies.NewPolicyEngineWithConfig(config, nil, nil, nil)
+	unauthenticated := errors.New(policies.OKMessage(engine.ValidateStages(ctx, deletion, policies.WriteAccessStages...)))
+
+	tests := []struct {
+		name         string
+		err          error
+		want         string
+		authRequired bool
+	}{
+		{"unauthenticated", unauthenticated,
+			"blocked: [auth_required] auth policy: publishing academic events requires NIP-42 authentication", true},
+		{"target of another author", fmt.Errorf("blocked: %w", checkDeletionTargets(deletion, []*nostr.Event{{ID: "x", PubKey: strings.Repeat("c", 64)}})),
+			"blocked: you are not the author of event x", false},
+		{"storage error", errors.New("error: failed to store deletion request: connection refused"), "blocked: failed to store deletion request: connection refused", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reason, authRequired := deletionReason(tt.err)
+			// khatru prefixes the reason of a rejected deletion request
+			if got := "blocked: " + reason; got != tt.want {
+				t.Errorf("Expected OK message %q, got %q", tt.want, got)
+			}
+			if authRequired != tt.authRequired {
+				t.Errorf("Expected auth required=%v, got %v", tt.authRequired, authRequired)
+			}
+		})
+	}
+}
+
+func TestCheckDeletionTargets(t *testing.T) {
+	author := strings.Repeat("a", 64)
+	other := strings.Repeat("c", 64)
+	deletion := func(tags ...nostr.Tag) *nostr.Event {
+		return &nostr.Event{PubKey: author, Kind: deletionKind, Tags: tags}
+	}
+
+	tests := []struct {
+		name     string
+		deletion *nostr.Event
+		targets  []*nostr.Event
+		wantErr  string
+	}{
+		{"own event", deletion(nostr.Tag{"e", "p"}), []*nostr.Event{{ID: "p", PubKey: author, Kind: 31428}}, ""},
+		{"own address", deletion(nostr.Tag{"a", "31428:" + author + ":paper"}), nil, ""},
+		{"event of another author among own events", deletion(nostr.Tag{"e", "p"}, nostr.Tag{"e", "q"}),
+			[]*nostr.Event{{ID: "p", PubKey: author, Kind: 31428}, {ID: "q", PubKey: other, Kind: 31428}}, "not the author of event q"},
+		{"address of another author", deletion(nostr.Tag{"e", "p"}, nostr.Tag{"a", "31428:" + other + ":paper"}),
+			[]*nostr.Event{{ID: "p", PubKey: author, Kind: 31428}}, "not the author of address"},
+		{"deletion request", deletion(nostr.Tag{"e", "r"}), []*nostr.Event{{ID: "r", PubKey: author, Kind: deletionKind}}, "cannot be withdrawn"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkDeletionTargets(tt.deletion, tt.targets)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("Expected the request to be accepted, got: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Expected error containing %q, got: %v", tt.wantErr, err)
+			}
+		})
+	}
+}
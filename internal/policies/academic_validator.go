@@ -1,7 +1,9 @@
 package policies
 
 import (
-	"fmt"
+	"net/url"
+	"regexp"
+	"strconv"
 	"strings"
 
 	"github.com/nbd-wtf/go-nostr"
@@ -14,220 +16,437 @@ const (
 	AcademicReviewKind     = 31430
 	AcademicDataKind       = 31431
 	AcademicDiscussionKind = 31432
+	AcademicRetractionKind = 31433
+	AcademicErratumKind    = 31434
+	AcademicConcernKind    = 31435
 )
 
-// ValidateAcademicEvent verifies required tags based on event kind
-func ValidateAcademicEvent(event *nostr.Event) error {
-	switch event.Kind {
-	case AcademicPaperKind:
-		return validatePaper(event)
-	case AcademicCitationKind:
-		return validateCitation(event)
-	case AcademicReviewKind:
-		return validateReview(event)
-	case AcademicDataKind:
-		return validateData(event)
-	case AcademicDiscussionKind:
-		return validateDiscussion(event)
-	default:
-		return fmt.Errorf("invalid academic event kind: %d. Only kinds 31428-31432 are accepted", event.Kind)
-	}
+// AcademicKinds lists every event kind accepted by the archive
+var AcademicKinds = []int{
+	AcademicPaperKind,
+	AcademicCitationKind,
+	AcademicReviewKind,
+	AcademicDataKind,
+	AcademicDiscussionKind,
+	AcademicRetractionKind,
+	AcademicErratumKind,
+	AcademicConcernKind,
 }
 
-// validatePaper ensures papers have required metadata
-func validatePaper(event *nostr.Event) error {
-	requiredTags := map[string]bool{
-		"title":    false,
-		"subject":  false,
-		"abstract": false,
-		"author":   false,
-	}
+// NoticeKinds are the editorial notices attached to a published paper:
+// retractions, errata and expressions of concern
+var NoticeKinds = []int{
+	AcademicRetractionKind,
+	AcademicErratumKind,
+	AcademicConcernKind,
+}
 
-	// Check for required tags
-	for _, tag := range event.Tags {
-		if len(tag) < 2 {
-			continue
-		}
-		if _, ok := requiredTags[tag[0]]; ok {
-			requiredTags[tag[0]] = true
+// IsNoticeKind checks if a kind is a retraction, erratum or expression of concern
+func IsNoticeKind(kind int) bool {
+	for _, k := range NoticeKinds {
+		if k == kind {
+			return true
 		}
 	}
+	return false
+}
 
-	// Build error message for missing tags
-	var missing []string
-	for tag, found := range requiredTags {
-		if !found {
-			missing = append(missing, tag)
+// IsAcademicKind checks if a kind is one of the academic kinds
+func IsAcademicKind(kind int) bool {
+	for _, k := range AcademicKinds {
+		if k == kind {
+			return true
 		}
 	}
+	return false
+}
 
-	if len(missing) > 0 {
-		return fmt.Errorf("academic paper missing required tags: %s. Papers must include title, subject, abstract, and at least one author tag", strings.Join(missing, ", "))
-	}
+// KindRules configures the metadata requirements for a single academic kind
+type KindRules struct {
+	// Tags that must be present with a non-empty value slot
+	RequiredTags []string `json:"required_tags"`
+	// Minimum trimmed length of tag values, keyed by tag name
+	MinTagLengths map[string]int `json:"min_tag_lengths,omitempty"`
+	// Minimum trimmed length of the event content
+	MinContentLength int `json:"min_content_length,omitempty"`
+}
 
-	// Validate tag content
-	for _, tag := range event.Tags {
-		if len(tag) < 2 {
-			continue
-		}
-		switch tag[0] {
-		case "title":
-			if len(strings.TrimSpace(tag[1])) < 10 {
-				return fmt.Errorf("paper title too short: must be at least 10 characters")
-			}
-		case "abstract":
-			if len(strings.TrimSpace(tag[1])) < 50 {
-				return fmt.Errorf("paper abstract too short: must be at least 50 characters to provide meaningful summary")
-			}
-		case "author":
-			if len(strings.TrimSpace(tag[1])) < 3 {
-				return fmt.Errorf("author name too short: must be at least 3 characters")
-			}
-		}
+// ValidationRules maps academic kinds to their metadata requirements
+type ValidationRules map[int]KindRules
+
+// DefaultValidationRules returns the archive's standard metadata requirements
+func DefaultValidationRules() ValidationRules {
+	return ValidationRules{
+		AcademicPaperKind: {
+			RequiredTags:  []string{"d", "title", "subject", "abstract", "author"},
+			MinTagLengths: map[string]int{"title": 10, "abstract": 50, "author": 3},
+		},
+		AcademicCitationKind: {
+			RequiredTags:  []string{"d", "context"},
+			MinTagLengths: map[string]int{"context": 20},
+		},
+		AcademicReviewKind: {
+			RequiredTags:  []string{"d", "e"},
+			MinTagLengths: map[string]int{"content": 100},
+		},
+		AcademicDataKind: {
+			RequiredTags:  []string{"d", "data-type", "description", "e", "url", "x", "m", "size"},
+			MinTagLengths: map[string]int{"description": 30},
+		},
+		AcademicDiscussionKind: {
+			RequiredTags:     []string{"d", "e"},
+			MinContentLength: 50,
+		},
+		AcademicRetractionKind: {
+			RequiredTags:  []string{"d", "reason"},
+			MinTagLengths: map[string]int{"reason": 20},
+		},
+		AcademicErratumKind: {
+			RequiredTags:     []string{"d", "reason"},
+			MinTagLengths:    map[string]int{"reason": 20},
+			MinContentLength: 20,
+		},
+		AcademicConcernKind: {
+			RequiredTags:  []string{"d", "reason"},
+			MinTagLengths: map[string]int{"reason": 20},
+		},
 	}
+}
 
-	return nil
+// missingTagMessages explains well-known missing tags per kind
+var missingTagMessages = map[int]map[string]string{
+	AcademicPaperKind: {
+		"title":    "academic paper must have a title: missing 'title' tag",
+		"abstract": "academic paper must have an abstract: missing 'abstract' tag",
+		"author":   "academic paper must name its authors: missing 'author' tag",
+		"subject":  "academic paper must specify its subject: missing 'subject' tag",
+	},
+	AcademicCitationKind: {
+		"e":       "citation must reference a paper: missing 'e' tag pointing to the cited paper event",
+		"context": "citation must provide context: missing 'context' tag explaining the citation",
+	},
+	AcademicReviewKind: {
+		"e": "review must reference a paper: missing 'e' tag pointing to the reviewed paper",
+	},
+	AcademicDataKind: {
+		"data-type":   "research data must specify type: missing 'data-type' tag (e.g., 'dataset', 'code', 'supplementary')",
+		"description": "research data must have description: missing 'description' tag",
+		"e":           "research data must reference related paper: missing 'e' tag pointing to associated paper",
+		"url":         "research data must link to its file: missing 'url' tag",
+		"x":           "research data must include a fixity hash: missing 'x' tag with the SHA-256 of the file",
+		"m":           "research data must specify the file's format: missing 'm' tag with its MIME type",
+		"size":        "research data must specify the file's size: missing 'size' tag with its size in bytes",
+	},
+	AcademicDiscussionKind: {
+		"e": "academic discussion must reference a paper or parent discussion: missing 'e' tag",
+	},
+	AcademicRetractionKind: {
+		"reason": "retraction must state why the paper is retracted: missing 'reason' tag",
+	},
+	AcademicErratumKind: {
+		"reason": "erratum must state what is being corrected: missing 'reason' tag",
+	},
+	AcademicConcernKind: {
+		"reason": "expression of concern must state the concern: missing 'reason' tag",
+	},
 }
 
-// validateCitation ensures citations reference valid papers
-func validateCitation(event *nostr.Event) error {
-	hasCitedPaper := false
-	hasContext := false
+// tooShortMessages explains well-known length violations per kind; %d is the minimum
+var tooShortMessages = map[int]map[string]string{
+	AcademicPaperKind: {
+		"title":    "paper title too short: must be at least %d characters",
+		"abstract": "paper abstract too short: must be at least %d characters to provide meaningful summary",
+		"author":   "author name too short: must be at least %d characters",
+	},
+	AcademicCitationKind: {
+		"context": "citation context too short: must provide at least %d characters of context",
+	},
+	AcademicReviewKind: {
+		"content": "review content too short: must provide at least %d characters of substantive review",
+	},
+	AcademicDataKind: {
+		"description": "data description too short: must provide at least %d characters describing the dataset",
+	},
+	AcademicRetractionKind: {
+		"reason": "retraction reason too short: must provide at least %d characters",
+	},
+	AcademicErratumKind: {
+		"reason": "erratum reason too short: must provide at least %d characters",
+	},
+	AcademicConcernKind: {
+		"reason": "expression of concern reason too short: must provide at least %d characters",
+	},
+}
 
-	for _, tag := range event.Tags {
-		if len(tag) < 2 {
-			continue
-		}
-		switch tag[0] {
-		case "e":
-			// Reference to cited paper
-			hasCitedPaper = true
-		case "context":
-			// Citation context
-			hasContext = true
-			if len(strings.TrimSpace(tag[1])) < 20 {
-				return fmt.Errorf("citation context too short: must provide at least 20 characters of context")
-			}
-		}
-	}
+// ValidateAcademicEvent verifies required tags based on event kind
+func ValidateAcademicEvent(event *nostr.Event) error {
+	return ValidateAcademicEventWithRules(event, DefaultValidationRules())
+}
+
+// ValidateAcademicEventWithRules verifies required tags using the given rules
+func ValidateAcademicEventWithRules(event *nostr.Event, rules ValidationRules) error {
+	kindRules := rules[event.Kind]
 
-	if !hasCitedPaper {
-		return fmt.Errorf("citation must reference a paper: missing 'e' tag pointing to the cited paper event")
+	switch event.Kind {
+	case AcademicPaperKind:
+		return validatePaper(event, kindRules)
+	case AcademicCitationKind:
+		return validateCitation(event, kindRules)
+	case AcademicReviewKind:
+		return validateReview(event, kindRules)
+	case AcademicDataKind:
+		return validateData(event, kindRules)
+	case AcademicDiscussionKind:
+		return validateDiscussion(event, kindRules)
+	case AcademicRetractionKind, AcademicErratumKind, AcademicConcernKind:
+		return validateNotice(event, kindRules)
+	default:
+		return policyErrorf(CodeInvalidKind, "kind", "invalid academic event kind: %d. Only kinds 31428-31435 are accepted", event.Kind)
 	}
+}
+
+// validatePaper ensures papers have required metadata
+func validatePaper(event *nostr.Event, rules KindRules) error {
+	errs := commonViolations(event, rules)
+	errs = append(errs, identifierViolations(event)...)
+
+	return joinViolations(errs)
+}
 
-	if !hasContext {
-		return fmt.Errorf("citation must provide context: missing 'context' tag explaining the citation")
+// validateCitation ensures citations reference an archived event or identify
+// an external work, and that external identifiers are well formed
+func validateCitation(event *nostr.Event, rules KindRules) error {
+	errs := commonViolations(event, rules)
+
+	id, address := PaperReference(event)
+	doi, arxiv := ExternalIdentifiers(event)
+	if id == "" && address == "" && doi == "" && arxiv == "" {
+		errs = append(errs, policyErrorf(CodeMissingTag, "e",
+			"citation must reference a paper: missing 'e' tag pointing to the cited paper event, 'a' tag with its address, or 'doi' or 'arxiv' tag identifying an external work"))
+	}
+	if _, _, _, ok := ParseAddress(address); address != "" && !ok {
+		errs = append(errs, policyErrorf(CodeMissingReference, "a", "citation 'a' tag must be an address of the form <kind>:<pubkey>:<d>"))
 	}
+	errs = append(errs, identifierViolations(event)...)
 
-	return nil
+	return joinViolations(errs)
 }
 
 // validateReview ensures reviews are properly linked to papers
-func validateReview(event *nostr.Event) error {
-	hasReferencedPaper := false
+func validateReview(event *nostr.Event, rules KindRules) error {
+	errs := commonViolations(event, rules)
+
 	hasRating := false
 	hasContent := false
-
 	for _, tag := range event.Tags {
 		if len(tag) < 2 {
 			continue
 		}
 		switch tag[0] {
-		case "e":
-			// Reference to reviewed paper
-			hasReferencedPaper = true
 		case "rating":
 			hasRating = true
 		case "content":
 			hasContent = true
-			if len(strings.TrimSpace(tag[1])) < 100 {
-				return fmt.Errorf("review content too short: must provide at least 100 characters of substantive review")
-			}
 		}
 	}
 
-	if !hasReferencedPaper {
-		return fmt.Errorf("review must reference a paper: missing 'e' tag pointing to the reviewed paper")
-	}
-
 	if !hasRating && !hasContent {
-		return fmt.Errorf("review must include either a rating or content review (preferably both)")
+		errs = append(errs, policyErrorf(CodeMissingTag, "rating", "review must include either a rating or content review (preferably both)"))
 	}
 
-	return nil
+	return joinViolations(errs)
 }
 
 // validateData ensures research data has proper metadata
-func validateData(event *nostr.Event) error {
-	hasDataType := false
-	hasDescription := false
-	hasRelatedPaper := false
+func validateData(event *nostr.Event, rules KindRules) error {
+	errs := commonViolations(event, rules)
+	errs = append(errs, fileViolations(event)...)
+	errs = append(errs, identifierViolations(event)...)
+	return joinViolations(errs)
+}
 
-	for _, tag := range event.Tags {
-		if len(tag) < 2 {
-			continue
+// mimeTypePattern matches a lowercase <type>/<subtype> MIME type
+var mimeTypePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9!#$&^_.+-]*/[a-z0-9][a-z0-9!#$&^_.+-]*$`)
+
+// fileViolations checks NIP-94 file metadata: an http(s) url, the SHA-256 of
+// the file in x, a lowercase MIME type in m and the size in bytes
+func fileViolations(event *nostr.Event) []error {
+	var errs []error
+	if tag := event.Tags.GetFirst([]string{"url", ""}); tag != nil {
+		if u, err := url.Parse((*tag)[1]); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			errs = append(errs, policyErrorf(CodeInvalidFile, "url", "research data 'url' tag must be an http or https URL, got %q", (*tag)[1]))
 		}
-		switch tag[0] {
-		case "data-type":
-			hasDataType = true
-		case "description":
-			hasDescription = true
-			if len(strings.TrimSpace(tag[1])) < 30 {
-				return fmt.Errorf("data description too short: must provide at least 30 characters describing the dataset")
-			}
-		case "e":
-			// Reference to related paper
-			hasRelatedPaper = true
+	}
+	if tag := event.Tags.GetFirst([]string{"x", ""}); tag != nil && !nostr.IsValid32ByteHex((*tag)[1]) {
+		errs = append(errs, policyErrorf(CodeInvalidFile, "x", "research data 'x' tag must be the SHA-256 of the file as 64 lowercase hex characters"))
+	}
+	if tag := event.Tags.GetFirst([]string{"m", ""}); tag != nil && !mimeTypePattern.MatchString((*tag)[1]) {
+		errs = append(errs, policyErrorf(CodeInvalidFile, "m", "research data 'm' tag must be a lowercase MIME type such as text/csv, got %q", (*tag)[1]))
+	}
+	if tag := event.Tags.GetFirst([]string{"size", ""}); tag != nil {
+		if size, err := strconv.ParseInt((*tag)[1], 10, 64); err != nil || size <= 0 {
+			errs = append(errs, policyErrorf(CodeInvalidFile, "size", "research data 'size' tag must be the file size in bytes, got %q", (*tag)[1]))
 		}
 	}
+	return errs
+}
 
-	if !hasDataType {
-		return fmt.Errorf("research data must specify type: missing 'data-type' tag (e.g., 'dataset', 'code', 'supplementary')")
+// FileHash returns the SHA-256 of a dataset's file from its NIP-94 x tag, or
+// "" when it has no valid one. The file hash identifies the dataset.
+func FileHash(event *nostr.Event) string {
+	if tag := event.Tags.GetFirst([]string{"x", ""}); tag != nil && nostr.IsValid32ByteHex((*tag)[1]) {
+		return (*tag)[1]
 	}
+	return ""
+}
+
+// validateDiscussion ensures discussions are properly threaded
+func validateDiscussion(event *nostr.Event, rules KindRules) error {
+	return joinViolations(commonViolations(event, rules))
+}
 
-	if !hasDescription {
-		return fmt.Errorf("research data must have description: missing 'description' tag")
+// validateNotice ensures retractions, errata and expressions of concern
+// reference the paper they concern and state a reason
+func validateNotice(event *nostr.Event, rules KindRules) error {
+	errs := commonViolations(event, rules)
+
+	id, address := PaperReference(event)
+	switch {
+	case id == "" && address == "":
+		errs = append(errs, policyErrorf(CodeMissingTag, "e",
+			"%s must reference the paper: missing 'e' tag with the paper event id or 'a' tag with its address", getEventTypeName(event.Kind)))
+	case id == "" && !isPaperAddress(address):
+		errs = append(errs, policyErrorf(CodeMissingReference, "a",
+			"%s 'a' tag must be a paper address of the form %d:<pubkey>:<d>", getEventTypeName(event.Kind), AcademicPaperKind))
 	}
 
-	if !hasRelatedPaper {
-		return fmt.Errorf("research data must reference related paper: missing 'e' tag pointing to associated paper")
+	return joinViolations(errs)
+}
+
+// PaperReference returns the paper event id from the first 'e' tag and the
+// paper address from the first 'a' tag, either of which may be empty
+func PaperReference(event *nostr.Event) (id, address string) {
+	if tag := event.Tags.GetFirst([]string{"e", ""}); tag != nil {
+		id = (*tag)[1]
 	}
+	if tag := event.Tags.GetFirst([]string{"a", ""}); tag != nil {
+		address = (*tag)[1]
+	}
+	return id, address
+}
 
-	return nil
+// ParseAddress splits a NIP-33 address of the form <kind>:<pubkey>:<d>
+func ParseAddress(address string) (kind int, pubkey, d string, ok bool) {
+	parts := strings.SplitN(address, ":", 3)
+	if len(parts) != 3 || !nostr.IsValid32ByteHex(parts[1]) {
+		return 0, "", "", false
+	}
+	kind, err := strconv.Atoi(parts[0])
+	if err != nil {
+		return 0, "", "", false
+	}
+	return kind, parts[1], parts[2], true
 }
 
-// validateDiscussion ensures discussions are properly threaded
-func validateDiscussion(event *nostr.Event) error {
-	hasReference := false
-	contentLength := len(strings.TrimSpace(event.Content))
+func isPaperAddress(address string) bool {
+	kind, _, _, ok := ParseAddress(address)
+	return ok && kind == AcademicPaperKind
+}
+
+// commonViolations applies the required tag and length rules shared by all kinds
+func commonViolations(event *nostr.Event, rules KindRules) []error {
+	var errs []error
+	errs = append(errs, requiredTagViolations(event, rules)...)
+	errs = append(errs, tagLengthViolations(event, rules)...)
+	errs = append(errs, contentLengthViolations(event, rules)...)
+	return errs
+}
 
+// missingTags returns the required tags that do not appear on the event
+func missingTags(event *nostr.Event, required []string) []string {
+	var missing []string
+	for _, name := range required {
+		found := false
+		for _, tag := range event.Tags {
+			if len(tag) >= 2 && tag[0] == name {
+				found = true
+				break
+			}
+		}
+		if !found {
+			missing = append(missing, name)
+		}
+	}
+	return missing
+}
+
+// requiredTagViolations reports every required tag missing from the event
+func requiredTagViolations(event *nostr.Event, rules KindRules) []error {
+	var errs []error
+	for _, name := range missingTags(event, rules.RequiredTags) {
+		if name == "d" {
+			errs = append(errs, policyErrorf(CodeMissingTag, name,
+				"%s must include a 'd' tag identifying it, so revisions can be published as new versions", getEventTypeName(event.Kind)))
+			continue
+		}
+		if msg, ok := missingTagMessages[event.Kind][name]; ok {
+			errs = append(errs, &PolicyError{Code: CodeMissingTag, Field: name, Message: msg})
+			continue
+		}
+		errs = append(errs, policyErrorf(CodeMissingTag, name, "%s must include a '%s' tag", getEventTypeName(event.Kind), name))
+	}
+	return errs
+}
+
+// tagLengthViolations reports every tag value shorter than its configured minimum
+func tagLengthViolations(event *nostr.Event, rules KindRules) []error {
+	var errs []error
 	for _, tag := range event.Tags {
 		if len(tag) < 2 {
 			continue
 		}
-		if tag[0] == "e" {
-			// Reference to paper or parent discussion
-			hasReference = true
+		min, ok := rules.MinTagLengths[tag[0]]
+		length := len(strings.TrimSpace(tag[1]))
+		if !ok || length >= min {
+			continue
 		}
-	}
 
-	if !hasReference {
-		return fmt.Errorf("academic discussion must reference a paper or parent discussion: missing 'e' tag")
+		if msg, ok := tooShortMessages[event.Kind][tag[0]]; ok {
+			errs = append(errs, policyErrorf(CodeTooShort, tag[0], msg, min).withLimit(min, length))
+			continue
+		}
+		errs = append(errs, policyErrorf(CodeTooShort, tag[0], "%s tag too short: must be at least %d characters", tag[0], min).withLimit(min, length))
 	}
+	return errs
+}
 
-	if contentLength < 50 {
-		return fmt.Errorf("discussion content too short: must provide at least 50 characters for meaningful academic discourse")
+// contentLengthViolations enforces the configured minimum content length
+func contentLengthViolations(event *nostr.Event, rules KindRules) []error {
+	length := len(strings.TrimSpace(event.Content))
+	if rules.MinContentLength <= 0 || length >= rules.MinContentLength {
+		return nil
 	}
 
-	return nil
+	if event.Kind == AcademicDiscussionKind {
+		return []error{policyErrorf(CodeTooShort, "content", "discussion content too short: must provide at least %d characters for meaningful academic discourse", rules.MinContentLength).
+			withLimit(rules.MinContentLength, length)}
+	}
+	return []error{policyErrorf(CodeTooShort, "content", "%s content too short: must be at least %d characters", getEventTypeName(event.Kind), rules.MinContentLength).
+		withLimit(rules.MinContentLength, length)}
 }
 
 // RequireMinimalMetadata ensures all academic events have basic required metadata
 func RequireMinimalMetadata(event *nostr.Event) error {
+	return RequireMinimalMetadataWithRules(event, DefaultValidationRules())
+}
+
+// RequireMinimalMetadataWithRules ensures academic events satisfy the given rules.
+// Every violation is reported; multiple violations are returned as a *MultiError.
+func RequireMinimalMetadataWithRules(event *nostr.Event, rules ValidationRules) error {
+	var errs []error
+
 	// First validate according to specific type
-	if err := ValidateAcademicEvent(event); err != nil {
-		return err
-	}
+	errs = append(errs, Violations(ValidateAcademicEventWithRules(event, rules))...)
 
 	// Additional cross-type validations
 	hasTimestamp := false
@@ -239,8 +458,8 @@ func RequireMinimalMetadata(event *nostr.Event) error {
 	}
 
 	if !hasTimestamp && event.CreatedAt == 0 {
-		return fmt.Errorf("academic content must have a timestamp: either in created_at field or published_at tag")
+		errs = append(errs, policyErrorf(CodeMissingTimestamp, "created_at", "academic content must have a timestamp: either in created_at field or published_at tag"))
 	}
 
-	return nil
-}
\ No newline at end of file
+	return joinViolations(errs)
+}
@@ -1,6 +1,8 @@
 package policies
 
 import (
+	"errors"
+	"strings"
 	"testing"
 	"time"
 
@@ -19,6 +21,7 @@ func TestValidateAcademicEvent(t *testing.T) {
 			event: &nostr.Event{
 				Kind: AcademicPaperKind,
 				Tags: nostr.Tags{
+					{"d", "academic-validator-1"},
 					{"title", "A Study on Distributed Systems Performance"},
 					{"abstract", "This paper presents a comprehensive analysis of distributed systems performance under various load conditions and network topologies."},
 					{"subject", "Computer Science"},
@@ -32,19 +35,21 @@ func TestValidateAcademicEvent(t *testing.T) {
 			event: &nostr.Event{
 				Kind: AcademicPaperKind,
 				Tags: nostr.Tags{
+					{"d", "academic-validator-2"},
 					{"abstract", "This paper presents a comprehensive analysis of distributed systems performance under various load conditions."},
 					{"subject", "Computer Science"},
 					{"author", "John Doe"},
 				},
 			},
 			wantErr: true,
-			errMsg:  "missing required tags: title",
+			errMsg:  "missing 'title' tag",
 		},
 		{
 			name: "paper with short title",
 			event: &nostr.Event{
 				Kind: AcademicPaperKind,
 				Tags: nostr.Tags{
+					{"d", "academic-validator-3"},
 					{"title", "Study"},
 					{"abstract", "This paper presents a comprehensive analysis of distributed systems performance under various load conditions."},
 					{"subject", "Computer Science"},
@@ -54,11 +59,28 @@ func TestValidateAcademicEvent(t *testing.T) {
 			wantErr: true,
 			errMsg:  "title too short",
 		},
+		{
+			name: "paper with invalid isbn",
+			event: &nostr.Event{
+				Kind: AcademicPaperKind,
+				Tags: nostr.Tags{
+					{"d", "academic-validator-isbn"},
+					{"title", "A Study on Distributed Systems Performance"},
+					{"abstract", "This paper presents a comprehensive analysis of distributed systems performance under various load conditions and network topologies."},
+					{"subject", "Computer Science"},
+					{"author", "John Doe"},
+					{"isbn", "978-0-306-40615-8"},
+				},
+			},
+			wantErr: true,
+			errMsg:  "'isbn' tag must be an ISBN-10 or ISBN-13 with a valid check digit",
+		},
 		{
 			name: "paper with short abstract",
 			event: &nostr.Event{
 				Kind: AcademicPaperKind,
 				Tags: nostr.Tags{
+					{"d", "academic-validator-4"},
 					{"title", "A Study on Distributed Systems"},
 					{"abstract", "Short abstract"},
 					{"subject", "Computer Science"},
@@ -73,6 +95,7 @@ func TestValidateAcademicEvent(t *testing.T) {
 			event: &nostr.Event{
 				Kind: AcademicCitationKind,
 				Tags: nostr.Tags{
+					{"d", "academic-validator-5"},
 					{"e", "referenced-paper-id"},
 					{"context", "This work builds upon the foundational research presented in the referenced paper"},
 				},
@@ -84,17 +107,44 @@ func TestValidateAcademicEvent(t *testing.T) {
 			event: &nostr.Event{
 				Kind: AcademicCitationKind,
 				Tags: nostr.Tags{
+					{"d", "academic-validator-6"},
 					{"context", "This work builds upon previous research"},
 				},
 			},
 			wantErr: true,
 			errMsg:  "must reference a paper",
 		},
+		{
+			name: "citation of an external work",
+			event: &nostr.Event{
+				Kind: AcademicCitationKind,
+				Tags: nostr.Tags{
+					{"d", "citation-external-work"},
+					{"doi", "10.1145/3132747.3132757"},
+					{"context", "We compare against the consensus protocol evaluated in this work"},
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "citation with malformed doi",
+			event: &nostr.Event{
+				Kind: AcademicCitationKind,
+				Tags: nostr.Tags{
+					{"d", "citation-malformed-doi"},
+					{"doi", "https://example.org/paper"},
+					{"context", "We compare against the consensus protocol evaluated in this work"},
+				},
+			},
+			wantErr: true,
+			errMsg:  "'doi' tag must be a DOI",
+		},
 		{
 			name: "valid review",
 			event: &nostr.Event{
 				Kind: AcademicReviewKind,
 				Tags: nostr.Tags{
+					{"d", "academic-validator-7"},
 					{"e", "reviewed-paper-id"},
 					{"content", "This paper presents an innovative approach to distributed consensus. The methodology is sound and the experimental results are convincing. The authors have made a significant contribution to the field."},
 					{"rating", "4/5"},
@@ -107,6 +157,7 @@ func TestValidateAcademicEvent(t *testing.T) {
 			event: &nostr.Event{
 				Kind: AcademicReviewKind,
 				Tags: nostr.Tags{
+					{"d", "academic-validator-8"},
 					{"content", "Great paper!"},
 				},
 			},
@@ -118,18 +169,56 @@ func TestValidateAcademicEvent(t *testing.T) {
 			event: &nostr.Event{
 				Kind: AcademicDataKind,
 				Tags: nostr.Tags{
+					{"d", "academic-validator-9"},
 					{"e", "related-paper-id"},
 					{"data-type", "dataset"},
 					{"description", "Experimental results from distributed systems performance testing"},
+					{"url", "https://example.com/data/latency.csv"},
+					{"x", strings.Repeat("ab", 32)},
+					{"m", "text/csv"},
+					{"size", "48213"},
 				},
 			},
 			wantErr: false,
 		},
+		{
+			name: "data with malformed file hash",
+			event: &nostr.Event{
+				Kind: AcademicDataKind,
+				Tags: nostr.Tags{
+					{"d", "academic-validator-file-hash"},
+					{"e", "related-paper-id"},
+					{"data-type", "dataset"},
+					{"description", "Experimental results from distributed systems performance testing"},
+					{"url", "https://example.com/data/latency.csv"},
+					{"x", "ABCDEF"},
+					{"m", "text/csv"},
+					{"size", "48213"},
+				},
+			},
+			wantErr: true,
+			errMsg:  "'x' tag must be the SHA-256 of the file",
+		},
+		{
+			name: "data without file metadata",
+			event: &nostr.Event{
+				Kind: AcademicDataKind,
+				Tags: nostr.Tags{
+					{"d", "academic-validator-no-file"},
+					{"e", "related-paper-id"},
+					{"data-type", "dataset"},
+					{"description", "Experimental results from distributed systems performance testing"},
+				},
+			},
+			wantErr: true,
+			errMsg:  "missing 'x' tag",
+		},
 		{
 			name: "data missing type",
 			event: &nostr.Event{
 				Kind: AcademicDataKind,
 				Tags: nostr.Tags{
+					{"d", "academic-validator-10"},
 					{"e", "related-paper-id"},
 					{"description", "Some experimental data"},
 				},
@@ -143,6 +232,7 @@ func TestValidateAcademicEvent(t *testing.T) {
 				Kind:    AcademicDiscussionKind,
 				Content: "I found the methodology section particularly interesting. Have you considered applying this approach to edge computing scenarios?",
 				Tags: nostr.Tags{
+					{"d", "academic-validator-11"},
 					{"e", "paper-or-discussion-id"},
 				},
 			},
@@ -154,12 +244,75 @@ func TestValidateAcademicEvent(t *testing.T) {
 				Kind:    AcademicDiscussionKind,
 				Content: "Nice work!",
 				Tags: nostr.Tags{
+					{"d", "academic-validator-12"},
 					{"e", "paper-id"},
 				},
 			},
 			wantErr: true,
 			errMsg:  "content too short",
 		},
+		{
+			name: "valid retraction by address",
+			event: &nostr.Event{
+				Kind: AcademicRetractionKind,
+				Tags: nostr.Tags{
+					{"d", "academic-validator-13"},
+					{"a", "31428:" + strings.Repeat("a", 64) + ":distributed-systems"},
+					{"reason", "The main results could not be reproduced"},
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "erratum without paper reference",
+			event: &nostr.Event{
+				Kind:    AcademicErratumKind,
+				Content: "Table 2 reports latency in seconds, not milliseconds.",
+				Tags: nostr.Tags{
+					{"d", "academic-validator-14"},
+					{"reason", "Wrong units in the latency table"},
+				},
+			},
+			wantErr: true,
+			errMsg:  "missing 'e' tag with the paper event id or 'a' tag",
+		},
+		{
+			name: "concern referencing a non-paper address",
+			event: &nostr.Event{
+				Kind: AcademicConcernKind,
+				Tags: nostr.Tags{
+					{"d", "academic-validator-15"},
+					{"a", "31430:" + strings.Repeat("a", 64) + ":review"},
+					{"reason", "Editors are investigating possible image manipulation"},
+				},
+			},
+			wantErr: true,
+			errMsg:  "must be a paper address",
+		},
+		{
+			name: "retraction without reason",
+			event: &nostr.Event{
+				Kind: AcademicRetractionKind,
+				Tags: nostr.Tags{
+					{"d", "academic-validator-16"},
+					{"e", "paper-id"},
+				},
+			},
+			wantErr: true,
+			errMsg:  "missing 'reason' tag",
+		},
+		{
+			name: "review without d tag",
+			event: &nostr.Event{
+				Kind: AcademicReviewKind,
+				Tags: nostr.Tags{
+					{"e", "reviewed-paper-id"},
+					{"rating", "4/5"},
+				},
+			},
+			wantErr: true,
+			errMsg:  "must include a 'd' tag",
+		},
 		{
 			name: "invalid event kind",
 			event: &nostr.Event{
@@ -197,6 +350,7 @@ func TestRequireMinimalMetadata(t *testing.T) {
 				Kind:      AcademicPaperKind,
 				CreatedAt: nostr.Timestamp(time.Now().Unix()),
 				Tags: nostr.Tags{
+					{"d", "academic-validator-17"},
 					{"title", "A Valid Title for Testing"},
 					{"abstract", "This is a sufficiently long abstract that meets the minimum character requirement for academic papers."},
 					{"subject", "Testing"},
@@ -210,6 +364,7 @@ func TestRequireMinimalMetadata(t *testing.T) {
 			event: &nostr.Event{
 				Kind: AcademicPaperKind,
 				Tags: nostr.Tags{
+					{"d", "academic-validator-18"},
 					{"title", "A Valid Title for Testing"},
 					{"abstract", "This is a sufficiently long abstract that meets the minimum character requirement for academic papers."},
 					{"subject", "Testing"},
@@ -225,6 +380,7 @@ func TestRequireMinimalMetadata(t *testing.T) {
 				Kind:      AcademicPaperKind,
 				CreatedAt: 0,
 				Tags: nostr.Tags{
+					{"d", "academic-validator-19"},
 					{"title", "A Valid Title for Testing"},
 					{"abstract", "This is a sufficiently long abstract that meets the minimum character requirement for academic papers."},
 					{"subject", "Testing"},
@@ -256,4 +412,51 @@ func containsHelper(s, substr string) bool {
 		}
 	}
 	return false
-}
\ No newline at end of file
+}
+
+func TestValidationCollectsAllViolations(t *testing.T) {
+	paper := &nostr.Event{
+		Kind:      AcademicPaperKind,
+		CreatedAt: 0,
+		Tags: nostr.Tags{
+			{"d", "academic-validator-20"},
+			{"title", "Short"},
+			{"abstract", "Also too short"},
+		},
+	}
+
+	err := RequireMinimalMetadata(paper)
+	if err == nil {
+		t.Fatal("Expected validation to fail")
+	}
+
+	codes := make(map[string]bool)
+	for _, violation := range Violations(err) {
+		var policyErr *PolicyError
+		if !errors.As(violation, &policyErr) {
+			t.Fatalf("Expected PolicyError, got %v", violation)
+		}
+		codes[string(policyErr.Code)+":"+policyErr.Field] = true
+	}
+
+	expected := []string{
+		"missing_tag:subject",
+		"too_short:title",
+		"too_short:abstract",
+		"missing_tag:author",
+		"missing_timestamp:created_at",
+	}
+	for _, code := range expected {
+		if !codes[code] {
+			t.Errorf("Expected violation %s, got %v", code, codes)
+		}
+	}
+	if len(codes) != len(expected) {
+		t.Errorf("Expected %d violations, got %d: %v", len(expected), len(codes), codes)
+	}
+
+	var multi *MultiError
+	if !errors.As(err, &multi) {
+		t.Errorf("Expected *MultiError, got %T", err)
+	}
+}
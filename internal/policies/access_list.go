@@ -0,0 +1,223 @@
+package policies
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/nbd-wtf/go-nostr"
+)
+
+// Access list names
+const (
+	AccessAllow = "allow"
+	AccessBlock = "block"
+)
+
+// AllKinds is the Kind of an access list entry that applies to every kind
+const AllKinds = 0
+
+// AccessEntry allows or blocks a pubkey from publishing one kind, or every
+// kind when Kind is AllKinds
+type AccessEntry struct {
+	List      string     `json:"list" db:"list"`
+	Pubkey    string     `json:"pubkey" db:"pubkey"`
+	Kind      int        `json:"kind" db:"kind"`
+	Reason    string     `json:"reason,omitempty" db:"reason"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty" db:"expires_at"`
+	CreatedAt time.Time  `json:"created_at" db:"created_at"`
+}
+
+// Active reports whether the entry has not expired at now
+func (e AccessEntry) Active(now time.Time) bool {
+	return e.ExpiresAt == nil || now.Before(*e.ExpiresAt)
+}
+
+// Matches reports whether the entry applies to kind
+func (e AccessEntry) Matches(kind int) bool {
+	return e.Kind == AllKinds || e.Kind == kind
+}
+
+// Validate checks the entry before it is stored
+func (e AccessEntry) Validate() error {
+	if e.List != AccessAllow && e.List != AccessBlock {
+		return fmt.Errorf("list must be %q or %q, got %q", AccessAllow, AccessBlock, e.List)
+	}
+	if !nostr.IsValid32ByteHex(e.Pubkey) {
+		return fmt.Errorf("pubkey must be 64 lowercase hex characters")
+	}
+	if e.Kind != AllKinds && !IsAcademicKind(e.Kind) {
+		return fmt.Errorf("kind %d is not an academic kind", e.Kind)
+	}
+	return nil
+}
+
+// AccessListStore holds allowlist and blocklist entries. Entries are read on
+// every check so changes take effect without restarting the relay.
+type AccessListStore interface {
+	// Entries returns the active entries for a pubkey on both lists
+	Entries(ctx context.Context, pubkey string) ([]AccessEntry, error)
+	// List returns the active entries on one list
+	List(ctx context.Context, list string) ([]AccessEntry, error)
+	// Add creates or replaces the entry for its list, pubkey and kind
+	Add(ctx context.Context, entry AccessEntry) error
+	// Remove deletes the entry for a list, pubkey and kind
+	Remove(ctx context.Context, list, pubkey string, kind int) error
+}
+
+// AccessListConfig configures the access_list stage
+type AccessListConfig struct {
+	// AllowlistKinds lists the kinds only allowlisted pubkeys may publish
+	AllowlistKinds []int `json:"allowlist_kinds"`
+}
+
+// InMemoryAccessList implements AccessListStore in memory
+type InMemoryAccessList struct {
+	mu      sync.RWMutex
+	entries map[string]AccessEntry
+}
+
+// NewInMemoryAccessList creates an empty in-memory access list
+func NewInMemoryAccessList() *InMemoryAccessList {
+	return &InMemoryAccessList{entries: make(map[string]AccessEntry)}
+}
+
+func accessKey(list, pubkey string, kind int) string {
+	return fmt.Sprintf("%s:%s:%d", list, pubkey, kind)
+}
+
+// Entries returns the active entries for a pubkey
+func (a *InMemoryAccessList) Entries(ctx context.Context, pubkey string) ([]AccessEntry, error) {
+	return a.filter(func(e AccessEntry) bool { return e.Pubkey == pubkey }), nil
+}
+
+// List returns the active entries on one list
+func (a *InMemoryAccessList) List(ctx context.Context, list string) ([]AccessEntry, error) {
+	return a.filter(func(e AccessEntry) bool { return e.List == list }), nil
+}
+
+func (a *InMemoryAccessList) filter(match func(AccessEntry) bool) []AccessEntry {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	now := time.Now()
+	var entries []AccessEntry
+	for _, entry := range a.entries {
+		if entry.Active(now) && match(entry) {
+			entries = append(entries, entry)
+		}
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].CreatedAt.Before(entries[j].CreatedAt) })
+	return entries
+}
+
+// Add creates or replaces an entry
+func (a *InMemoryAccessList) Add(ctx context.Context, entry AccessEntry) error {
+	if err := entry.Validate(); err != nil {
+		return err
+	}
+	if entry.CreatedAt.IsZero() {
+		entry.CreatedAt = time.Now()
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.entries[accessKey(entry.List, entry.Pubkey, entry.Kind)] = entry
+	return nil
+}
+
+// Remove deletes an entry
+func (a *InMemoryAccessList) Remove(ctx context.Context, list, pubkey string, kind int) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	delete(a.entries, accessKey(list, pubkey, kind))
+	return nil
+}
+
+// AccessListPolicy rejects blocked pubkeys and, for kinds that require it,
+// pubkeys that are not allowlisted
+type AccessListPolicy struct {
+	store          AccessListStore
+	allowlistKinds []int
+}
+
+// NewAccessListPolicy creates the access list stage
+func NewAccessListPolicy(store AccessListStore, config AccessListConfig) *AccessListPolicy {
+	if store == nil {
+		store = NewInMemoryAccessList()
+	}
+	return &AccessListPolicy{store: store, allowlistKinds: config.AllowlistKinds}
+}
+
+// Name returns the stage name
+func (p *AccessListPolicy) Name() string { return PolicyAccessList }
+
+// Kinds returns nil: blocklist entries may apply to every kind
+func (p *AccessListPolicy) Kinds() []int { return nil }
+
+// Validate checks the event's pubkey against the current lists
+func (p *AccessListPolicy) Validate(ctx context.Context, event *nostr.Event) error {
+	entries, err := p.store.Entries(ctx, event.PubKey)
+	if err != nil {
+		return fmt.Errorf("failed to check access list: %w", err)
+	}
+
+	allowed := false
+	for _, entry := range entries {
+		if !entry.Matches(event.Kind) {
+			continue
+		}
+		switch entry.List {
+		case AccessBlock:
+			return prefixViolations("access policy", blockedError(event.Kind, entry))
+		case AccessAllow:
+			allowed = true
+		}
+	}
+
+	if !allowed && p.requiresAllowlist(event.Kind) {
+		return prefixViolations("access policy", policyErrorf(CodeNotAllowlisted, "pubkey",
+			"only allowlisted pubkeys may publish %s to this archive", getEventTypeName(event.Kind)))
+	}
+	return nil
+}
+
+func (p *AccessListPolicy) requiresAllowlist(kind int) bool {
+	for _, k := range p.allowlistKinds {
+		if k == kind {
+			return true
+		}
+	}
+	return false
+}
+
+// blockedError describes a blocklist entry, including its reason and expiry
+func blockedError(kind int, entry AccessEntry) *PolicyError {
+	message := fmt.Sprintf("pubkey is blocked from publishing %s", getEventTypeName(kind))
+	if entry.Reason != "" {
+		message += ": " + entry.Reason
+	}
+	if entry.ExpiresAt != nil {
+		message += fmt.Sprintf(" (until %s)", entry.ExpiresAt.UTC().Format(time.RFC3339))
+	}
+	return NewPolicyError(CodeBlockedPubkey, "pubkey", message)
+}
+
+// PostProcess does nothing for access lists
+func (p *AccessListPolicy) PostProcess(ctx context.Context, event *nostr.Event) error {
+	return nil
+}
+
+// Settings reports which kinds require an allowlist entry
+func (p *AccessListPolicy) Settings() map[string]interface{} {
+	allowlisted := make([]string, 0, len(p.allowlistKinds))
+	for _, kind := range p.allowlistKinds {
+		allowlisted = append(allowlisted, EventTypeKey(kind))
+	}
+	return map[string]interface{}{
+		"allowlist_required": allowlisted,
+		"blocklist":          "pubkeys may be blocked per kind or for every kind, with an optional expiry and reason",
+	}
+}
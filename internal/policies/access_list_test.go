@@ -0,0 +1,85 @@
+package policies
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nbd-wtf/go-nostr"
+)
+
+func TestAccessListPolicy(t *testing.T) {
+	ctx := context.Background()
+	member := strings.Repeat("a", 64)
+	outsider := strings.Repeat("b", 64)
+	suspended := strings.Repeat("c", 64)
+
+	store := NewInMemoryAccessList()
+	config := DefaultConfig()
+	config.AccessList.AllowlistKinds = []int{AcademicPaperKind}
+	engine := NewPolicyEngineWithConfig(config, nil, nil, nil)
+	if err := engine.ReplacePolicy(NewAccessListPolicy(store, config.AccessList)); err != nil {
+		t.Fatalf("Failed to replace access list stage: %v", err)
+	}
+
+	paper := func(pubkey string) *nostr.Event {
+		return &nostr.Event{
+			ID:        "access_paper_" + pubkey[:1],
+			PubKey:    pubkey,
+			Kind:      AcademicPaperKind,
+			CreatedAt: nostr.Timestamp(time.Now().Unix()),
+			Tags: nostr.Tags{
+				{"d", "access-list-1"},
+				{"title", "Departmental Archive Access Control"},
+				{"abstract", "This paper describes how a departmental archive restricts who may publish papers to it."},
+				{"subject", "Information Science"},
+				{"author", "Department Member"},
+			},
+		}
+	}
+	codeOf := func(err error) ErrorCode {
+		var policyErr *PolicyError
+		if errors.As(err, &policyErr) {
+			return policyErr.Code
+		}
+		return ""
+	}
+
+	store.Add(ctx, AccessEntry{List: AccessAllow, Pubkey: member, Kind: AcademicPaperKind})
+
+	if err := engine.ValidateEvent(ctx, paper(member)); err != nil {
+		t.Errorf("Allowlisted member was rejected: %v", err)
+	}
+	if code := codeOf(engine.ValidateEvent(ctx, paper(outsider))); code != CodeNotAllowlisted {
+		t.Errorf("Expected not_allowlisted for outsider, got %q", code)
+	}
+
+	// Kinds without an allowlist stay open, but a blocklist entry for every kind applies
+	expires := time.Now().Add(time.Hour)
+	store.Add(ctx, AccessEntry{List: AccessBlock, Pubkey: suspended, Kind: AllKinds, Reason: "spam", ExpiresAt: &expires})
+	discussion := &nostr.Event{
+		PubKey:    suspended,
+		Kind:      AcademicDiscussionKind,
+		CreatedAt: nostr.Timestamp(time.Now().Unix()),
+		Content:   "A discussion long enough to pass the metadata requirements for discussions.",
+		Tags:      nostr.Tags{{"d", "access-list-2"}, {"e", "paper"}},
+	}
+	err := engine.ValidateEvent(ctx, discussion)
+	if codeOf(err) != CodeBlockedPubkey || !contains(OKMessage(err), "blocked:") || !contains(err.Error(), "spam") {
+		t.Errorf("Expected blocked_pubkey with reason, got: %v", err)
+	}
+
+	// Changes apply immediately, and expired entries are ignored
+	store.Remove(ctx, AccessBlock, suspended, AllKinds)
+	past := time.Now().Add(-time.Minute)
+	store.Add(ctx, AccessEntry{List: AccessBlock, Pubkey: suspended, Kind: AcademicDiscussionKind, ExpiresAt: &past})
+	if err := engine.ValidateEvent(ctx, discussion); err != nil {
+		t.Errorf("Expected expired block to be ignored, got: %v", err)
+	}
+
+	if err := store.Add(ctx, AccessEntry{List: "maybe", Pubkey: member}); err == nil {
+		t.Error("Expected invalid list name to be rejected")
+	}
+}
@@ -0,0 +1,101 @@
+package policies
+
+import (
+	"context"
+
+	"github.com/nbd-wtf/go-nostr"
+)
+
+// Authentication modes for auth.mode
+const (
+	// AuthModeOptional accepts NIP-42 authentication but does not require it
+	AuthModeOptional = "optional"
+	// AuthModeRequired only accepts academic events from connections
+	// authenticated as the event's pubkey or one of its declared co-authors
+	AuthModeRequired = "required"
+)
+
+// AuthConfig configures NIP-42 authentication for writes
+type AuthConfig struct {
+	Mode string `json:"mode"`
+}
+
+type authedPubkeyKey struct{}
+
+// WithAuthedPubkey records the pubkey the connection authenticated as with NIP-42
+func WithAuthedPubkey(ctx context.Context, pubkey string) context.Context {
+	return context.WithValue(ctx, authedPubkeyKey{}, pubkey)
+}
+
+// AuthedPubkey returns the authenticated pubkey, or "" if the connection
+// has not authenticated
+func AuthedPubkey(ctx context.Context) string {
+	pubkey, _ := ctx.Value(authedPubkeyKey{}).(string)
+	return pubkey
+}
+
+// IsEventAuthor reports whether pubkey created the event or is declared as
+// a co-author in its p or author-pubkey tags
+func IsEventAuthor(event *nostr.Event, pubkey string) bool {
+	for _, author := range extractAuthorsFromEvent(event) {
+		if author == pubkey {
+			return true
+		}
+	}
+	return false
+}
+
+// AuthPolicy requires writes to come from an authenticated author
+type AuthPolicy struct {
+	mode string
+}
+
+// NewAuthPolicy creates the authentication stage
+func NewAuthPolicy(config AuthConfig) *AuthPolicy {
+	if config.Mode == "" {
+		config.Mode = AuthModeOptional
+	}
+	return &AuthPolicy{mode: config.Mode}
+}
+
+// Name returns the stage name
+func (p *AuthPolicy) Name() string { return PolicyAuth }
+
+// Kinds returns nil: authentication applies to every kind
+func (p *AuthPolicy) Kinds() []int { return nil }
+
+// Validate rejects events from unauthenticated connections, or from
+// connections authenticated as someone other than an author, when
+// authentication is required. Dry runs are not authenticated and skip it.
+func (p *AuthPolicy) Validate(ctx context.Context, event *nostr.Event) error {
+	if p.mode != AuthModeRequired || IsDryRun(ctx) {
+		return nil
+	}
+
+	authed := AuthedPubkey(ctx)
+	if authed == "" {
+		return prefixViolations("auth policy", policyErrorf(CodeAuthRequired, "",
+			"publishing academic events requires NIP-42 authentication"))
+	}
+	if !IsEventAuthor(event, authed) {
+		return prefixViolations("auth policy", policyErrorf(CodeNotAuthor, "pubkey",
+			"authenticated as %s, which is neither the event author nor a declared co-author", authed))
+	}
+	return nil
+}
+
+// PostProcess does nothing for authentication
+func (p *AuthPolicy) PostProcess(ctx context.Context, event *nostr.Event) error {
+	return nil
+}
+
+// Settings reports the mode and who may publish
+func (p *AuthPolicy) Settings() map[string]interface{} {
+	settings := map[string]interface{}{
+		"mode": p.mode,
+	}
+	if p.mode == AuthModeRequired {
+		settings["authorized"] = "the event pubkey or a co-author listed in p or author-pubkey tags"
+	}
+	return settings
+}
@@ -0,0 +1,101 @@
+package policies
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/nbd-wtf/go-nostr"
+)
+
+func TestAuthPolicy(t *testing.T) {
+	config := DefaultConfig()
+	config.Auth.Mode = AuthModeRequired
+	engine := NewPolicyEngineWithConfig(config, nil, nil, nil)
+
+	discussion := &nostr.Event{
+		ID:        "auth_discussion",
+		PubKey:    "discussion_author",
+		Kind:      AcademicDiscussionKind,
+		CreatedAt: nostr.Timestamp(time.Now().Unix()),
+		Content:   "This discussion is long enough to satisfy the minimum content length for discussions.",
+		Tags: nostr.Tags{
+			{"d", "auth-1"},
+			{"e", "paper_id"},
+			{"p", "co_author"},
+		},
+	}
+
+	tests := []struct {
+		name   string
+		authed string
+		code   ErrorCode
+		prefix string
+	}{
+		{"unauthenticated", "", CodeAuthRequired, "auth-required:"},
+		{"authenticated as someone else", "stranger", CodeNotAuthor, "restricted:"},
+		{"authenticated as the author", "discussion_author", "", ""},
+		{"authenticated as a declared co-author", "co_author", "", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			if tt.authed != "" {
+				ctx = WithAuthedPubkey(ctx, tt.authed)
+			}
+
+			err := engine.ValidateEvent(ctx, discussion)
+			if tt.code == "" {
+				if err != nil {
+					t.Errorf("Expected event to be accepted, got: %v", err)
+				}
+				return
+			}
+
+			var policyErr *PolicyError
+			if !errors.As(err, &policyErr) || policyErr.Code != tt.code {
+				t.Fatalf("Expected %s, got: %v", tt.code, err)
+			}
+			if msg := OKMessage(err); !contains(msg, tt.prefix) {
+				t.Errorf("Expected OK message starting with %q, got %q", tt.prefix, msg)
+			}
+		})
+	}
+
+	t.Run("dry runs are not authenticated", func(t *testing.T) {
+		if report := engine.DryRun(context.Background(), discussion); !report.Valid {
+			t.Errorf("Expected dry run to skip authentication, got %+v", report.Violations)
+		}
+	})
+}
+
+func TestRateLimitKeyedOnAuthedIdentity(t *testing.T) {
+	limiter := NewMemoryRateLimiter(&RateLimitConfig{
+		EventsPerWindow: 100,
+		WindowDuration:  time.Hour,
+		KindLimits: map[int]KindLimit{
+			AcademicPaperKind: {EventsPerWindow: 1, WindowDuration: 24 * time.Hour},
+		},
+	})
+	ctx := WithAuthedPubkey(context.Background(), "lab_manager")
+
+	first := &nostr.Event{PubKey: "student_one", Kind: AcademicPaperKind}
+	reservation, err := ReserveRateLimit(ctx, first, limiter)
+	if err != nil {
+		t.Fatalf("First paper failed: %v", err)
+	}
+	reservation.Commit(ctx)
+
+	// The authenticated identity's quota is used up, even for another author
+	second := &nostr.Event{PubKey: "student_two", Kind: AcademicPaperKind}
+	if _, err := ReserveRateLimit(ctx, second, limiter); err == nil {
+		t.Error("Expected the authenticated identity to be rate limited")
+	}
+
+	// The failed reservation did not consume the second author's quota
+	if _, err := ReserveRateLimit(context.Background(), second, limiter); err != nil {
+		t.Errorf("Expected second author to have quota left, got: %v", err)
+	}
+}
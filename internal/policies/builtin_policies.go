@@ -0,0 +1,302 @@
+package policies
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/nbd-wtf/go-nostr"
+)
+
+// RateLimitPolicy enforces per-pubkey submission limits. Quota is reserved
+// during validation and only consumed once the event has been stored.
+type RateLimitPolicy struct {
+	limiter RateLimiter
+	config  *RateLimitConfig
+	
+	mu      sync.Mutex
+	pending map[string][]Reservation
+}
+
+// NewRateLimitPolicy creates the rate limit stage; config is used for reporting only
+func NewRateLimitPolicy(limiter RateLimiter, config *RateLimitConfig) *RateLimitPolicy {
+	if config == nil {
+		config = DefaultRateLimitConfig()
+	}
+	return &RateLimitPolicy{
+		limiter: limiter,
+		config:  config,
+		pending: make(map[string][]Reservation),
+	}
+}
+
+// Name returns the stage name
+func (p *RateLimitPolicy) Name() string { return PolicyRateLimit }
+
+// Kinds returns nil: rate limits apply to every kind
+func (p *RateLimitPolicy) Kinds() []int { return nil }
+
+// Validate reserves quota for the submitting pubkey.
+// Dry runs are skipped so they never consume quota.
+func (p *RateLimitPolicy) Validate(ctx context.Context, event *nostr.Event) error {
+	if IsDryRun(ctx) {
+		return nil
+	}
+	reservation, err := ReserveRateLimit(ctx, event, p.limiter)
+	if err != nil {
+		return prefixViolations("rate limit policy", err)
+	}
+	
+	p.mu.Lock()
+	p.pending[event.ID] = append(p.pending[event.ID], reservation)
+	p.mu.Unlock()
+	return nil
+}
+
+// PostProcess commits the quota reserved for a stored event
+func (p *RateLimitPolicy) PostProcess(ctx context.Context, event *nostr.Event) error {
+	reservation := p.takeReservation(event.ID)
+	if reservation == nil {
+		return nil
+	}
+	if err := reservation.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit rate limit reservation: %w", err)
+	}
+	return nil
+}
+
+// Rollback releases the quota reserved for an event that was not stored
+func (p *RateLimitPolicy) Rollback(ctx context.Context, event *nostr.Event) error {
+	reservation := p.takeReservation(event.ID)
+	if reservation == nil {
+		return nil
+	}
+	if err := reservation.Rollback(ctx); err != nil {
+		return fmt.Errorf("failed to release rate limit reservation: %w", err)
+	}
+	return nil
+}
+
+// takeReservation removes the oldest pending reservation for an event ID.
+// The same event may be submitted concurrently, so several can be pending.
+func (p *RateLimitPolicy) takeReservation(eventID string) Reservation {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	
+	reservations := p.pending[eventID]
+	if len(reservations) == 0 {
+		return nil
+	}
+	if len(reservations) == 1 {
+		delete(p.pending, eventID)
+	} else {
+		p.pending[eventID] = reservations[1:]
+	}
+	return reservations[0]
+}
+
+// Settings reports the general and per-kind limits
+func (p *RateLimitPolicy) Settings() map[string]interface{} {
+	limits := map[string]interface{}{
+		"general": fmt.Sprintf("%d events per %v", p.config.EventsPerWindow, p.config.WindowDuration),
+	}
+	for kind, limit := range p.config.KindLimits {
+		limits[EventTypeKey(kind)] = limit.String()
+	}
+	return limits
+}
+
+// MetadataPolicy enforces required tags and minimum lengths
+type MetadataPolicy struct {
+	rules ValidationRules
+}
+
+// NewMetadataPolicy creates the metadata stage
+func NewMetadataPolicy(rules ValidationRules) *MetadataPolicy {
+	if rules == nil {
+		rules = DefaultValidationRules()
+	}
+	return &MetadataPolicy{rules: rules}
+}
+
+// Name returns the stage name
+func (p *MetadataPolicy) Name() string { return PolicyMetadata }
+
+// Kinds returns nil: every event needs valid metadata
+func (p *MetadataPolicy) Kinds() []int { return nil }
+
+// Validate checks the event against the configured rules
+func (p *MetadataPolicy) Validate(ctx context.Context, event *nostr.Event) error {
+	if err := RequireMinimalMetadataWithRules(event, p.rules); err != nil {
+		return prefixViolations("metadata policy", err)
+	}
+	return nil
+}
+
+// PostProcess does nothing for metadata
+func (p *MetadataPolicy) PostProcess(ctx context.Context, event *nostr.Event) error {
+	return nil
+}
+
+// Settings lists the requirements for each kind
+func (p *MetadataPolicy) Settings() map[string]interface{} {
+	requirements := make(map[string]interface{}, len(p.rules))
+	for kind, rules := range p.rules {
+		requirements[EventTypeKey(kind)] = describeKindRules(rules)
+	}
+	return requirements
+}
+
+// DuplicatePolicy rejects papers and datasets already in the archive, by
+// content or by a standard identifier such as a DOI. Identifiers are claimed
+// during validation, so of two events claiming one only the first passes,
+// and released if the event is not stored.
+type DuplicatePolicy struct {
+	checker     DuplicateChecker
+	identifiers IdentifierStore
+	hasher      ContentHasher
+
+	mu      sync.Mutex
+	pending map[string][][]Identifier
+}
+
+// NewDuplicatePolicy creates the duplicate prevention stage. A nil identifier
+// store keeps identifier claims in memory.
+func NewDuplicatePolicy(checker DuplicateChecker, identifiers IdentifierStore) *DuplicatePolicy {
+	if identifiers == nil {
+		identifiers = NewInMemoryIdentifierStore()
+	}
+	return &DuplicatePolicy{
+		checker:     checker,
+		identifiers: identifiers,
+		hasher:      &DefaultContentHasher{},
+		pending:     make(map[string][][]Identifier),
+	}
+}
+
+// Name returns the stage name
+func (p *DuplicatePolicy) Name() string { return PolicyDuplicates }
+
+// Kinds returns the kinds that are checked for duplicates
+func (p *DuplicatePolicy) Kinds() []int {
+	return []int{AcademicPaperKind, AcademicDataKind}
+}
+
+// Validate rejects events whose content hash is already stored or that claim
+// an identifier belonging to another address. The event's identifiers are
+// claimed for its address; dry runs only check them.
+func (p *DuplicatePolicy) Validate(ctx context.Context, event *nostr.Event) error {
+	errs := []error{PreventDuplicatePapers(ctx, event, p.checker)}
+	if IsDryRun(ctx) {
+		errs = append(errs, PreventDuplicateIdentifiers(ctx, event, p.identifiers))
+	} else {
+		claimed, err := ClaimEventIdentifiers(ctx, event, p.identifiers)
+		errs = append(errs, err)
+		if len(claimed) > 0 {
+			p.mu.Lock()
+			p.pending[event.ID] = append(p.pending[event.ID], claimed)
+			p.mu.Unlock()
+		}
+	}
+	if err := joinViolations(errs); err != nil {
+		return prefixViolations("duplicate prevention", err)
+	}
+	return nil
+}
+
+// PostProcess stores the content hash of an accepted event and keeps the
+// identifiers claimed for it
+func (p *DuplicatePolicy) PostProcess(ctx context.Context, event *nostr.Event) error {
+	p.takeClaims(event.ID)
+	hash := p.hasher.GenerateHash(event)
+	if err := p.checker.StoreHash(ctx, event, hash); err != nil {
+		return fmt.Errorf("failed to store content hash: %w", err)
+	}
+	return nil
+}
+
+// Rollback releases the identifiers claimed for an event that was not stored
+func (p *DuplicatePolicy) Rollback(ctx context.Context, event *nostr.Event) error {
+	claimed := p.takeClaims(event.ID)
+	if len(claimed) == 0 {
+		return nil
+	}
+	if err := p.identifiers.ReleaseIdentifiers(ctx, event, claimed); err != nil {
+		return fmt.Errorf("failed to release identifiers: %w", err)
+	}
+	return nil
+}
+
+// takeClaims removes the oldest pending claims for an event ID. As with rate
+// limit reservations, the same event may be submitted concurrently.
+func (p *DuplicatePolicy) takeClaims(eventID string) []Identifier {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	claims := p.pending[eventID]
+	if len(claims) == 0 {
+		return nil
+	}
+	if len(claims) == 1 {
+		delete(p.pending, eventID)
+	} else {
+		p.pending[eventID] = claims[1:]
+	}
+	return claims[0]
+}
+
+// Settings describes what is compared
+func (p *DuplicatePolicy) Settings() map[string]interface{} {
+	settings := map[string]interface{}{
+		"papers":      "title, authors and abstract (case-insensitive)",
+		"data":        "file SHA-256 (x tag), or content, data-type and description without one",
+		"identifiers": IdentifierSchemes,
+	}
+	if checker, ok := p.checker.(*NearDuplicateChecker); ok {
+		settings["similarity_threshold"] = checker.Threshold()
+	}
+	return settings
+}
+
+// ReviewIntegrityPolicy prevents conflicts of interest in peer review
+type ReviewIntegrityPolicy struct {
+	store PaperAuthorStore
+}
+
+// NewReviewIntegrityPolicy creates the review integrity stage
+func NewReviewIntegrityPolicy(store PaperAuthorStore) *ReviewIntegrityPolicy {
+	return &ReviewIntegrityPolicy{store: store}
+}
+
+// Name returns the stage name
+func (p *ReviewIntegrityPolicy) Name() string { return PolicyReviewIntegrity }
+
+// Kinds returns the review kind
+func (p *ReviewIntegrityPolicy) Kinds() []int {
+	return []int{AcademicReviewKind}
+}
+
+// Validate checks the reviewer against the paper's authors
+func (p *ReviewIntegrityPolicy) Validate(ctx context.Context, event *nostr.Event) error {
+	if err := ValidateReviewIntegrity(ctx, event, p.store); err != nil {
+		return prefixViolations("review policy", err)
+	}
+	return nil
+}
+
+// PostProcess does nothing for review integrity
+func (p *ReviewIntegrityPolicy) PostProcess(ctx context.Context, event *nostr.Event) error {
+	return nil
+}
+
+// Settings lists the review requirements
+func (p *ReviewIntegrityPolicy) Settings() map[string]interface{} {
+	return map[string]interface{}{
+		"requirements": []string{
+			"referenced paper must exist",
+			"no self-reviews",
+			"no co-author reviews",
+			"structured feedback (2 of methodology-assessment, strengths, weaknesses, recommendation)",
+		},
+	}
+}
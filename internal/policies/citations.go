@@ -0,0 +1,179 @@
+package policies
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/nbd-wtf/go-nostr"
+)
+
+// Citation resolution statuses
+const (
+	// CitationArchived cites a paper in the archive
+	CitationArchived = "archived"
+	// CitationNotPaper cites an archived event that is not a paper
+	CitationNotPaper = "not_paper"
+	// CitationExternal cites a work outside the archive by DOI or arXiv ID
+	CitationExternal = "external"
+	// CitationUnknown cites nothing the archive can identify
+	CitationUnknown = "unknown"
+)
+
+// CitationConfig configures which citation targets are accepted. By default
+// every citation is accepted and only its resolution is recorded.
+type CitationConfig struct {
+	// RejectUnknown rejects citations of events that are not in the archive,
+	// unless they identify an external work with a doi or arxiv tag
+	RejectUnknown bool `json:"reject_unknown"`
+	// RejectNonPaper rejects citations of archived events that are not papers
+	RejectNonPaper bool `json:"reject_non_paper"`
+}
+
+// CitationResolution records what a citation's reference resolved to when
+// the citation was accepted
+type CitationResolution struct {
+	CitationID string    `json:"citation_id" db:"citation_id"`
+	Status     string    `json:"status" db:"status"`
+	TargetID   string    `json:"target_id,omitempty" db:"target_id"`
+	TargetKind int       `json:"target_kind,omitempty" db:"target_kind"`
+	DOI        string    `json:"doi,omitempty" db:"doi"`
+	ArXiv      string    `json:"arxiv,omitempty" db:"arxiv"`
+	ResolvedAt time.Time `json:"resolved_at" db:"resolved_at"`
+}
+
+// CitationStore records citation resolutions
+type CitationStore interface {
+	RecordCitation(ctx context.Context, resolution CitationResolution) error
+	// GetCitation returns the resolution recorded for a citation, or nil
+	GetCitation(ctx context.Context, citationID string) (*CitationResolution, error)
+}
+
+// ResolveCitation looks up the event a citation references, by id or, for an
+// address, its latest version, and classifies the citation
+func ResolveCitation(ctx context.Context, event *nostr.Event, store PaperAuthorStore) (CitationResolution, error) {
+	resolution := CitationResolution{CitationID: event.ID, Status: CitationUnknown}
+	resolution.DOI, resolution.ArXiv = ExternalIdentifiers(event)
+
+	var target *nostr.Event
+	var err error
+	id, address := PaperReference(event)
+	if id != "" {
+		target, err = store.GetEvent(ctx, id)
+	} else if kind, pubkey, d, ok := ParseAddress(address); ok {
+		target, err = latestVersion(ctx, store, kind, pubkey, d)
+	}
+	if err != nil {
+		return resolution, err
+	}
+
+	switch {
+	case target != nil && target.Kind == AcademicPaperKind:
+		resolution.Status = CitationArchived
+	case target != nil:
+		resolution.Status = CitationNotPaper
+	case resolution.DOI != "" || resolution.ArXiv != "":
+		resolution.Status = CitationExternal
+	}
+	if target != nil {
+		resolution.TargetID = target.ID
+		resolution.TargetKind = target.Kind
+	}
+	return resolution, nil
+}
+
+// CitationPolicy resolves cited references and, depending on configuration,
+// rejects citations of unknown or non-paper events
+type CitationPolicy struct {
+	papers PaperAuthorStore
+	store  CitationStore
+	config CitationConfig
+}
+
+// NewCitationPolicy creates the citation stage. A nil store keeps resolutions
+// in memory.
+func NewCitationPolicy(papers PaperAuthorStore, store CitationStore, config CitationConfig) *CitationPolicy {
+	if store == nil {
+		store = NewInMemoryCitationStore()
+	}
+	return &CitationPolicy{papers: papers, store: store, config: config}
+}
+
+// Name returns the stage name
+func (p *CitationPolicy) Name() string { return PolicyCitations }
+
+// Kinds returns the citation kind
+func (p *CitationPolicy) Kinds() []int {
+	return []int{AcademicCitationKind}
+}
+
+// Validate resolves the citation and applies the configured restrictions
+func (p *CitationPolicy) Validate(ctx context.Context, event *nostr.Event) error {
+	resolution, err := ResolveCitation(ctx, event, p.papers)
+	if err != nil {
+		return fmt.Errorf("citation policy: cannot look up cited event: %w", err)
+	}
+
+	switch {
+	case resolution.Status == CitationUnknown && p.config.RejectUnknown:
+		return prefixViolations("citation policy", policyErrorf(CodeUnknownReference, "e",
+			"citation must reference an event in the archive or identify an external work with a 'doi' or 'arxiv' tag"))
+	case resolution.Status == CitationNotPaper && p.config.RejectNonPaper:
+		return prefixViolations("citation policy", policyErrorf(CodeUnknownReference, "e",
+			"citation must reference a paper, but the cited event is kind %d (%s)", resolution.TargetKind, getEventTypeName(resolution.TargetKind)))
+	}
+	return nil
+}
+
+// PostProcess records what the citation resolved to
+func (p *CitationPolicy) PostProcess(ctx context.Context, event *nostr.Event) error {
+	resolution, err := ResolveCitation(ctx, event, p.papers)
+	if err != nil {
+		return fmt.Errorf("failed to resolve citation: %w", err)
+	}
+	resolution.ResolvedAt = time.Now()
+	if err := p.store.RecordCitation(ctx, resolution); err != nil {
+		return fmt.Errorf("failed to record citation: %w", err)
+	}
+	return nil
+}
+
+// Settings lists which citation targets are accepted
+func (p *CitationPolicy) Settings() map[string]interface{} {
+	return map[string]interface{}{
+		"reject_unknown":       p.config.RejectUnknown,
+		"reject_non_paper":     p.config.RejectNonPaper,
+		"external_identifiers": []string{"doi", "arxiv"},
+	}
+}
+
+// InMemoryCitationStore keeps citation resolutions in memory
+type InMemoryCitationStore struct {
+	mu          sync.RWMutex
+	resolutions map[string]CitationResolution
+}
+
+// NewInMemoryCitationStore creates an empty citation store
+func NewInMemoryCitationStore() *InMemoryCitationStore {
+	return &InMemoryCitationStore{resolutions: make(map[string]CitationResolution)}
+}
+
+// RecordCitation stores a resolution, replacing any earlier one
+func (s *InMemoryCitationStore) RecordCitation(ctx context.Context, resolution CitationResolution) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.resolutions[resolution.CitationID] = resolution
+	return nil
+}
+
+// GetCitation returns the resolution recorded for a citation
+func (s *InMemoryCitationStore) GetCitation(ctx context.Context, citationID string) (*CitationResolution, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	resolution, ok := s.resolutions[citationID]
+	if !ok {
+		return nil, nil
+	}
+	return &resolution, nil
+}
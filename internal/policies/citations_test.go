@@ -0,0 +1,68 @@
+package policies
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/nbd-wtf/go-nostr"
+)
+
+func TestCitationPolicy(t *testing.T) {
+	author := strings.Repeat("a", 64)
+
+	papers := NewInMemoryPaperStore()
+	paper := &nostr.Event{ID: "cited_paper", PubKey: author, Kind: AcademicPaperKind, Tags: nostr.Tags{{"d", "cited-study"}}}
+	papers.StoreEvent(paper)
+	papers.StoreEvent(&nostr.Event{ID: "cited_discussion", PubKey: author, Kind: AcademicDiscussionKind})
+
+	citation := func(id string, tags ...nostr.Tag) *nostr.Event {
+		return &nostr.Event{ID: id, Kind: AcademicCitationKind, Tags: tags}
+	}
+
+	tests := []struct {
+		name   string
+		event  *nostr.Event
+		status string
+		code   ErrorCode // when unknown and non-paper citations are rejected
+	}{
+		{"archived paper", citation("c1", nostr.Tag{"e", paper.ID}), CitationArchived, ""},
+		{"archived paper by address", citation("c2", nostr.Tag{"a", EventAddress(paper)}), CitationArchived, ""},
+		{"discussion", citation("c3", nostr.Tag{"e", "cited_discussion"}), CitationNotPaper, CodeUnknownReference},
+		{"external work by doi", citation("c4", nostr.Tag{"doi", "10.1145/3132747.3132757"}), CitationExternal, ""},
+		{"unarchived event with arxiv id", citation("c5", nostr.Tag{"e", "missing"}, nostr.Tag{"arxiv", "2401.01234"}), CitationExternal, ""},
+		{"unknown event", citation("c6", nostr.Tag{"e", "missing"}), CitationUnknown, CodeUnknownReference},
+	}
+
+	ctx := context.Background()
+	recording := NewCitationPolicy(papers, nil, CitationConfig{})
+	strict := NewCitationPolicy(papers, nil, CitationConfig{RejectUnknown: true, RejectNonPaper: true})
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := recording.Validate(ctx, tt.event); err != nil {
+				t.Errorf("Expected citation to be accepted by default, got: %v", err)
+			}
+			if err := recording.PostProcess(ctx, tt.event); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			resolution, _ := recording.store.GetCitation(ctx, tt.event.ID)
+			if resolution == nil || resolution.Status != tt.status {
+				t.Errorf("Expected status %s, got %+v", tt.status, resolution)
+			}
+
+			err := strict.Validate(ctx, tt.event)
+			if tt.code == "" {
+				if err != nil {
+					t.Errorf("Expected citation to be accepted, got: %v", err)
+				}
+				return
+			}
+			var policyErr *PolicyError
+			if !errors.As(err, &policyErr) || policyErr.Code != tt.code {
+				t.Errorf("Expected %s, got: %v", tt.code, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,346 @@
+package policies
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/nbd-wtf/go-nostr"
+)
+
+// Names of the built-in policy stages
+const (
+	PolicyAuth            = "auth"
+	PolicyAccessList      = "access_list"
+	PolicyRateLimit       = "rate_limit"
+	PolicyMetadata        = "metadata"
+	PolicyDuplicates      = "duplicates"
+	PolicyReviewIntegrity = "review_integrity"
+	PolicyNotices         = "notices"
+	PolicyCitations       = "citations"
+)
+
+// builtinChecks lists the built-in stages in their default order
+var builtinChecks = []string{
+	PolicyAuth,
+	PolicyAccessList,
+	PolicyRateLimit,
+	PolicyMetadata,
+	PolicyDuplicates,
+	PolicyReviewIntegrity,
+	PolicyNotices,
+	PolicyCitations,
+}
+
+// Rate limiter backends selectable with rate_limits.backend
+const (
+	// RateLimitBackendMemory keeps rate limit state in process memory
+	RateLimitBackendMemory = "memory"
+	// RateLimitBackendPostgres stores rate limit state in PostgreSQL so it
+	// survives restarts and is shared by every relay instance
+	RateLimitBackendPostgres = "postgres"
+)
+
+// Config is the declarative policy configuration loaded at relay startup
+type Config struct {
+	// Policy stages that run for each event, in order
+	Checks []string `json:"checks"`
+	// Metadata requirements per academic kind
+	Kinds ValidationRules `json:"kinds"`
+	// Rate limit settings
+	RateLimits RateLimitSettings `json:"rate_limits"`
+	// Reputation tiers that scale kind-specific rate limits
+	Reputation ReputationConfig `json:"reputation"`
+	// NIP-42 authentication requirements for writes
+	Auth AuthConfig `json:"auth"`
+	// Kinds restricted to allowlisted pubkeys
+	AccessList AccessListConfig `json:"access_list"`
+	// Venue editors recognized for retractions
+	Notices NoticeConfig `json:"notices"`
+	// Which cited references are accepted
+	Citations CitationConfig `json:"citations"`
+	// Near-duplicate detection for papers
+	Duplicates DuplicateConfig `json:"duplicates"`
+}
+
+// DuplicateConfig configures near-duplicate detection
+type DuplicateConfig struct {
+	// SimilarityThreshold rejects papers whose title and abstract are at
+	// least this similar, from MinSimilarityThreshold to 1, to an archived
+	// paper. 0 only rejects exact duplicates.
+	SimilarityThreshold float64 `json:"similarity_threshold"`
+}
+
+// RateLimitSettings is the file representation of RateLimitConfig
+type RateLimitSettings struct {
+	Backend         string                    `json:"backend"`
+	EventsPerWindow int                       `json:"events_per_window"`
+	Window          Duration                  `json:"window"`
+	Kinds           map[int]KindLimitSettings `json:"kinds"`
+}
+
+// KindLimitSettings is the file representation of KindLimit
+type KindLimitSettings struct {
+	EventsPerWindow int      `json:"events_per_window"`
+	Window          Duration `json:"window"`
+	Mode            string   `json:"mode,omitempty"`
+	Burst           int      `json:"burst,omitempty"`
+	PowDifficulty   int      `json:"pow_difficulty,omitempty"`
+}
+
+// Duration is a time.Duration written as a string such as "24h" in config files
+type Duration time.Duration
+
+// MarshalJSON encodes the duration in time.Duration string form
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+// UnmarshalJSON parses a duration string such as "90m" or "24h"
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return fmt.Errorf("duration must be a string such as \"24h\": %w", err)
+	}
+	parsed, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	*d = Duration(parsed)
+	return nil
+}
+
+// DefaultConfig returns the built-in policy configuration
+func DefaultConfig() *Config {
+	return &Config{
+		Checks:     append([]string(nil), builtinChecks...),
+		Kinds:      DefaultValidationRules(),
+		RateLimits: rateLimitSettingsFrom(DefaultRateLimitConfig()),
+		Reputation: DefaultReputationConfig(),
+		Auth:       AuthConfig{Mode: AuthModeOptional},
+	}
+}
+
+// LoadConfig reads a policy file, overlaying it on the defaults.
+// Sections omitted from the file keep their default values; a kind listed
+// under "kinds" or "rate_limits.kinds" replaces that kind's defaults entirely.
+func LoadConfig(path string) (*Config, error) {
+	if ext := strings.ToLower(filepath.Ext(path)); ext != ".json" {
+		return nil, fmt.Errorf("unsupported policy config format %q: only .json files are supported", ext)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read policy config: %w", err)
+	}
+
+	config, err := ParseConfig(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	return config, nil
+}
+
+// ParseConfig decodes and validates a JSON policy configuration
+func ParseConfig(data []byte) (*Config, error) {
+	config := DefaultConfig()
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(config); err != nil {
+		return nil, fmt.Errorf("invalid policy config: %w", err)
+	}
+
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+
+	return config, nil
+}
+
+// Validate checks the configuration for values the relay cannot enforce
+func (c *Config) Validate() error {
+	var errs []error
+
+	// Check names are resolved against the engine's registry once custom
+	// stages have been registered, see PolicyEngine.SetOrder
+	seen := make(map[string]bool)
+	for _, check := range c.Checks {
+		if strings.TrimSpace(check) == "" {
+			errs = append(errs, fmt.Errorf("checks: names must not be empty"))
+		}
+		if seen[check] {
+			errs = append(errs, fmt.Errorf("checks: %q listed more than once", check))
+		}
+		seen[check] = true
+	}
+
+	for kind, rules := range c.Kinds {
+		if !IsAcademicKind(kind) {
+			errs = append(errs, fmt.Errorf("kinds: %d is not an academic kind", kind))
+			continue
+		}
+		hasD := false
+		for _, tag := range rules.RequiredTags {
+			if strings.TrimSpace(tag) == "" {
+				errs = append(errs, fmt.Errorf("kinds.%d.required_tags: tag names must not be empty", kind))
+			}
+			hasD = hasD || tag == "d"
+		}
+		// Versions are addressed by the d tag, so it cannot be made optional
+		if !hasD {
+			errs = append(errs, fmt.Errorf("kinds.%d.required_tags: must include \"d\"", kind))
+		}
+		for tag, min := range rules.MinTagLengths {
+			if min < 0 {
+				errs = append(errs, fmt.Errorf("kinds.%d.min_tag_lengths.%s: must not be negative", kind, tag))
+			}
+		}
+		if rules.MinContentLength < 0 {
+			errs = append(errs, fmt.Errorf("kinds.%d.min_content_length: must not be negative", kind))
+		}
+	}
+	for _, kind := range AcademicKinds {
+		if _, ok := c.Kinds[kind]; !ok {
+			errs = append(errs, fmt.Errorf("kinds: missing rules for kind %d", kind))
+		}
+	}
+
+	switch c.RateLimits.Backend {
+	case RateLimitBackendMemory, RateLimitBackendPostgres:
+	default:
+		errs = append(errs, fmt.Errorf("rate_limits.backend: must be %q or %q, got %q",
+			RateLimitBackendMemory, RateLimitBackendPostgres, c.RateLimits.Backend))
+	}
+	if c.RateLimits.EventsPerWindow <= 0 {
+		errs = append(errs, fmt.Errorf("rate_limits.events_per_window: must be positive"))
+	}
+	if c.RateLimits.Window <= 0 {
+		errs = append(errs, fmt.Errorf("rate_limits.window: must be positive"))
+	}
+	for kind, limit := range c.RateLimits.Kinds {
+		if !IsAcademicKind(kind) {
+			errs = append(errs, fmt.Errorf("rate_limits.kinds: %d is not an academic kind", kind))
+			continue
+		}
+		if limit.EventsPerWindow <= 0 {
+			errs = append(errs, fmt.Errorf("rate_limits.kinds.%d.events_per_window: must be positive", kind))
+		}
+		if limit.Window <= 0 {
+			errs = append(errs, fmt.Errorf("rate_limits.kinds.%d.window: must be positive", kind))
+		}
+		if limit.PowDifficulty < 0 || limit.PowDifficulty > 256 {
+			errs = append(errs, fmt.Errorf("rate_limits.kinds.%d.pow_difficulty: must be between 0 and 256", kind))
+		}
+		switch limit.Mode {
+		case "", RateLimitModeWindow:
+			if limit.Burst != 0 {
+				errs = append(errs, fmt.Errorf("rate_limits.kinds.%d.burst: only applies to %q mode", kind, RateLimitModeTokenBucket))
+			}
+		case RateLimitModeTokenBucket:
+			if limit.Burst < 0 {
+				errs = append(errs, fmt.Errorf("rate_limits.kinds.%d.burst: must not be negative", kind))
+			}
+		default:
+			errs = append(errs, fmt.Errorf("rate_limits.kinds.%d.mode: must be %q or %q, got %q",
+				kind, RateLimitModeWindow, RateLimitModeTokenBucket, limit.Mode))
+		}
+	}
+
+	errs = append(errs, c.Reputation.validate()...)
+
+	for _, kind := range c.AccessList.AllowlistKinds {
+		if !IsAcademicKind(kind) {
+			errs = append(errs, fmt.Errorf("access_list.allowlist_kinds: %d is not an academic kind", kind))
+		}
+	}
+	if len(c.AccessList.AllowlistKinds) > 0 && !c.CheckEnabled(PolicyAccessList) {
+		errs = append(errs, fmt.Errorf("access_list.allowlist_kinds: requires the %q check to be listed in checks", PolicyAccessList))
+	}
+
+	for i, editor := range c.Notices.Editors {
+		if !nostr.IsValid32ByteHex(editor) {
+			errs = append(errs, fmt.Errorf("notices.editors.%d: must be 64 lowercase hex characters", i))
+		}
+	}
+
+	if (c.Citations.RejectUnknown || c.Citations.RejectNonPaper) && !c.CheckEnabled(PolicyCitations) {
+		errs = append(errs, fmt.Errorf("citations: rejecting citations requires the %q check to be listed in checks", PolicyCitations))
+	}
+
+	if threshold := c.Duplicates.SimilarityThreshold; threshold != 0 && (threshold < MinSimilarityThreshold || threshold > 1) {
+		errs = append(errs, fmt.Errorf("duplicates.similarity_threshold: must be 0 or between %g and 1", MinSimilarityThreshold))
+	} else if c.Duplicates.SimilarityThreshold > 0 && !c.CheckEnabled(PolicyDuplicates) {
+		errs = append(errs, fmt.Errorf("duplicates.similarity_threshold: requires the %q check to be listed in checks", PolicyDuplicates))
+	}
+
+	switch c.Auth.Mode {
+	case AuthModeOptional:
+	case AuthModeRequired:
+		if !c.CheckEnabled(PolicyAuth) {
+			errs = append(errs, fmt.Errorf("auth.mode: %q requires the %q check to be listed in checks", AuthModeRequired, PolicyAuth))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("auth.mode: must be %q or %q, got %q", AuthModeOptional, AuthModeRequired, c.Auth.Mode))
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("invalid policy config: %w", errors.Join(errs...))
+	}
+	return nil
+}
+
+// CheckEnabled reports whether the named check is configured to run
+func (c *Config) CheckEnabled(name string) bool {
+	for _, check := range c.Checks {
+		if check == name {
+			return true
+		}
+	}
+	return false
+}
+
+// RateLimitConfig converts the rate limit settings for use by a RateLimiter
+func (c *Config) RateLimitConfig() *RateLimitConfig {
+	config := &RateLimitConfig{
+		EventsPerWindow: c.RateLimits.EventsPerWindow,
+		WindowDuration:  time.Duration(c.RateLimits.Window),
+		KindLimits:      make(map[int]KindLimit, len(c.RateLimits.Kinds)),
+	}
+	for kind, limit := range c.RateLimits.Kinds {
+		config.KindLimits[kind] = KindLimit{
+			EventsPerWindow: limit.EventsPerWindow,
+			WindowDuration:  time.Duration(limit.Window),
+			Mode:            limit.Mode,
+			Burst:           limit.Burst,
+			PowDifficulty:   limit.PowDifficulty,
+		}
+	}
+	return config
+}
+
+// rateLimitSettingsFrom converts a RateLimitConfig to its file representation
+func rateLimitSettingsFrom(config *RateLimitConfig) RateLimitSettings {
+	settings := RateLimitSettings{
+		Backend:         RateLimitBackendMemory,
+		EventsPerWindow: config.EventsPerWindow,
+		Window:          Duration(config.WindowDuration),
+		Kinds:           make(map[int]KindLimitSettings, len(config.KindLimits)),
+	}
+	for kind, limit := range config.KindLimits {
+		settings.Kinds[kind] = KindLimitSettings{
+			EventsPerWindow: limit.EventsPerWindow,
+			Window:          Duration(limit.WindowDuration),
+			Mode:            limit.Mode,
+			Burst:           limit.Burst,
+			PowDifficulty:   limit.PowDifficulty,
+		}
+	}
+	return settings
+}
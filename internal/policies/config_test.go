@@ -0,0 +1,158 @@
+package policies
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestDefaultConfig(t *testing.T) {
+	config := DefaultConfig()
+
+	if err := config.Validate(); err != nil {
+		t.Fatalf("Default config should be valid: %v", err)
+	}
+
+	for _, check := range builtinChecks {
+		if !config.CheckEnabled(check) {
+			t.Errorf("Expected check %s to be enabled by default", check)
+		}
+	}
+
+	rateLimits := config.RateLimitConfig()
+	defaults := DefaultRateLimitConfig()
+	if rateLimits.EventsPerWindow != defaults.EventsPerWindow || rateLimits.WindowDuration != defaults.WindowDuration {
+		t.Errorf("Expected general limit %d per %v, got %d per %v",
+			defaults.EventsPerWindow, defaults.WindowDuration, rateLimits.EventsPerWindow, rateLimits.WindowDuration)
+	}
+	if rateLimits.KindLimits[AcademicPaperKind] != defaults.KindLimits[AcademicPaperKind] {
+		t.Errorf("Expected paper limit %+v, got %+v", defaults.KindLimits[AcademicPaperKind], rateLimits.KindLimits[AcademicPaperKind])
+	}
+}
+
+func TestParseConfig(t *testing.T) {
+	t.Run("overrides are applied on top of defaults", func(t *testing.T) {
+		config, err := ParseConfig([]byte(`{
+			"checks": ["metadata", "duplicates"],
+			"kinds": {
+				"31428": {"required_tags": ["d", "title", "abstract"], "min_tag_lengths": {"title": 20}}
+			},
+			"rate_limits": {
+				"kinds": {
+					"31428": {"events_per_window": 2, "window": "12h"},
+					"31431": {"events_per_window": 10, "window": "24h", "mode": "token_bucket", "burst": 10}
+				}
+			}
+		}`))
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		if config.CheckEnabled(PolicyRateLimit) {
+			t.Error("Rate limit check should have been disabled")
+		}
+		if got := config.Kinds[AcademicPaperKind].MinTagLengths["title"]; got != 20 {
+			t.Errorf("Expected title minimum 20, got %d", got)
+		}
+		if _, ok := config.Kinds[AcademicCitationKind]; !ok {
+			t.Error("Citation rules should keep their defaults")
+		}
+
+		paperLimit := config.RateLimitConfig().KindLimits[AcademicPaperKind]
+		if paperLimit.EventsPerWindow != 2 || paperLimit.WindowDuration != 12*time.Hour {
+			t.Errorf("Expected 2 papers per 12h, got %+v", paperLimit)
+		}
+		dataLimit := config.RateLimitConfig().KindLimits[AcademicDataKind]
+		if !dataLimit.IsTokenBucket() || dataLimit.BurstSize() != 10 {
+			t.Errorf("Expected token bucket with burst 10 for data, got %+v", dataLimit)
+		}
+		if config.RateLimits.EventsPerWindow != 100 {
+			t.Errorf("General limit should keep its default, got %d", config.RateLimits.EventsPerWindow)
+		}
+	})
+
+	invalid := []struct {
+		name   string
+		config string
+	}{
+		{"unknown field", `{"rate_limit": {}}`},
+		{"empty check name", `{"checks": [""]}`},
+		{"duplicate check", `{"checks": ["metadata", "metadata"]}`},
+		{"non-academic kind", `{"kinds": {"1": {"required_tags": ["title"]}}}`},
+		{"negative length", `{"kinds": {"31428": {"required_tags": ["d", "title"], "min_tag_lengths": {"title": -1}}}}`},
+		{"required tags without d", `{"kinds": {"31428": {"required_tags": ["title", "abstract"]}}}`},
+		{"unknown rate limit mode", `{"rate_limits": {"kinds": {"31431": {"events_per_window": 10, "window": "24h", "mode": "leaky"}}}}`},
+		{"burst in window mode", `{"rate_limits": {"kinds": {"31431": {"events_per_window": 10, "window": "24h", "burst": 5}}}}`},
+		{"tier without multiplier", `{"reputation": {"enabled": true, "tiers": [{"name": "new"}]}}`},
+		{"first tier with requirements", `{"reputation": {"enabled": true, "tiers": [{"name": "senior", "min_papers": 5, "multiplier": 2}]}}`},
+		{"pow difficulty out of range", `{"rate_limits": {"kinds": {"31428": {"events_per_window": 5, "window": "24h", "pow_difficulty": 300}}}}`},
+		{"required auth without auth check", `{"checks": ["metadata"], "auth": {"mode": "required"}}`},
+		{"unknown auth mode", `{"auth": {"mode": "sometimes"}}`},
+		{"allowlist for non-academic kind", `{"access_list": {"allowlist_kinds": [1]}}`},
+		{"malformed editor pubkey", `{"notices": {"editors": ["editor"]}}`},
+		{"citation rejection without check", `{"checks": ["metadata"], "citations": {"reject_unknown": true}}`},
+		{"similarity threshold above 1", `{"duplicates": {"similarity_threshold": 1.5}}`},
+		{"similarity threshold below the minimum", `{"duplicates": {"similarity_threshold": 0.3}}`},
+		{"negative similarity threshold", `{"duplicates": {"similarity_threshold": -0.8}}`},
+		{"similarity threshold without check", `{"checks": ["metadata"], "duplicates": {"similarity_threshold": 0.8}}`},
+		{"unknown backend", `{"rate_limits": {"backend": "redis"}}`},
+		{"bad duration", `{"rate_limits": {"window": "one day"}}`},
+		{"zero limit", `{"rate_limits": {"kinds": {"31428": {"events_per_window": 0, "window": "1h"}}}}`},
+		{"malformed json", `{"checks": [`},
+	}
+
+	for _, tt := range invalid {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := ParseConfig([]byte(tt.config)); err == nil {
+				t.Error("Expected config to be rejected")
+			}
+		})
+	}
+}
+
+func TestLoadConfig(t *testing.T) {
+	dir := t.TempDir()
+
+	path := filepath.Join(dir, "policy.json")
+	if err := os.WriteFile(path, []byte(`{"rate_limits": {"events_per_window": 42, "window": "30m"}}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	config, err := LoadConfig(path)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if config.RateLimits.EventsPerWindow != 42 || time.Duration(config.RateLimits.Window) != 30*time.Minute {
+		t.Errorf("Expected 42 events per 30m, got %+v", config.RateLimits)
+	}
+
+	if _, err := LoadConfig(filepath.Join(dir, "missing.json")); err == nil {
+		t.Error("Expected error for missing file")
+	}
+
+	if _, err := LoadConfig(filepath.Join(dir, "policy.yaml")); err == nil {
+		t.Error("Expected error for unsupported format")
+	}
+}
+
+func TestPolicyEngineUsesConfig(t *testing.T) {
+	config := DefaultConfig()
+	config.Kinds[AcademicPaperKind] = KindRules{
+		RequiredTags:  []string{"title", "author"},
+		MinTagLengths: map[string]int{"title": 5},
+	}
+	config.RateLimits.Kinds[AcademicPaperKind] = KindLimitSettings{EventsPerWindow: 1, Window: Duration(time.Hour)}
+
+	engine := NewPolicyEngineWithConfig(config, nil, nil, nil)
+
+	info := engine.GetPolicyInfo()
+	rateLimits := info["rate_limits"].(map[string]interface{})
+	if rateLimits["papers"] != "1 per 1h0m0s" {
+		t.Errorf("Expected loaded paper limit in policy info, got %v", rateLimits["papers"])
+	}
+	requirements := info["content_requirements"].(map[string]interface{})["papers"].([]string)
+	if len(requirements) != 2 || requirements[0] != "title tag (min 5 chars)" {
+		t.Errorf("Expected loaded paper requirements in policy info, got %v", requirements)
+	}
+}
@@ -0,0 +1,94 @@
+package policies
+
+import (
+	"context"
+	"errors"
+
+	"github.com/nbd-wtf/go-nostr"
+)
+
+type dryRunKey struct{}
+
+// WithDryRun marks a context as a dry run: stages must not record anything
+// or consume quota while validating under it
+func WithDryRun(ctx context.Context) context.Context {
+	return context.WithValue(ctx, dryRunKey{}, true)
+}
+
+// IsDryRun reports whether validation is running as a dry run
+func IsDryRun(ctx context.Context) bool {
+	dryRun, _ := ctx.Value(dryRunKey{}).(bool)
+	return dryRun
+}
+
+// Violation is a single policy failure found during validation
+type Violation struct {
+	// Stage is the policy stage that reported the violation
+	Stage string `json:"stage"`
+	PolicyError
+	// Reason is the message the relay would send in its OK response
+	Reason string `json:"reason"`
+}
+
+// ValidationReport lists every violation found for an event
+type ValidationReport struct {
+	Valid      bool        `json:"valid"`
+	EventID    string      `json:"event_id,omitempty"`
+	Kind       int         `json:"kind"`
+	Violations []Violation `json:"violations"`
+}
+
+// NewValidationReport creates an empty report for an event
+func NewValidationReport(event *nostr.Event) *ValidationReport {
+	return &ValidationReport{
+		Valid:      true,
+		EventID:    event.ID,
+		Kind:       event.Kind,
+		Violations: []Violation{},
+	}
+}
+
+// Add records the violations contained in err under the given stage
+func (r *ValidationReport) Add(stage string, err error) {
+	if err == nil {
+		return
+	}
+
+	for _, violation := range Violations(err) {
+		v := Violation{Stage: stage, Reason: OKMessage(violation)}
+
+		var policyErr *PolicyError
+		if errors.As(violation, &policyErr) {
+			v.PolicyError = *policyErr
+			v.Message = violation.Error()
+		} else {
+			v.Code = CodeInternal
+			v.Message = violation.Error()
+		}
+
+		r.Violations = append(r.Violations, v)
+	}
+	r.Valid = len(r.Violations) == 0
+}
+
+// DryRun validates an event against every enabled stage without storing
+// anything or consuming rate limits, collecting all violations found
+func (pe *PolicyEngine) DryRun(ctx context.Context, event *nostr.Event) *ValidationReport {
+	report := NewValidationReport(event)
+	ctx = WithDryRun(ctx)
+
+	stages, err := pe.stages()
+	if err != nil {
+		report.Add("engine", err)
+		return report
+	}
+
+	for _, stage := range stages {
+		if !appliesTo(stage, event.Kind) {
+			continue
+		}
+		report.Add(stage.Name(), stage.Validate(ctx, event))
+	}
+
+	return report
+}
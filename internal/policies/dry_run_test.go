@@ -0,0 +1,97 @@
+package policies
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/nbd-wtf/go-nostr"
+)
+
+func TestDryRun(t *testing.T) {
+	ctx := context.Background()
+
+	rateLimiter := NewMemoryRateLimiter(&RateLimitConfig{
+		EventsPerWindow: 10,
+		WindowDuration:  time.Minute,
+		KindLimits: map[int]KindLimit{
+			AcademicPaperKind: {EventsPerWindow: 1, WindowDuration: time.Minute},
+		},
+	})
+	paperStore := NewInMemoryPaperStore()
+	engine := NewPolicyEngine(rateLimiter, nil, paperStore)
+
+	paper := &nostr.Event{
+		ID:        "dry_run_paper",
+		PubKey:    "dry_run_author",
+		Kind:      AcademicPaperKind,
+		CreatedAt: nostr.Timestamp(time.Now().Unix()),
+		Tags: nostr.Tags{
+			{"d", "dry-run-1"},
+			{"title", "Dry Run Validation for Authoring Tools"},
+			{"abstract", "This paper explains how authoring tools can ask a relay whether it would accept a submission."},
+			{"subject", "Computer Science"},
+			{"author", "Dry Run Author"},
+		},
+	}
+
+	t.Run("valid paper produces an empty report", func(t *testing.T) {
+		report := engine.DryRun(ctx, paper)
+		if !report.Valid || len(report.Violations) != 0 {
+			t.Errorf("Expected valid report, got %+v", report)
+		}
+	})
+
+	t.Run("dry runs do not consume rate limits or store hashes", func(t *testing.T) {
+		for i := 0; i < 3; i++ {
+			if report := engine.DryRun(ctx, paper); !report.Valid {
+				t.Fatalf("Dry run %d failed: %+v", i, report.Violations)
+			}
+		}
+
+		// The single allowed paper is still available, and the paper is not a duplicate
+		if err := engine.ValidateEvent(ctx, paper); err != nil {
+			t.Errorf("Real submission after dry runs failed: %v", err)
+		}
+	})
+
+	t.Run("violations from every stage are reported", func(t *testing.T) {
+		paperStore.StoreEvent(paper)
+
+		selfReview := &nostr.Event{
+			ID:     "dry_run_review",
+			PubKey: "dry_run_author",
+			Kind:   AcademicReviewKind,
+			Tags: nostr.Tags{
+				{"d", "dry-run-2"},
+				{"e", paper.ID},
+				{"content", "Great paper!"},
+				{"strengths", "Everything"},
+				{"weaknesses", "Nothing"},
+			},
+			CreatedAt: nostr.Timestamp(time.Now().Unix()),
+		}
+
+		report := engine.DryRun(ctx, selfReview)
+		if report.Valid {
+			t.Fatal("Expected invalid report")
+		}
+
+		stages := make(map[string]Violation)
+		for _, v := range report.Violations {
+			stages[v.Stage] = v
+		}
+
+		metadata, ok := stages[PolicyMetadata]
+		if !ok || metadata.Code != CodeTooShort || metadata.Field != "content" {
+			t.Errorf("Expected too_short content violation from metadata stage, got %+v", report.Violations)
+		}
+		integrity, ok := stages[PolicyReviewIntegrity]
+		if !ok || integrity.Code != CodeConflictOfInterest {
+			t.Errorf("Expected conflict_of_interest violation from review stage, got %+v", report.Violations)
+		}
+		if integrity.Reason == "" || !contains(integrity.Reason, "blocked:") {
+			t.Errorf("Expected OK reason with blocked prefix, got %q", integrity.Reason)
+		}
+	})
+}
@@ -6,8 +6,10 @@ import (
 	"encoding/hex"
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/nbd-wtf/go-nostr"
+	"github.com/nbd-wtf/go-nostr/nip19"
 )
 
 // ContentHasher generates a deterministic hash for academic content
@@ -15,13 +17,73 @@ type ContentHasher interface {
 	GenerateHash(event *nostr.Event) string
 }
 
-// DuplicateChecker checks for duplicate academic content
+// DuplicateChecker checks for duplicate academic content. A new version of an
+// event, published at the same NIP-33 address, is never a duplicate of the
+// versions before it.
 type DuplicateChecker interface {
-	IsDuplicate(ctx context.Context, event *nostr.Event) (bool, error)
+	// IsDuplicate returns the archived events an event duplicates, if any
+	IsDuplicate(ctx context.Context, event *nostr.Event) ([]DuplicateMatch, error)
 	StoreHash(ctx context.Context, event *nostr.Event, hash string) error
 }
 
-// DefaultContentHasher implements ContentHasher
+// DuplicateMatch is an archived event that a submission duplicates
+type DuplicateMatch struct {
+	EventID string `json:"event_id" db:"event_id"`
+	// Address is the NIP-33 address of the archived event, if it has one
+	Address string `json:"address,omitempty" db:"address"`
+	// Hash is the content hash both events share. Near-duplicates match by
+	// similarity rather than by hash and have none.
+	Hash string `json:"hash,omitempty" db:"hash"`
+	// Score is how similar the two events are, 1 for exact duplicates
+	Score    float64   `json:"score" db:"score"`
+	StoredAt time.Time `json:"stored_at" db:"stored_at"`
+}
+
+// Reference identifies the archived event for clients: its id followed by
+// its nevent and, when it has an address, naddr encodings
+func (m DuplicateMatch) Reference() string {
+	kind, pubkey, d, hasAddress := ParseAddress(m.Address)
+
+	var encodings []string
+	if nevent, err := nip19.EncodeEvent(m.EventID, nil, pubkey); err == nil {
+		encodings = append(encodings, nevent)
+	}
+	if hasAddress {
+		if naddr, err := nip19.EncodeEntity(pubkey, kind, d, nil); err == nil {
+			encodings = append(encodings, naddr)
+		}
+	}
+	if len(encodings) == 0 {
+		return m.EventID
+	}
+	return fmt.Sprintf("%s (%s)", m.EventID, strings.Join(encodings, ", "))
+}
+
+// describeMatches lists the first matches for a rejection message, with how
+// similar near-duplicates are and when each match was archived
+func describeMatches(matches []DuplicateMatch) string {
+	const shown = 3
+	described := make([]string, 0, shown+1)
+	for i, match := range matches {
+		if i == shown {
+			described = append(described, fmt.Sprintf("and %d more", len(matches)-shown))
+			break
+		}
+		description := match.Reference()
+		if match.Hash == "" {
+			description += fmt.Sprintf(", %.0f%% similar", 100*match.Score)
+		}
+		if !match.StoredAt.IsZero() {
+			description += ", archived " + match.StoredAt.UTC().Format(time.RFC3339)
+		}
+		described = append(described, description)
+	}
+	return strings.Join(described, "; ")
+}
+
+// DefaultContentHasher implements ContentHasher. Text is normalized with
+// NormalizeText, and author names with NormalizeName, before it is hashed, as
+// of NormalizationVersion.
 type DefaultContentHasher struct{}
 
 // GenerateHash creates a deterministic hash for duplicate detection
@@ -31,17 +93,27 @@ func (h *DefaultContentHasher) GenerateHash(event *nostr.Event) string {
 		return h.hashPaper(event)
 	}
 	
+	// A dataset is identified by its file, whatever its description says
+	if event.Kind == AcademicDataKind {
+		if hash := FileHash(event); hash != "" {
+			return hash
+		}
+	}
+	
 	// For other types, use content + key tags
 	hasher := sha256.New()
-	hasher.Write([]byte(event.Content))
+	hasher.Write([]byte(NormalizeText(event.Content)))
 	
 	// Add relevant tags to hash
 	for _, tag := range event.Tags {
 		if len(tag) >= 2 {
 			switch tag[0] {
-			case "title", "abstract", "author", "data-type", "description":
+			case "title", "abstract", "data-type", "description":
+				hasher.Write([]byte(tag[0]))
+				hasher.Write([]byte(NormalizeText(tag[1])))
+			case "author":
 				hasher.Write([]byte(tag[0]))
-				hasher.Write([]byte(tag[1]))
+				hasher.Write([]byte(NormalizeName(tag[1])))
 			}
 		}
 	}
@@ -62,11 +134,11 @@ func (h *DefaultContentHasher) hashPaper(event *nostr.Event) string {
 		}
 		switch tag[0] {
 		case "title":
-			title = strings.ToLower(strings.TrimSpace(tag[1]))
+			title = NormalizeText(tag[1])
 		case "abstract":
-			abstract = strings.ToLower(strings.TrimSpace(tag[1]))
+			abstract = NormalizeText(tag[1])
 		case "author":
-			authors = append(authors, strings.ToLower(strings.TrimSpace(tag[1])))
+			authors = append(authors, NormalizeName(tag[1]))
 		}
 	}
 	
@@ -79,8 +151,8 @@ func (h *DefaultContentHasher) hashPaper(event *nostr.Event) string {
 	}
 	
 	// Hash first 500 chars of abstract (normalized)
-	if len(abstract) > 500 {
-		abstract = abstract[:500]
+	if runes := []rune(abstract); len(runes) > 500 {
+		abstract = string(runes[:500])
 	}
 	hasher.Write([]byte(abstract))
 	
@@ -94,39 +166,54 @@ func PreventDuplicatePapers(ctx context.Context, event *nostr.Event, checker Dup
 		return nil
 	}
 	
-	isDuplicate, err := checker.IsDuplicate(ctx, event)
+	matches, err := checker.IsDuplicate(ctx, event)
 	if err != nil {
 		return fmt.Errorf("failed to check for duplicates: %w", err)
 	}
 	
-	if isDuplicate {
-		if event.Kind == AcademicPaperKind {
-			return fmt.Errorf("duplicate paper detected: a paper with the same title, authors, and abstract already exists in the archive")
-		}
-		return fmt.Errorf("duplicate research data detected: this dataset already exists in the archive")
+	// Name the originals so clients can link authors to the existing record
+	switch {
+	case len(matches) == 0:
+		return nil
+	case matches[0].Hash == "":
+		return policyErrorf(CodeDuplicate, "", "near-duplicate paper detected: the title and abstract match %s", describeMatches(matches))
+	case event.Kind == AcademicPaperKind:
+		return policyErrorf(CodeDuplicate, "", "duplicate paper detected: a paper with the same title, authors, and abstract already exists in the archive as %s", describeMatches(matches))
+	case matches[0].Hash == FileHash(event):
+		return policyErrorf(CodeDuplicate, "x", "duplicate research data detected: a dataset with the same file (sha256 %s) already exists in the archive as %s; publish new versions of it at that address", matches[0].Hash, describeMatches(matches))
+	default:
+		return policyErrorf(CodeDuplicate, "", "duplicate research data detected: this dataset already exists in the archive as %s", describeMatches(matches))
 	}
-	
-	return nil
 }
 
 // InMemoryDuplicateChecker is a simple in-memory implementation for testing
 type InMemoryDuplicateChecker struct {
 	hasher ContentHasher
-	hashes map[string]bool
+	// hashes maps a content hash to the events stored with it
+	hashes map[string][]DuplicateMatch
 }
 
 // NewInMemoryDuplicateChecker creates a new in-memory duplicate checker
 func NewInMemoryDuplicateChecker() *InMemoryDuplicateChecker {
 	return &InMemoryDuplicateChecker{
 		hasher: &DefaultContentHasher{},
-		hashes: make(map[string]bool),
+		hashes: make(map[string][]DuplicateMatch),
 	}
 }
 
-// IsDuplicate checks if content hash already exists
-func (c *InMemoryDuplicateChecker) IsDuplicate(ctx context.Context, event *nostr.Event) (bool, error) {
+// IsDuplicate returns the events stored with the same content hash at other
+// addresses, oldest first. Events without a d tag have no address and match
+// any stored hash.
+func (c *InMemoryDuplicateChecker) IsDuplicate(ctx context.Context, event *nostr.Event) ([]DuplicateMatch, error) {
 	hash := c.hasher.GenerateHash(event)
-	return c.hashes[hash], nil
+	address := EventAddress(event)
+	var matches []DuplicateMatch
+	for _, stored := range c.hashes[hash] {
+		if address == "" || stored.Address != address {
+			matches = append(matches, stored)
+		}
+	}
+	return matches, nil
 }
 
 // StoreHash stores a content hash
@@ -134,6 +221,12 @@ func (c *InMemoryDuplicateChecker) StoreHash(ctx context.Context, event *nostr.E
 	if hash == "" {
 		hash = c.hasher.GenerateHash(event)
 	}
-	c.hashes[hash] = true
+	c.hashes[hash] = append(c.hashes[hash], DuplicateMatch{
+		EventID:  event.ID,
+		Address:  EventAddress(event),
+		Hash:     hash,
+		Score:    1,
+		StoredAt: time.Now(),
+	})
 	return nil
 }
\ No newline at end of file
@@ -2,9 +2,12 @@ package policies
 
 import (
 	"context"
+	"errors"
+	"strings"
 	"testing"
 
 	"github.com/nbd-wtf/go-nostr"
+	"github.com/nbd-wtf/go-nostr/nip19"
 )
 
 func TestContentHasher(t *testing.T) {
@@ -140,11 +143,11 @@ func TestInMemoryDuplicateChecker(t *testing.T) {
 	}
 
 	// Test initial state - no duplicates
-	isDup, err := checker.IsDuplicate(ctx, event1)
+	matches, err := checker.IsDuplicate(ctx, event1)
 	if err != nil {
 		t.Fatalf("Unexpected error: %v", err)
 	}
-	if isDup {
+	if len(matches) > 0 {
 		t.Error("Expected no duplicate for first event")
 	}
 
@@ -154,20 +157,24 @@ func TestInMemoryDuplicateChecker(t *testing.T) {
 	}
 
 	// Check duplicate with same content
-	isDup, err = checker.IsDuplicate(ctx, event2)
+	matches, err = checker.IsDuplicate(ctx, event2)
 	if err != nil {
 		t.Fatalf("Unexpected error: %v", err)
 	}
-	if !isDup {
-		t.Error("Expected duplicate for event with same content")
+	if len(matches) != 1 {
+		t.Fatalf("Expected duplicate for event with same content, got %+v", matches)
+	}
+	hash := (&DefaultContentHasher{}).GenerateHash(event1)
+	if matches[0].EventID != "event1" || matches[0].Hash != hash || matches[0].StoredAt.IsZero() {
+		t.Errorf("Expected the match to name event1, its hash and when it was stored, got %+v", matches[0])
 	}
 
 	// Check non-duplicate
-	isDup, err = checker.IsDuplicate(ctx, event3)
+	matches, err = checker.IsDuplicate(ctx, event3)
 	if err != nil {
 		t.Fatalf("Unexpected error: %v", err)
 	}
-	if isDup {
+	if len(matches) > 0 {
 		t.Error("Expected no duplicate for different content")
 	}
 }
@@ -214,4 +221,110 @@ func TestPreventDuplicatePapers(t *testing.T) {
 	if err != nil {
 		t.Errorf("Non-paper event failed: %v", err)
 	}
-}
\ No newline at end of file
+}
+
+func TestRevisionsAreNotDuplicates(t *testing.T) {
+	ctx := context.Background()
+	checker := NewInMemoryDuplicateChecker()
+
+	paper := func(id, pubkey, d string) *nostr.Event {
+		return &nostr.Event{
+			ID:     id,
+			PubKey: pubkey,
+			Kind:   AcademicPaperKind,
+			Tags: nostr.Tags{
+				{"d", d},
+				{"title", "Test Paper"},
+				{"abstract", "A sufficiently long abstract for testing duplicate detection in academic papers"},
+				{"author", "Author Name"},
+			},
+		}
+	}
+
+	original := paper("v1", "author", "test-paper")
+	checker.StoreHash(ctx, original, "")
+
+	if err := PreventDuplicatePapers(ctx, paper("v2", "author", "test-paper"), checker); err != nil {
+		t.Errorf("A revision at the same address should not be a duplicate: %v", err)
+	}
+	if err := PreventDuplicatePapers(ctx, paper("copy", "author", "another-paper"), checker); err == nil {
+		t.Error("Expected the same content at another address to be a duplicate")
+	}
+	if err := PreventDuplicatePapers(ctx, paper("copy", "someone-else", "test-paper"), checker); err == nil {
+		t.Error("Expected the same content from another author to be a duplicate")
+	}
+}
+
+func TestDuplicateRejectionReferencesOriginal(t *testing.T) {
+	ctx := context.Background()
+	checker := NewInMemoryDuplicateChecker()
+	author := strings.Repeat("a", 64)
+
+	original := &nostr.Event{
+		ID:     strings.Repeat("1", 64),
+		PubKey: author,
+		Kind:   AcademicPaperKind,
+		Tags: nostr.Tags{
+			{"d", "test-paper"},
+			{"title", "Test Paper"},
+			{"abstract", "A sufficiently long abstract for testing duplicate detection in academic papers"},
+			{"author", "Author Name"},
+		},
+	}
+	checker.StoreHash(ctx, original, "")
+
+	nevent, _ := nip19.EncodeEvent(original.ID, nil, author)
+	naddr, _ := nip19.EncodeEntity(author, AcademicPaperKind, "test-paper", nil)
+
+	copied := *original
+	copied.ID = strings.Repeat("2", 64)
+	copied.Tags = append(nostr.Tags{{"d", "copied-paper"}}, original.Tags[1:]...)
+	err := PreventDuplicatePapers(ctx, &copied, checker)
+	if err == nil {
+		t.Fatal("Expected the copy to be rejected")
+	}
+	for _, want := range []string{original.ID, nevent, naddr, "archived "} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("Expected the rejection to include %q, got: %v", want, err)
+		}
+	}
+}
+
+func TestDatasetFileDeduplication(t *testing.T) {
+	ctx := context.Background()
+	checker := NewInMemoryDuplicateChecker()
+	fileHash := strings.Repeat("ab", 32)
+
+	dataset := func(id, d, description string) *nostr.Event {
+		return &nostr.Event{
+			ID:     id,
+			PubKey: strings.Repeat("a", 64),
+			Kind:   AcademicDataKind,
+			Tags: nostr.Tags{
+				{"d", d},
+				{"data-type", "dataset"},
+				{"description", description},
+				{"url", "https://example.com/data/latency.csv"},
+				{"x", fileHash},
+				{"m", "text/csv"},
+				{"size", "48213"},
+			},
+		}
+	}
+
+	original := dataset("original", "latency-data", "Latency measurements from the consensus experiments")
+	checker.StoreHash(ctx, original, "")
+
+	err := PreventDuplicatePapers(ctx, dataset("copy", "other-data", "A differently worded description of the same file"), checker)
+	var policyErr *PolicyError
+	if !errors.As(err, &policyErr) || policyErr.Code != CodeDuplicate || policyErr.Field != "x" {
+		t.Fatalf("Expected a dataset with the same file to be rejected, got: %v", err)
+	}
+	if !strings.Contains(err.Error(), fileHash) || !strings.Contains(err.Error(), "original") {
+		t.Errorf("Expected the rejection to name the file hash and the original, got: %v", err)
+	}
+
+	if err := PreventDuplicatePapers(ctx, dataset("v2", "latency-data", "Latency measurements, with corrected units"), checker); err != nil {
+		t.Errorf("A new version at the same address should be accepted: %v", err)
+	}
+}
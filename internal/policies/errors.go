@@ -0,0 +1,231 @@
+package policies
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrorCode is a stable, machine-readable reason for rejecting an event
+type ErrorCode string
+
+const (
+	// Event structure
+	CodeInvalidSignature ErrorCode = "invalid_signature"
+
+	// Authentication
+	CodeAuthRequired ErrorCode = "auth_required"
+	CodeNotAuthor    ErrorCode = "not_author"
+
+	// Access lists
+	CodeBlockedPubkey  ErrorCode = "blocked_pubkey"
+	CodeNotAllowlisted ErrorCode = "not_allowlisted"
+
+	// Metadata violations
+	CodeInvalidKind       ErrorCode = "invalid_kind"
+	CodeMissingTag        ErrorCode = "missing_tag"
+	CodeTooShort          ErrorCode = "too_short"
+	CodeMissingTimestamp  ErrorCode = "missing_timestamp"
+	CodeInvalidIdentifier ErrorCode = "invalid_identifier"
+	CodeInvalidFile       ErrorCode = "invalid_file"
+
+	// Duplicate prevention
+	CodeDuplicate ErrorCode = "duplicate"
+
+	// Review integrity
+	CodeMissingReference     ErrorCode = "missing_reference"
+	CodeUnknownReference     ErrorCode = "unknown_reference"
+	CodeConflictOfInterest   ErrorCode = "conflict_of_interest"
+	CodeInsufficientFeedback ErrorCode = "insufficient_feedback"
+
+	// Rate limiting
+	CodeRateLimited ErrorCode = "rate_limited"
+
+	// Failures unrelated to the event itself, such as a database outage
+	CodeInternal ErrorCode = "internal_error"
+)
+
+// errorCodes lists every code in the order they are documented
+var errorCodes = []ErrorCode{
+	CodeInvalidSignature,
+	CodeAuthRequired,
+	CodeNotAuthor,
+	CodeBlockedPubkey,
+	CodeNotAllowlisted,
+	CodeInvalidKind,
+	CodeMissingTag,
+	CodeTooShort,
+	CodeMissingTimestamp,
+	CodeInvalidIdentifier,
+	CodeInvalidFile,
+	CodeDuplicate,
+	CodeMissingReference,
+	CodeUnknownReference,
+	CodeConflictOfInterest,
+	CodeInsufficientFeedback,
+	CodeRateLimited,
+	CodeInternal,
+}
+
+// Prefix returns the NIP-01 OK message prefix for the code
+func (c ErrorCode) Prefix() string {
+	switch c {
+	case CodeAuthRequired:
+		return "auth-required"
+	case CodeNotAuthor, CodeNotAllowlisted:
+		return "restricted"
+	case CodeBlockedPubkey:
+		return "blocked"
+	case CodeDuplicate:
+		return "duplicate"
+	case CodeRateLimited:
+		return "rate-limited"
+	case CodeConflictOfInterest:
+		return "blocked"
+	case CodeInternal:
+		return "error"
+	default:
+		return "invalid"
+	}
+}
+
+// PolicyError describes a single policy violation in machine-readable form
+type PolicyError struct {
+	// Code identifies the kind of violation
+	Code ErrorCode `json:"code"`
+	// Field is the offending tag or event field, if any
+	Field string `json:"field,omitempty"`
+	// Limit is the configured threshold that was not met
+	Limit interface{} `json:"limit,omitempty"`
+	// Actual is the value found on the event
+	Actual interface{} `json:"actual,omitempty"`
+	// Message is the human-readable explanation
+	Message string `json:"message"`
+}
+
+// NewPolicyError creates a PolicyError for violations detected outside this package
+func NewPolicyError(code ErrorCode, field, message string) *PolicyError {
+	return &PolicyError{Code: code, Field: field, Message: message}
+}
+
+// policyErrorf creates a PolicyError with a formatted message
+func policyErrorf(code ErrorCode, field string, format string, args ...interface{}) *PolicyError {
+	return &PolicyError{
+		Code:    code,
+		Field:   field,
+		Message: fmt.Sprintf(format, args...),
+	}
+}
+
+// withLimit records the threshold and the value that violated it
+func (e *PolicyError) withLimit(limit, actual interface{}) *PolicyError {
+	e.Limit = limit
+	e.Actual = actual
+	return e
+}
+
+// Error returns the human-readable message
+func (e *PolicyError) Error() string {
+	return e.Message
+}
+
+// MultiError holds every violation found for a single event
+type MultiError struct {
+	Errors []error
+}
+
+// Error joins the individual messages
+func (m *MultiError) Error() string {
+	messages := make([]string, len(m.Errors))
+	for i, err := range m.Errors {
+		messages[i] = err.Error()
+	}
+	return strings.Join(messages, "; ")
+}
+
+// Unwrap exposes the individual violations to errors.Is and errors.As
+func (m *MultiError) Unwrap() []error {
+	return m.Errors
+}
+
+// joinViolations returns nil, the single violation, or a *MultiError
+func joinViolations(errs []error) error {
+	var violations []error
+	for _, err := range errs {
+		violations = append(violations, Violations(err)...)
+	}
+
+	switch len(violations) {
+	case 0:
+		return nil
+	case 1:
+		return violations[0]
+	default:
+		return &MultiError{Errors: violations}
+	}
+}
+
+// Violations expands an error into the individual violations it contains
+func Violations(err error) []error {
+	if err == nil {
+		return nil
+	}
+	if joined, ok := err.(interface{ Unwrap() []error }); ok {
+		var errs []error
+		for _, e := range joined.Unwrap() {
+			errs = append(errs, Violations(e)...)
+		}
+		return errs
+	}
+	return []error{err}
+}
+
+// prefixViolations prefixes each violation in err with the stage description
+func prefixViolations(prefix string, err error) error {
+	violations := Violations(err)
+	for i, violation := range violations {
+		violations[i] = fmt.Errorf("%s: %w", prefix, violation)
+	}
+	return joinViolations(violations)
+}
+
+// OKMessage formats an error for the reason field of a NIP-01 OK message.
+// Policy violations become "<prefix>: [<code>:<field>] <message>" so
+// clients can match on the code; other errors are reported as "error: ...".
+// When several violations are present their codes are comma-separated and
+// their messages joined with "; ", using the prefix of the first violation.
+func OKMessage(err error) string {
+	violations := Violations(err)
+	if len(violations) == 0 {
+		return ""
+	}
+
+	prefix := ""
+	tags := make([]string, 0, len(violations))
+	messages := make([]string, 0, len(violations))
+	for _, violation := range violations {
+		messages = append(messages, violation.Error())
+
+		var policyErr *PolicyError
+		if !errors.As(violation, &policyErr) {
+			if prefix == "" {
+				prefix = "error"
+			}
+			continue
+		}
+
+		if prefix == "" {
+			prefix = policyErr.Code.Prefix()
+		}
+		tag := string(policyErr.Code)
+		if policyErr.Field != "" {
+			tag += ":" + policyErr.Field
+		}
+		tags = append(tags, tag)
+	}
+
+	if len(tags) == 0 {
+		return fmt.Sprintf("%s: %s", prefix, strings.Join(messages, "; "))
+	}
+	return fmt.Sprintf("%s: [%s] %s", prefix, strings.Join(tags, ","), strings.Join(messages, "; "))
+}
@@ -0,0 +1,145 @@
+package policies
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/nbd-wtf/go-nostr"
+)
+
+func TestOKMessage(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		expected string
+	}{
+		{
+			name:     "policy error with field",
+			err:      fmt.Errorf("metadata policy: %w", policyErrorf(CodeTooShort, "title", "paper title too short: must be at least %d characters", 10)),
+			expected: "invalid: [too_short:title] metadata policy: paper title too short: must be at least 10 characters",
+		},
+		{
+			name:     "rate limit error",
+			err:      policyErrorf(CodeRateLimited, "", "rate limit exceeded"),
+			expected: "rate-limited: [rate_limited] rate limit exceeded",
+		},
+		{
+			name:     "duplicate error",
+			err:      policyErrorf(CodeDuplicate, "", "duplicate paper detected"),
+			expected: "duplicate: [duplicate] duplicate paper detected",
+		},
+		{
+			name:     "conflict of interest",
+			err:      policyErrorf(CodeConflictOfInterest, "pubkey", "authors cannot review their own papers"),
+			expected: "blocked: [conflict_of_interest:pubkey] authors cannot review their own papers",
+		},
+		{
+			name:     "internal error",
+			err:      errors.New("failed to check for duplicates: connection refused"),
+			expected: "error: failed to check for duplicates: connection refused",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := OKMessage(tt.err); got != tt.expected {
+				t.Errorf("OKMessage() = %q, want %q", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestPolicyErrorsFromValidators(t *testing.T) {
+	ctx := context.Background()
+	engine := NewPolicyEngine(nil, nil, nil)
+
+	t.Run("too short title reports limit and actual length", func(t *testing.T) {
+		paper := &nostr.Event{
+			PubKey:    "typed_errors_author",
+			Kind:      AcademicPaperKind,
+			CreatedAt: nostr.Timestamp(time.Now().Unix()),
+			Tags: nostr.Tags{
+				{"d", "errors-1"},
+				{"title", "Short"},
+				{"abstract", "This abstract is long enough to satisfy the minimum length requirement for papers."},
+				{"subject", "Testing"},
+				{"author", "Test Author"},
+			},
+		}
+
+		var policyErr *PolicyError
+		if err := engine.ValidateEvent(ctx, paper); !errors.As(err, &policyErr) {
+			t.Fatalf("Expected PolicyError, got: %v", err)
+		}
+		if policyErr.Code != CodeTooShort || policyErr.Field != "title" {
+			t.Errorf("Expected too_short on title, got %s on %s", policyErr.Code, policyErr.Field)
+		}
+		if policyErr.Limit != 10 || policyErr.Actual != 5 {
+			t.Errorf("Expected limit 10 and actual 5, got %v and %v", policyErr.Limit, policyErr.Actual)
+		}
+	})
+
+	t.Run("missing tag names the tag", func(t *testing.T) {
+		citation := &nostr.Event{
+			Kind:      AcademicCitationKind,
+			CreatedAt: nostr.Timestamp(time.Now().Unix()),
+			Tags: nostr.Tags{
+				{"d", "errors-2"},
+				{"e", "cited_paper"},
+			},
+		}
+
+		var policyErr *PolicyError
+		if err := ValidateAcademicEvent(citation); !errors.As(err, &policyErr) {
+			t.Fatalf("Expected PolicyError, got: %v", err)
+		}
+		if policyErr.Code != CodeMissingTag || policyErr.Field != "context" {
+			t.Errorf("Expected missing_tag on context, got %s on %s", policyErr.Code, policyErr.Field)
+		}
+	})
+
+	t.Run("rate limit reports the limit", func(t *testing.T) {
+		limiter := NewMemoryRateLimiter(&RateLimitConfig{
+			EventsPerWindow: 1,
+			WindowDuration:  time.Minute,
+		})
+		event := &nostr.Event{PubKey: "typed_rate_limit", Kind: AcademicDiscussionKind}
+
+		CheckRateLimit(ctx, event, limiter)
+		err := CheckRateLimit(ctx, event, limiter)
+
+		var policyErr *PolicyError
+		if !errors.As(err, &policyErr) || policyErr.Code != CodeRateLimited {
+			t.Fatalf("Expected rate_limited PolicyError, got: %v", err)
+		}
+		if policyErr.Limit != 1 {
+			t.Errorf("Expected limit 1, got %v", policyErr.Limit)
+		}
+	})
+}
+
+func TestOKMessageMultipleViolations(t *testing.T) {
+	err := joinViolations([]error{
+		prefixViolations("rate limit policy", policyErrorf(CodeRateLimited, "kind", "rate limit exceeded")),
+		prefixViolations("metadata policy", &MultiError{Errors: []error{
+			policyErrorf(CodeMissingTag, "subject", "missing subject"),
+			policyErrorf(CodeTooShort, "title", "title too short"),
+		}}),
+	})
+
+	expected := "rate-limited: [rate_limited:kind,missing_tag:subject,too_short:title] " +
+		"rate limit policy: rate limit exceeded; metadata policy: missing subject; metadata policy: title too short"
+	if got := OKMessage(err); got != expected {
+		t.Errorf("OKMessage() = %q, want %q", got, expected)
+	}
+
+	if n := len(Violations(err)); n != 3 {
+		t.Errorf("Expected 3 violations, got %d", n)
+	}
+	if joinViolations(nil) != nil {
+		t.Error("Expected nil for no violations")
+	}
+}
@@ -0,0 +1,371 @@
+package policies
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/nbd-wtf/go-nostr"
+)
+
+// Standard identifier schemes, each read from the tag of the same name
+const (
+	IdentifierDOI    = "doi"
+	IdentifierArXiv  = "arxiv"
+	IdentifierISBN   = "isbn"
+	IdentifierHandle = "handle"
+	IdentifierPMID   = "pmid"
+)
+
+// IdentifierSchemes lists the identifier tags papers, datasets and citations
+// may carry
+var IdentifierSchemes = []string{IdentifierDOI, IdentifierArXiv, IdentifierISBN, IdentifierHandle, IdentifierPMID}
+
+var (
+	doiPattern    = regexp.MustCompile(`^10\.\d{4,9}/\S+$`)
+	arxivPattern  = regexp.MustCompile(`^(\d{4}\.\d{4,5}|[a-z-]+(\.[A-Z]{2})?/\d{7})$`)
+	arxivVersion  = regexp.MustCompile(`v\d+$`)
+	handlePattern = regexp.MustCompile(`^\d+(\.\d+)*/\S+$`)
+	pmidPattern   = regexp.MustCompile(`^\d{1,8}$`)
+)
+
+// identifierFormats describes each scheme's accepted form for error messages
+var identifierFormats = map[string]string{
+	IdentifierDOI:    "a DOI of the form 10.<registrant>/<suffix>",
+	IdentifierArXiv:  "an arXiv identifier such as 2401.01234 or hep-th/9901001",
+	IdentifierISBN:   "an ISBN-10 or ISBN-13 with a valid check digit",
+	IdentifierHandle: "a handle of the form <prefix>/<suffix>",
+	IdentifierPMID:   "a PubMed ID of up to 8 digits",
+}
+
+// identifierPrefixes are stripped, ignoring case, before an identifier is
+// validated, so resolver URLs and scheme prefixes name the same work as the
+// bare identifier
+var identifierPrefixes = map[string][]string{
+	IdentifierDOI:    {"https://doi.org/", "http://doi.org/", "https://dx.doi.org/", "http://dx.doi.org/", "doi.org/", "doi:"},
+	IdentifierArXiv:  {"https://arxiv.org/abs/", "http://arxiv.org/abs/", "https://arxiv.org/pdf/", "http://arxiv.org/pdf/", "arxiv.org/abs/", "arxiv:"},
+	IdentifierISBN:   {"isbn-13:", "isbn-10:", "isbn:", "isbn"},
+	IdentifierHandle: {"https://hdl.handle.net/", "http://hdl.handle.net/", "hdl.handle.net/", "hdl:"},
+	IdentifierPMID:   {"https://pubmed.ncbi.nlm.nih.gov/", "http://pubmed.ncbi.nlm.nih.gov/", "pubmed.ncbi.nlm.nih.gov/", "pmid:"},
+}
+
+// Identifier is a normalized standard identifier of a published work
+type Identifier struct {
+	Scheme string `json:"scheme" db:"scheme"`
+	Value  string `json:"value" db:"value"`
+}
+
+// String returns the identifier as <scheme>:<value>
+func (i Identifier) String() string {
+	return i.Scheme + ":" + i.Value
+}
+
+// NormalizeIdentifier returns the canonical form of an identifier, or false
+// when it is not valid for its scheme. URL and scheme prefixes are removed;
+// DOIs and handles are lowercased, as both are case-insensitive; arXiv
+// version suffixes are dropped, so every version of a preprint has the same
+// identifier; ISBNs lose their hyphens and ISBN-10s become ISBN-13s; and
+// PubMed IDs lose leading zeros.
+func NormalizeIdentifier(scheme, value string) (string, bool) {
+	value = strings.TrimSpace(value)
+	for _, prefix := range identifierPrefixes[scheme] {
+		if len(value) >= len(prefix) && strings.EqualFold(value[:len(prefix)], prefix) {
+			value = strings.TrimSpace(value[len(prefix):])
+			break
+		}
+	}
+
+	switch scheme {
+	case IdentifierDOI:
+		value = strings.ToLower(value)
+		return value, doiPattern.MatchString(value)
+	case IdentifierArXiv:
+		value = arxivVersion.ReplaceAllString(strings.TrimSuffix(value, ".pdf"), "")
+		return value, arxivPattern.MatchString(value)
+	case IdentifierISBN:
+		return normalizeISBN(value)
+	case IdentifierHandle:
+		value = strings.ToLower(value)
+		return value, handlePattern.MatchString(value)
+	case IdentifierPMID:
+		value = strings.TrimSuffix(value, "/")
+		if !pmidPattern.MatchString(value) {
+			return value, false
+		}
+		if trimmed := strings.TrimLeft(value, "0"); trimmed != "" {
+			return trimmed, true
+		}
+		return value, false
+	}
+	return value, false
+}
+
+// normalizeISBN validates an ISBN's check digit and returns it as an ISBN-13
+func normalizeISBN(value string) (string, bool) {
+	isbn := strings.ToUpper(strings.NewReplacer("-", "", " ", "").Replace(value))
+
+	switch len(isbn) {
+	case 10:
+		sum := 0
+		for i, r := range isbn {
+			digit := int(r - '0')
+			switch {
+			case r == 'X' && i == 9:
+				digit = 10
+			case r < '0' || r > '9':
+				return isbn, false
+			}
+			sum += (10 - i) * digit
+		}
+		if sum%11 != 0 {
+			return isbn, false
+		}
+		isbn = "978" + isbn[:9]
+		return isbn + isbn13CheckDigit(isbn), true
+	case 13:
+		for _, r := range isbn {
+			if r < '0' || r > '9' {
+				return isbn, false
+			}
+		}
+		return isbn, isbn13CheckDigit(isbn[:12]) == isbn[12:]
+	}
+	return isbn, false
+}
+
+// isbn13CheckDigit computes the check digit for the first 12 digits of an ISBN-13
+func isbn13CheckDigit(digits string) string {
+	sum := 0
+	for i, r := range digits {
+		weight := 1
+		if i%2 == 1 {
+			weight = 3
+		}
+		sum += weight * int(r-'0')
+	}
+	return fmt.Sprint((10 - sum%10) % 10)
+}
+
+// ValidDOI checks that a DOI has the 10.<registrant>/<suffix> form, optionally
+// as a doi.org URL or with a doi: prefix
+func ValidDOI(doi string) bool {
+	_, ok := NormalizeIdentifier(IdentifierDOI, doi)
+	return ok
+}
+
+// ValidArXivID checks for a current (2401.01234) or legacy (hep-th/9901001)
+// arXiv identifier, with an optional version suffix, arxiv: prefix or
+// arxiv.org URL
+func ValidArXivID(id string) bool {
+	_, ok := NormalizeIdentifier(IdentifierArXiv, id)
+	return ok
+}
+
+// ExternalIdentifiers returns the values of a citation's doi and arxiv tags,
+// normalized when they are valid
+func ExternalIdentifiers(event *nostr.Event) (doi, arxiv string) {
+	if tag := event.Tags.GetFirst([]string{IdentifierDOI, ""}); tag != nil {
+		doi, _ = NormalizeIdentifier(IdentifierDOI, (*tag)[1])
+	}
+	if tag := event.Tags.GetFirst([]string{IdentifierArXiv, ""}); tag != nil {
+		arxiv, _ = NormalizeIdentifier(IdentifierArXiv, (*tag)[1])
+	}
+	return doi, arxiv
+}
+
+// EventIdentifiers returns the valid identifiers an event carries, normalized
+// and without repeats
+func EventIdentifiers(event *nostr.Event) []Identifier {
+	var identifiers []Identifier
+	seen := make(map[Identifier]bool)
+	for _, tag := range event.Tags {
+		if len(tag) < 2 || identifierFormats[tag[0]] == "" {
+			continue
+		}
+		value, ok := NormalizeIdentifier(tag[0], tag[1])
+		identifier := Identifier{Scheme: tag[0], Value: value}
+		if ok && !seen[identifier] {
+			seen[identifier] = true
+			identifiers = append(identifiers, identifier)
+		}
+	}
+	return identifiers
+}
+
+// identifierViolations reports identifier tags that are not valid for their scheme
+func identifierViolations(event *nostr.Event) []error {
+	var errs []error
+	for _, tag := range event.Tags {
+		if len(tag) < 2 || identifierFormats[tag[0]] == "" {
+			continue
+		}
+		if _, ok := NormalizeIdentifier(tag[0], tag[1]); !ok {
+			errs = append(errs, policyErrorf(CodeInvalidIdentifier, tag[0], "%s '%s' tag must be %s, got %q",
+				getEventTypeName(event.Kind), tag[0], identifierFormats[tag[0]], tag[1]))
+		}
+	}
+	return errs
+}
+
+// IdentifierClaim records which archived event first claimed an identifier.
+// Each identifier belongs to one NIP-33 address; later versions at that
+// address share the claim.
+type IdentifierClaim struct {
+	Identifier
+	EventID  string    `json:"event_id" db:"event_id"`
+	Address  string    `json:"address" db:"address"`
+	StoredAt time.Time `json:"stored_at" db:"stored_at"`
+}
+
+// IdentifierStore keeps the identifiers claimed by archived papers and datasets
+type IdentifierStore interface {
+	// IdentifierClaims returns the claims on any of the identifiers
+	IdentifierClaims(ctx context.Context, identifiers []Identifier) ([]IdentifierClaim, error)
+	// ClaimIdentifiers claims an event's identifiers for its address in one
+	// step, so two events cannot both claim an identifier. When any of them
+	// belongs to another address nothing is claimed and those claims are
+	// returned; otherwise it returns the identifiers that were unclaimed.
+	ClaimIdentifiers(ctx context.Context, event *nostr.Event, identifiers []Identifier) (claimed []Identifier, conflicts []IdentifierClaim, err error)
+	// ReleaseIdentifiers removes the event's claims on the identifiers, for
+	// an event that was claimed for but not stored
+	ReleaseIdentifiers(ctx context.Context, event *nostr.Event, identifiers []Identifier) error
+}
+
+// PreventDuplicateIdentifiers rejects papers and datasets claiming an
+// identifier that already belongs to an archived event at another address.
+// A new version of the same work must be published at the original's address.
+// It only checks the claims; ClaimEventIdentifiers also makes them.
+func PreventDuplicateIdentifiers(ctx context.Context, event *nostr.Event, store IdentifierStore) error {
+	if event.Kind != AcademicPaperKind && event.Kind != AcademicDataKind {
+		return nil
+	}
+	identifiers := EventIdentifiers(event)
+	if len(identifiers) == 0 {
+		return nil
+	}
+
+	claims, err := store.IdentifierClaims(ctx, identifiers)
+	if err != nil {
+		return fmt.Errorf("failed to check identifiers: %w", err)
+	}
+	return claimViolations(conflictingClaims(event, claims))
+}
+
+// ClaimEventIdentifiers claims a paper's or dataset's identifiers for its
+// address, and rejects it like PreventDuplicateIdentifiers when any of them
+// belongs to another address. It returns the identifiers it claimed, to be
+// released if the event is not stored.
+func ClaimEventIdentifiers(ctx context.Context, event *nostr.Event, store IdentifierStore) ([]Identifier, error) {
+	if event.Kind != AcademicPaperKind && event.Kind != AcademicDataKind {
+		return nil, nil
+	}
+	identifiers := EventIdentifiers(event)
+	if len(identifiers) == 0 {
+		return nil, nil
+	}
+
+	claimed, conflicts, err := store.ClaimIdentifiers(ctx, event, identifiers)
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim identifiers: %w", err)
+	}
+	return claimed, claimViolations(conflicts)
+}
+
+// conflictingClaims returns the claims held by another address than the
+// event's. Events without an address share no claims.
+func conflictingClaims(event *nostr.Event, claims []IdentifierClaim) []IdentifierClaim {
+	address := EventAddress(event)
+	var conflicts []IdentifierClaim
+	for _, claim := range claims {
+		if claim.EventID == event.ID || (address != "" && claim.Address == address) {
+			continue
+		}
+		conflicts = append(conflicts, claim)
+	}
+	return conflicts
+}
+
+// claimViolations reports each identifier claimed by another address
+func claimViolations(conflicts []IdentifierClaim) error {
+	var errs []error
+	for _, claim := range conflicts {
+		original := DuplicateMatch{EventID: claim.EventID, Address: claim.Address, StoredAt: claim.StoredAt}
+		errs = append(errs, policyErrorf(CodeDuplicate, claim.Scheme,
+			"%s %s is already claimed by %s, archived %s; publish new versions of it at that address",
+			claim.Scheme, claim.Value, original.Reference(), claim.StoredAt.UTC().Format(time.RFC3339)))
+	}
+	return joinViolations(errs)
+}
+
+// InMemoryIdentifierStore keeps identifier claims in memory
+type InMemoryIdentifierStore struct {
+	mu     sync.RWMutex
+	claims map[Identifier]IdentifierClaim
+}
+
+// NewInMemoryIdentifierStore creates an empty identifier store
+func NewInMemoryIdentifierStore() *InMemoryIdentifierStore {
+	return &InMemoryIdentifierStore{claims: make(map[Identifier]IdentifierClaim)}
+}
+
+// IdentifierClaims returns the claims on any of the identifiers
+func (s *InMemoryIdentifierStore) IdentifierClaims(ctx context.Context, identifiers []Identifier) ([]IdentifierClaim, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var claims []IdentifierClaim
+	for _, identifier := range identifiers {
+		if claim, ok := s.claims[identifier]; ok {
+			claims = append(claims, claim)
+		}
+	}
+	return claims, nil
+}
+
+// ClaimIdentifiers claims an event's unclaimed identifiers unless one of
+// them belongs to another address
+func (s *InMemoryIdentifierStore) ClaimIdentifiers(ctx context.Context, event *nostr.Event, identifiers []Identifier) ([]Identifier, []IdentifierClaim, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var claims []IdentifierClaim
+	for _, identifier := range identifiers {
+		if claim, ok := s.claims[identifier]; ok {
+			claims = append(claims, claim)
+		}
+	}
+	if conflicts := conflictingClaims(event, claims); len(conflicts) > 0 {
+		return nil, conflicts, nil
+	}
+
+	var claimed []Identifier
+	for _, identifier := range identifiers {
+		if _, ok := s.claims[identifier]; ok {
+			continue
+		}
+		s.claims[identifier] = IdentifierClaim{
+			Identifier: identifier,
+			EventID:    event.ID,
+			Address:    EventAddress(event),
+			StoredAt:   time.Now(),
+		}
+		claimed = append(claimed, identifier)
+	}
+	return claimed, nil, nil
+}
+
+// ReleaseIdentifiers removes the event's claims on the identifiers
+func (s *InMemoryIdentifierStore) ReleaseIdentifiers(ctx context.Context, event *nostr.Event, identifiers []Identifier) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, identifier := range identifiers {
+		if s.claims[identifier].EventID == event.ID {
+			delete(s.claims, identifier)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,190 @@
+package policies
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/nbd-wtf/go-nostr"
+)
+
+func TestNormalizeIdentifier(t *testing.T) {
+	tests := []struct {
+		scheme string
+		value  string
+		want   string
+		valid  bool
+	}{
+		{IdentifierDOI, "10.1145/3132747.3132757", "10.1145/3132747.3132757", true},
+		{IdentifierDOI, "https://doi.org/10.1000/ABC.def", "10.1000/abc.def", true},
+		{IdentifierDOI, "doi:10.1000/182", "10.1000/182", true},
+		{IdentifierDOI, "1145/3132747", "", false},
+		{IdentifierDOI, "https://example.org/paper", "", false},
+		{IdentifierArXiv, "2401.01234v2", "2401.01234", true},
+		{IdentifierArXiv, "arXiv:2401.01234", "2401.01234", true},
+		{IdentifierArXiv, "https://arxiv.org/pdf/2401.01234v3.pdf", "2401.01234", true},
+		{IdentifierArXiv, "math.GT/0309136", "math.GT/0309136", true},
+		{IdentifierArXiv, "hep-th/99", "", false},
+		{IdentifierISBN, "978-0-306-40615-7", "9780306406157", true},
+		{IdentifierISBN, "ISBN 0-306-40615-2", "9780306406157", true},
+		{IdentifierISBN, "978-0-306-40615-8", "", false},
+		{IdentifierHandle, "https://hdl.handle.net/2027/MDP.39015", "2027/mdp.39015", true},
+		{IdentifierHandle, "not a handle", "", false},
+		{IdentifierPMID, "https://pubmed.ncbi.nlm.nih.gov/31452104/", "31452104", true},
+		{IdentifierPMID, "PMID:00123", "123", true},
+		{IdentifierPMID, "PMC1234", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.scheme+" "+tt.value, func(t *testing.T) {
+			got, ok := NormalizeIdentifier(tt.scheme, tt.value)
+			if ok != tt.valid {
+				t.Fatalf("Expected valid=%v, got %v (%q)", tt.valid, ok, got)
+			}
+			if ok && got != tt.want {
+				t.Errorf("Expected %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestPreventDuplicateIdentifiers(t *testing.T) {
+	ctx := context.Background()
+	store := NewInMemoryIdentifierStore()
+
+	paper := func(id, d, doi string) *nostr.Event {
+		return &nostr.Event{
+			ID:     id,
+			PubKey: strings.Repeat("a", 64),
+			Kind:   AcademicPaperKind,
+			Tags:   nostr.Tags{{"d", d}, {"doi", doi}, {"isbn", "978-0-306-40615-7"}},
+		}
+	}
+
+	original := paper("original", "consensus", "10.1000/ABC")
+	if err := PreventDuplicateIdentifiers(ctx, original, store); err != nil {
+		t.Fatalf("First claim should be accepted: %v", err)
+	}
+	if _, err := ClaimEventIdentifiers(ctx, original, store); err != nil {
+		t.Fatalf("First claim should be made: %v", err)
+	}
+
+	err := PreventDuplicateIdentifiers(ctx, paper("copy", "consensus-copy", "https://doi.org/10.1000/abc"), store)
+	violations := Violations(err)
+	if len(violations) != 2 {
+		t.Fatalf("Expected the DOI and ISBN to be reported as claimed, got: %v", err)
+	}
+	var policyErr *PolicyError
+	if !errors.As(violations[0], &policyErr) || policyErr.Code != CodeDuplicate || policyErr.Field != IdentifierDOI {
+		t.Errorf("Expected a duplicate doi violation, got: %v", violations[0])
+	}
+	if !strings.Contains(err.Error(), "original") {
+		t.Errorf("Expected the rejection to name the original, got: %v", err)
+	}
+
+	if err := PreventDuplicateIdentifiers(ctx, paper("v2", "consensus", "10.1000/abc"), store); err != nil {
+		t.Errorf("A new version at the same address should keep the identifier: %v", err)
+	}
+}
+
+func TestClaimEventIdentifiers(t *testing.T) {
+	ctx := context.Background()
+	store := NewInMemoryIdentifierStore()
+
+	paper := func(id, d string, tags ...nostr.Tag) *nostr.Event {
+		return &nostr.Event{
+			ID:     id,
+			PubKey: strings.Repeat("a", 64),
+			Kind:   AcademicPaperKind,
+			Tags:   append(nostr.Tags{{"d", d}}, tags...),
+		}
+	}
+	doi := nostr.Tag{"doi", "10.1000/abc"}
+	arxiv := nostr.Tag{"arxiv", "2401.01234"}
+
+	// Of events claiming the same identifier at once, only one gets it
+	var wg sync.WaitGroup
+	var accepted atomic.Int32
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			event := paper(fmt.Sprintf("racer-%d", i), fmt.Sprintf("racer-%d", i), doi)
+			if _, err := ClaimEventIdentifiers(ctx, event, store); err == nil {
+				accepted.Add(1)
+			}
+		}(i)
+	}
+	wg.Wait()
+	if accepted.Load() != 1 {
+		t.Fatalf("Expected exactly one event to claim the DOI, got %d", accepted.Load())
+	}
+
+	// A rejected event claims none of its identifiers
+	claimed, err := ClaimEventIdentifiers(ctx, paper("late", "late", arxiv, doi), store)
+	if err == nil || len(claimed) != 0 {
+		t.Fatalf("Expected the claimed DOI to be rejected, got %v and %v", claimed, err)
+	}
+	claims, _ := store.IdentifierClaims(ctx, EventIdentifiers(paper("", "", arxiv)))
+	if len(claims) != 0 {
+		t.Errorf("Expected the arXiv ID of a rejected event to stay unclaimed, got %v", claims)
+	}
+
+	// Released claims can be made again
+	first := paper("first", "first", arxiv)
+	claimed, err = ClaimEventIdentifiers(ctx, first, store)
+	if err != nil || len(claimed) != 1 {
+		t.Fatalf("Expected the arXiv ID to be claimed, got %v and %v", claimed, err)
+	}
+	if err := store.ReleaseIdentifiers(ctx, first, claimed); err != nil {
+		t.Fatalf("Unexpected release error: %v", err)
+	}
+	if _, err := ClaimEventIdentifiers(ctx, paper("second", "second", arxiv), store); err != nil {
+		t.Errorf("Expected a released identifier to be claimable, got: %v", err)
+	}
+
+	// Releasing an event that claimed nothing keeps the claims of others
+	if err := store.ReleaseIdentifiers(ctx, first, claimed); err != nil {
+		t.Fatalf("Unexpected release error: %v", err)
+	}
+	if err := PreventDuplicateIdentifiers(ctx, paper("third", "third", arxiv), store); err == nil {
+		t.Error("Expected the second event to keep its claim")
+	}
+}
+
+func TestDuplicatePolicyReleasesRejectedClaims(t *testing.T) {
+	ctx := context.Background()
+	store := NewInMemoryIdentifierStore()
+	policy := NewDuplicatePolicy(NewInMemoryDuplicateChecker(), store)
+
+	paper := &nostr.Event{
+		ID:     "unstored",
+		PubKey: strings.Repeat("a", 64),
+		Kind:   AcademicPaperKind,
+		Tags:   nostr.Tags{{"d", "unstored"}, {"title", "Unstored"}, {"doi", "10.1000/abc"}},
+	}
+	if err := policy.Validate(WithDryRun(ctx), paper); err != nil {
+		t.Fatalf("Unexpected dry run error: %v", err)
+	}
+	if claims, _ := store.IdentifierClaims(ctx, EventIdentifiers(paper)); len(claims) != 0 {
+		t.Fatalf("Expected a dry run to claim nothing, got %v", claims)
+	}
+
+	if err := policy.Validate(ctx, paper); err != nil {
+		t.Fatalf("Unexpected validation error: %v", err)
+	}
+	if err := policy.Rollback(ctx, paper); err != nil {
+		t.Fatalf("Unexpected rollback error: %v", err)
+	}
+
+	other := *paper
+	other.ID = "other"
+	other.Tags = nostr.Tags{{"d", "other"}, {"title", "Other"}, {"doi", "10.1000/abc"}}
+	if err := policy.Validate(ctx, &other); err != nil {
+		t.Errorf("Expected the DOI of an event that was not stored to be released, got: %v", err)
+	}
+}
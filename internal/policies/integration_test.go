@@ -28,6 +28,7 @@ func TestPolicyIntegration(t *testing.T) {
 		Kind:      AcademicPaperKind,
 		CreatedAt: nostr.Timestamp(time.Now().Unix()),
 		Tags: nostr.Tags{
+			{"d", "integration-1"},
 			{"title", "Integration Testing for Academic Relays"},
 			{"abstract", "This paper demonstrates the importance of integration testing in academic relay systems. We present a comprehensive testing framework that validates all policy components working together."},
 			{"subject", "Software Engineering"},
@@ -54,6 +55,7 @@ func TestPolicyIntegration(t *testing.T) {
 		Kind:      AcademicPaperKind,
 		CreatedAt: nostr.Timestamp(time.Now().Unix()),
 		Tags: nostr.Tags{
+			{"d", "integration-2"},
 			{"title", "Integration Testing for Academic Relays"}, // Same title
 			{"abstract", "This paper demonstrates the importance of integration testing in academic relay systems. We present a comprehensive testing framework that validates all policy components working together."}, // Same abstract
 			{"subject", "Computer Science"}, // Different subject
@@ -76,6 +78,7 @@ func TestPolicyIntegration(t *testing.T) {
 		Kind:      AcademicCitationKind,
 		CreatedAt: nostr.Timestamp(time.Now().Unix()),
 		Tags: nostr.Tags{
+			{"d", "integration-3"},
 			{"e", paper.ID},
 			{"context", "This groundbreaking work on integration testing provides the foundation for our research"},
 		},
@@ -93,6 +96,7 @@ func TestPolicyIntegration(t *testing.T) {
 		Kind:      AcademicReviewKind,
 		CreatedAt: nostr.Timestamp(time.Now().Unix()),
 		Tags: nostr.Tags{
+			{"d", "integration-4"},
 			{"e", paper.ID},
 			{"content", "This paper provides a thorough examination of integration testing in academic relay systems. The methodology is sound and the results are convincing. The authors have made a significant contribution to the field of distributed academic infrastructure."},
 			{"methodology-assessment", "The testing framework is well-designed and comprehensive"},
@@ -114,6 +118,7 @@ func TestPolicyIntegration(t *testing.T) {
 		Kind:      AcademicReviewKind,
 		CreatedAt: nostr.Timestamp(time.Now().Unix()),
 		Tags: nostr.Tags{
+			{"d", "integration-5"},
 			{"e", paper.ID},
 			{"content", "This is an excellent paper that deserves immediate publication without any changes."},
 			{"methodology-assessment", "Perfect"},
@@ -136,10 +141,14 @@ func TestPolicyIntegration(t *testing.T) {
 		Kind:      AcademicDataKind,
 		CreatedAt: nostr.Timestamp(time.Now().Unix()),
 		Tags: nostr.Tags{
+			{"d", "integration-6"},
 			{"e", paper.ID},
 			{"data-type", "dataset"},
 			{"description", "Test results and benchmarks from the integration testing framework"},
 			{"url", "https://example.com/data/integration-tests.csv"},
+			{"x", "5f70bf18a086007016e948b04aed3b82103a36bea41755b6cddfaf10ace3c6ef"},
+			{"m", "text/csv"},
+			{"size", "2048"},
 		},
 	}
 	
@@ -156,6 +165,7 @@ func TestPolicyIntegration(t *testing.T) {
 		CreatedAt: nostr.Timestamp(time.Now().Unix()),
 		Content:   "I'm curious about how this integration testing framework handles edge cases in distributed environments. Have you considered Byzantine failures?",
 		Tags: nostr.Tags{
+			{"d", "integration-7"},
 			{"e", paper.ID},
 		},
 	}
@@ -173,6 +183,7 @@ func TestPolicyIntegration(t *testing.T) {
 			Kind:      AcademicPaperKind,
 			CreatedAt: nostr.Timestamp(time.Now().Unix()),
 			Tags: nostr.Tags{
+				{"d", fmt.Sprintf("spam-%d", i)},
 				{"title", fmt.Sprintf("Spam Paper Number %d", i)},
 				{"abstract", fmt.Sprintf("This is spam paper number %d with a sufficiently long abstract to pass validation but trigger rate limiting.", i)},
 				{"subject", "Spam"},
@@ -206,6 +217,7 @@ func TestConcurrentPolicyValidation(t *testing.T) {
 				Kind:      AcademicPaperKind,
 				CreatedAt: nostr.Timestamp(time.Now().Unix()),
 				Tags: nostr.Tags{
+					{"d", fmt.Sprintf("concurrent-%d", index)},
 					{"title", fmt.Sprintf("Concurrent Paper %d", index)},
 					{"abstract", fmt.Sprintf("This is concurrent paper number %d with enough content to meet minimum requirements for testing thread safety.", index)},
 					{"subject", "Concurrency"},
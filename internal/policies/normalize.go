@@ -0,0 +1,90 @@
+package policies
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/runes"
+	"golang.org/x/text/transform"
+	"golang.org/x/text/unicode/norm"
+)
+
+// NormalizationVersion identifies the text normalization DefaultContentHasher
+// and FingerprintPaper apply. It is stored next to every content hash and
+// fingerprint so those made by an older pipeline can be found and recomputed.
+// Version 1 only lowercased and trimmed and version 2 could confuse private
+// use characters with escaped LaTeX; bump it whenever NormalizeText or
+// NormalizeName changes.
+const NormalizationVersion = 3
+
+// latexAccentPattern matches accent macros such as \'e, \"{o} and \c{c}
+const latexAccentPattern = `\\([\x60'"^~=.]|[cuvHk](?:\s+|\b))\{?([a-zA-Z])\}?`
+
+var (
+	latexAccent = regexp.MustCompile(`^` + latexAccentPattern + `$`)
+	// latexToken matches, in order of precedence, escaped special characters
+	// such as \& and \%, line breaks, accent macros, the remaining macros
+	// such as \emph and \alpha, whose arguments are kept, and braces, math
+	// delimiters and non-breaking spaces
+	latexToken = regexp.MustCompile(`\\[&%$#_{}]|\\\\|` + latexAccentPattern + `|\\[a-zA-Z]+\*?|[{}$~]`)
+)
+
+// latexAccents maps accent macros to the combining mark they stand for, which
+// NFKC then composes with the letter
+var latexAccents = map[string]rune{
+	"`": '̀', "'": '́', "^": '̂', "~": '̃', "=": '̄',
+	".": '̇', "\"": '̈', "u": '̆', "v": '̌', "H": '̋',
+	"c": '̧', "k": '̨',
+}
+
+// punctuationFolder replaces typographic punctuation with its ASCII form
+var punctuationFolder = strings.NewReplacer(
+	"‘", "'", "’", "'", "‚", "'", "‛", "'", "′", "'",
+	"“", "\"", "”", "\"", "„", "\"", "‟", "\"", "″", "\"",
+	"«", "\"", "»", "\"",
+	"‐", "-", "‑", "-", "‒", "-", "–", "-", "—", "-", "―", "-", "−", "-",
+)
+
+// StripLaTeX removes basic LaTeX markup, keeping the text it formats: math
+// delimiters and braces are dropped, macros are removed but their arguments
+// kept, accent macros become accented letters and escaped characters lose
+// their backslash. The text is read in a single pass, so an escaped character
+// is never mistaken for the markup it stands for.
+func StripLaTeX(text string) string {
+	return latexToken.ReplaceAllStringFunc(text, func(token string) string {
+		switch {
+		case len(token) == 2 && token[0] == '\\' && strings.ContainsRune(`&%$#_{}`, rune(token[1])):
+			return token[1:]
+		case token == `\\`, token == "~":
+			return " "
+		case token == "{", token == "}", token == "$":
+			return ""
+		}
+		if parts := latexAccent.FindStringSubmatch(token); parts != nil {
+			return parts[2] + string(latexAccents[strings.TrimSpace(parts[1])])
+		}
+		return " "
+	})
+}
+
+// NormalizeText prepares text for hashing so that the same words written
+// differently hash the same: LaTeX markup is stripped, the text is NFKC
+// normalized and lowercased, typographic quotes and dashes become ASCII and
+// runs of whitespace collapse to a single space
+func NormalizeText(text string) string {
+	text = norm.NFKC.String(StripLaTeX(text))
+	text = punctuationFolder.Replace(strings.ToLower(text))
+	return strings.Join(strings.Fields(text), " ")
+}
+
+// NormalizeName normalizes an author name like NormalizeText and also folds
+// diacritics, since names are often transliterated without them
+func NormalizeName(name string) string {
+	fold := transform.Chain(norm.NFD, runes.Remove(runes.In(unicode.Mn)), norm.NFC)
+	folded, _, err := transform.String(fold, NormalizeText(name))
+	if err != nil {
+		return NormalizeText(name)
+	}
+	return folded
+}
@@ -0,0 +1,95 @@
+package policies
+
+import (
+	"testing"
+
+	"github.com/nbd-wtf/go-nostr"
+)
+
+func TestNormalizeText(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{"composed and decomposed accents", "Café Society", "café society"},
+		{"decomposed accents are composed", "Cafe\u0301 Society", "café society"},
+		{"compatibility characters", "ﬁnite ①", "finite 1"},
+		{"smart quotes", "“Smart” ‘quotes’", "\"smart\" 'quotes'"},
+		{"dashes", "Peer–to—peer", "peer-to-peer"},
+		{"whitespace", "  Distributed\t\tSystems\n Study ", "distributed systems study"},
+		{"emphasis", `A \emph{Novel} Approach`, "a novel approach"},
+		{"inline math", `Bounds on $O(n \log n)$ Sorting`, "bounds on o(n n) sorting"},
+		{"accent macros", `Erd\H{o}s and Poincar\'e`, "erdős and poincaré"},
+		{"braced accent macros", `Schr\"{o}dinger`, "schrödinger"},
+		{"escaped characters", `Profit \& Loss at 50\%`, "profit & loss at 50%"},
+		{"non-breaking tilde", `Section~3`, "section 3"},
+		{"escaped markup characters", `\{x\} costs \$5 \#1`, "{x} costs $5 #1"},
+		{"line breaks", `First\\Second`, "first second"},
+		{"private use characters", "a\uE000b \\& c\uE001", "a\ue000b & c\ue001"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := NormalizeText(tt.input); got != tt.want {
+				t.Errorf("NormalizeText(%q) = %q, want %q", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNormalizeName(t *testing.T) {
+	tests := []struct {
+		name1 string
+		name2 string
+		same  bool
+	}{
+		{"José García", "Jose Garcia", true},
+		{"José García", "JOSE GARCIA", true},
+		{`Paul Erd\H{o}s`, "Paul Erdos", true},
+		{"René  Descartes", "Rene Descartes", true},
+		{"Jose Garcia", "Josh Garcia", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name1, func(t *testing.T) {
+			got1, got2 := NormalizeName(tt.name1), NormalizeName(tt.name2)
+			if tt.same && got1 != got2 {
+				t.Errorf("Expected %q and %q to match, got %q and %q", tt.name1, tt.name2, got1, got2)
+			}
+			if !tt.same && got1 == got2 {
+				t.Errorf("Expected %q and %q to differ, both became %q", tt.name1, tt.name2, got1)
+			}
+		})
+	}
+}
+
+func TestHashIgnoresFormatting(t *testing.T) {
+	hasher := &DefaultContentHasher{}
+
+	latex := &nostr.Event{
+		Kind: AcademicPaperKind,
+		Tags: nostr.Tags{
+			{"title", `A \emph{Na\"{i}ve}  Approach to $k$-Means`},
+			{"abstract", "We revisit “k-means” clustering — again."},
+			{"author", "José García"},
+		},
+	}
+	plain := &nostr.Event{
+		Kind: AcademicPaperKind,
+		Tags: nostr.Tags{
+			{"title", "A Naïve Approach to k-Means"},
+			{"abstract", "We revisit \"k-means\" clustering - again."},
+			{"author", "Jose Garcia"},
+		},
+	}
+
+	if hasher.GenerateHash(latex) != hasher.GenerateHash(plain) {
+		t.Error("Expected the LaTeX and plain versions of a paper to hash the same")
+	}
+
+	plain.Tags[0][1] = "A Naive Approach to k-Medoids"
+	if hasher.GenerateHash(latex) == hasher.GenerateHash(plain) {
+		t.Error("Expected papers with different titles to hash differently")
+	}
+}
@@ -0,0 +1,178 @@
+package policies
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/nbd-wtf/go-nostr"
+)
+
+// NoticeConfig configures who may issue editorial notices
+type NoticeConfig struct {
+	// Editors are the pubkeys of recognized venue editors, who may retract
+	// papers they did not author
+	Editors []string `json:"editors"`
+}
+
+// ResolvePaper finds the paper a notice refers to, by event id or, for an
+// address, its latest version
+func ResolvePaper(ctx context.Context, event *nostr.Event, store PaperAuthorStore) (*nostr.Event, error) {
+	id, address := PaperReference(event)
+	if id != "" {
+		return store.GetEvent(ctx, id)
+	}
+	kind, pubkey, d, ok := ParseAddress(address)
+	if !ok {
+		return nil, nil
+	}
+	return latestVersion(ctx, store, kind, pubkey, d)
+}
+
+// MayRetract reports whether pubkey is an author of the paper or one of the editors
+func MayRetract(paper *nostr.Event, pubkey string, editors []string) bool {
+	if IsEventAuthor(paper, pubkey) {
+		return true
+	}
+	for _, editor := range editors {
+		if editor == pubkey {
+			return true
+		}
+	}
+	return false
+}
+
+// ValidateNoticeAuthority checks that a notice refers to a paper in the
+// archive and that retractions are signed by an author or a venue editor
+func ValidateNoticeAuthority(ctx context.Context, event *nostr.Event, store PaperAuthorStore, editors []string) error {
+	if !IsNoticeKind(event.Kind) {
+		return nil
+	}
+
+	paper, err := ResolvePaper(ctx, event, store)
+	if err != nil {
+		return fmt.Errorf("notice check failed: cannot look up paper: %w", err)
+	}
+	if paper == nil || paper.Kind != AcademicPaperKind {
+		return policyErrorf(CodeUnknownReference, "e", "%s must reference a paper in the archive", getEventTypeName(event.Kind))
+	}
+
+	if event.Kind == AcademicRetractionKind && !MayRetract(paper, event.PubKey, editors) {
+		return policyErrorf(CodeNotAuthor, "pubkey", "retractions must be signed by an author of the paper or a recognized venue editor")
+	}
+	return nil
+}
+
+// Notice summarizes a retraction, erratum or expression of concern
+type Notice struct {
+	ID        string    `json:"id"`
+	Pubkey    string    `json:"pubkey"`
+	Reason    string    `json:"reason"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// PaperStatus reports the editorial notices attached to a paper
+type PaperStatus struct {
+	PaperID     string   `json:"paper_id"`
+	Retracted   bool     `json:"retracted"`
+	Retractions []Notice `json:"retractions"`
+	Errata      []Notice `json:"errata"`
+	Concerns    []Notice `json:"concerns"`
+}
+
+// NewPaperStatus sorts a paper's notices by kind. Retractions only count
+// when signed by an author or one of the editors, so a retraction accepted
+// before an editor was removed from the configuration no longer applies.
+func NewPaperStatus(paper *nostr.Event, notices []*nostr.Event, editors []string) *PaperStatus {
+	status := &PaperStatus{
+		PaperID:     paper.ID,
+		Retractions: []Notice{},
+		Errata:      []Notice{},
+		Concerns:    []Notice{},
+	}
+
+	for _, event := range notices {
+		notice := Notice{
+			ID:        event.ID,
+			Pubkey:    event.PubKey,
+			CreatedAt: event.CreatedAt.Time(),
+		}
+		if reason := event.Tags.GetFirst([]string{"reason", ""}); reason != nil {
+			notice.Reason = (*reason)[1]
+		}
+
+		switch event.Kind {
+		case AcademicRetractionKind:
+			if MayRetract(paper, event.PubKey, editors) {
+				status.Retractions = append(status.Retractions, notice)
+			}
+		case AcademicErratumKind:
+			status.Errata = append(status.Errata, notice)
+		case AcademicConcernKind:
+			status.Concerns = append(status.Concerns, notice)
+		}
+	}
+
+	status.Retracted = len(status.Retractions) > 0
+	return status
+}
+
+// GetPaperStatus looks up a paper and its notices. It returns nil when the
+// paper is not in the archive.
+func GetPaperStatus(ctx context.Context, store PaperAuthorStore, paperID string, editors []string) (*PaperStatus, error) {
+	paper, err := store.GetEvent(ctx, paperID)
+	if err != nil {
+		return nil, err
+	}
+	if paper == nil || paper.Kind != AcademicPaperKind {
+		return nil, nil
+	}
+
+	notices, err := store.GetNotices(ctx, paper)
+	if err != nil {
+		return nil, err
+	}
+	return NewPaperStatus(paper, notices, editors), nil
+}
+
+// NoticePolicy checks retractions, errata and expressions of concern against
+// the paper they refer to
+type NoticePolicy struct {
+	store   PaperAuthorStore
+	editors []string
+}
+
+// NewNoticePolicy creates the notice stage
+func NewNoticePolicy(store PaperAuthorStore, config NoticeConfig) *NoticePolicy {
+	return &NoticePolicy{store: store, editors: config.Editors}
+}
+
+// Name returns the stage name
+func (p *NoticePolicy) Name() string { return PolicyNotices }
+
+// Kinds returns the notice kinds
+func (p *NoticePolicy) Kinds() []int { return NoticeKinds }
+
+// Validate checks the referenced paper and, for retractions, the signer
+func (p *NoticePolicy) Validate(ctx context.Context, event *nostr.Event) error {
+	if err := ValidateNoticeAuthority(ctx, event, p.store, p.editors); err != nil {
+		return prefixViolations("notice policy", err)
+	}
+	return nil
+}
+
+// PostProcess does nothing for notices
+func (p *NoticePolicy) PostProcess(ctx context.Context, event *nostr.Event) error {
+	return nil
+}
+
+// Settings lists the notice requirements
+func (p *NoticePolicy) Settings() map[string]interface{} {
+	return map[string]interface{}{
+		"requirements": []string{
+			"referenced paper must exist",
+			"retractions signed by a paper author or a recognized venue editor",
+		},
+		"editors": len(p.editors),
+	}
+}
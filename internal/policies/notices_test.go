@@ -0,0 +1,143 @@
+package policies
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nbd-wtf/go-nostr"
+)
+
+func TestNoticePolicy(t *testing.T) {
+	author := strings.Repeat("a", 64)
+	coAuthor := strings.Repeat("b", 64)
+	editor := strings.Repeat("c", 64)
+	stranger := strings.Repeat("d", 64)
+
+	store := NewInMemoryPaperStore()
+	paper := &nostr.Event{
+		ID:        "notice_paper",
+		PubKey:    author,
+		Kind:      AcademicPaperKind,
+		CreatedAt: nostr.Timestamp(time.Now().Add(-time.Hour).Unix()),
+		Tags: nostr.Tags{
+			{"d", "consensus-study"},
+			{"p", coAuthor},
+		},
+	}
+	store.StoreEvent(paper)
+	store.StoreEvent(&nostr.Event{ID: "notice_review", PubKey: stranger, Kind: AcademicReviewKind})
+
+	policy := NewNoticePolicy(store, NoticeConfig{Editors: []string{editor}})
+	ctx := context.Background()
+
+	notice := func(kind int, pubkey string, reference nostr.Tag) *nostr.Event {
+		return &nostr.Event{
+			PubKey: pubkey,
+			Kind:   kind,
+			Tags:   nostr.Tags{reference, {"reason", "The main results could not be reproduced"}},
+		}
+	}
+	byID := nostr.Tag{"e", paper.ID}
+	byAddress := nostr.Tag{"a", EventAddress(paper)}
+
+	tests := []struct {
+		name  string
+		event *nostr.Event
+		code  ErrorCode
+	}{
+		{"retraction by the author", notice(AcademicRetractionKind, author, byID), ""},
+		{"retraction by a co-author via address", notice(AcademicRetractionKind, coAuthor, byAddress), ""},
+		{"retraction by an editor", notice(AcademicRetractionKind, editor, byID), ""},
+		{"retraction by a stranger", notice(AcademicRetractionKind, stranger, byID), CodeNotAuthor},
+		{"erratum by a stranger", notice(AcademicErratumKind, stranger, byID), ""},
+		{"concern for an unknown paper", notice(AcademicConcernKind, editor, nostr.Tag{"e", "missing"}), CodeUnknownReference},
+		{"concern referencing a review", notice(AcademicConcernKind, editor, nostr.Tag{"e", "notice_review"}), CodeUnknownReference},
+		{"unknown address", notice(AcademicRetractionKind, author, nostr.Tag{"a", "31428:" + author + ":other"}), CodeUnknownReference},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := policy.Validate(ctx, tt.event)
+			if tt.code == "" {
+				if err != nil {
+					t.Errorf("Expected notice to be accepted, got: %v", err)
+				}
+				return
+			}
+			var policyErr *PolicyError
+			if !errors.As(err, &policyErr) || policyErr.Code != tt.code {
+				t.Errorf("Expected %s, got: %v", tt.code, err)
+			}
+		})
+	}
+}
+
+func TestPaperStatus(t *testing.T) {
+	author := strings.Repeat("a", 64)
+	editor := strings.Repeat("c", 64)
+	stranger := strings.Repeat("d", 64)
+
+	store := NewInMemoryPaperStore()
+	paper := &nostr.Event{
+		ID:     "status_paper",
+		PubKey: author,
+		Kind:   AcademicPaperKind,
+		Tags:   nostr.Tags{{"d", "status-study"}},
+	}
+	store.StoreEvent(paper)
+
+	engine := NewPolicyEngineWithConfig(nil, nil, nil, store)
+	ctx := context.Background()
+
+	status, err := engine.PaperStatus(ctx, paper.ID)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if status.Retracted || len(status.Errata) != 0 {
+		t.Errorf("Expected a paper without notices, got %+v", status)
+	}
+
+	store.StoreEvent(&nostr.Event{
+		ID: "status_erratum", PubKey: author, Kind: AcademicErratumKind, CreatedAt: 1,
+		Tags: nostr.Tags{{"e", paper.ID}, {"reason", "Wrong units in the latency table"}},
+	})
+	store.StoreEvent(&nostr.Event{
+		ID: "status_concern", PubKey: editor, Kind: AcademicConcernKind, CreatedAt: 2,
+		Tags: nostr.Tags{{"a", EventAddress(paper)}, {"reason", "Editors are reviewing the image data"}},
+	})
+	// Not signed by an author or a configured editor, so it does not count
+	store.StoreEvent(&nostr.Event{
+		ID: "status_unauthorized", PubKey: stranger, Kind: AcademicRetractionKind, CreatedAt: 3,
+		Tags: nostr.Tags{{"e", paper.ID}, {"reason", "Retracted by someone without authority"}},
+	})
+
+	status, err = engine.PaperStatus(ctx, paper.ID)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if status.Retracted {
+		t.Error("A retraction by a stranger should not retract the paper")
+	}
+	if len(status.Errata) != 1 || status.Errata[0].Reason != "Wrong units in the latency table" {
+		t.Errorf("Expected the erratum to be listed, got %+v", status.Errata)
+	}
+	if len(status.Concerns) != 1 || status.Concerns[0].ID != "status_concern" {
+		t.Errorf("Expected the concern referencing the address to be listed, got %+v", status.Concerns)
+	}
+
+	store.StoreEvent(&nostr.Event{
+		ID: "status_retraction", PubKey: author, Kind: AcademicRetractionKind, CreatedAt: 4,
+		Tags: nostr.Tags{{"e", paper.ID}, {"reason", "The main results could not be reproduced"}},
+	})
+	status, _ = engine.PaperStatus(ctx, paper.ID)
+	if !status.Retracted || len(status.Retractions) != 1 {
+		t.Errorf("Expected the paper to be retracted by its author, got %+v", status)
+	}
+
+	if status, _ := engine.PaperStatus(ctx, "missing"); status != nil {
+		t.Errorf("Expected no status for an unknown paper, got %+v", status)
+	}
+}
@@ -2,16 +2,25 @@ package policies
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"sort"
+	"strings"
+	"sync"
 
 	"github.com/nbd-wtf/go-nostr"
 )
 
 // PolicyEngine combines all policy checks for academic events
 type PolicyEngine struct {
+	config           *Config
+	registry         *PolicyRegistry
 	rateLimiter      RateLimiter
 	duplicateChecker DuplicateChecker
 	paperStore       PaperAuthorStore
+	
+	mu    sync.RWMutex
+	order []string
 }
 
 // NewPolicyEngine creates a new policy engine with all validators
@@ -19,52 +28,200 @@ func NewPolicyEngine(
 	rateLimiter RateLimiter,
 	duplicateChecker DuplicateChecker,
 	paperStore PaperAuthorStore,
+) *PolicyEngine {
+	return NewPolicyEngineWithConfig(nil, rateLimiter, duplicateChecker, paperStore)
+}
+
+// NewPolicyEngineWithConfig creates a policy engine driven by a policy configuration.
+// The built-in stages are registered and run in the order listed in config.Checks.
+func NewPolicyEngineWithConfig(
+	config *Config,
+	rateLimiter RateLimiter,
+	duplicateChecker DuplicateChecker,
+	paperStore PaperAuthorStore,
 ) *PolicyEngine {
 	// Use defaults if not provided
+	if config == nil {
+		config = DefaultConfig()
+	}
 	if rateLimiter == nil {
-		rateLimiter = NewMemoryRateLimiter(DefaultRateLimitConfig())
+		rateLimiter = NewMemoryRateLimiter(config.RateLimitConfig())
 	}
 	if duplicateChecker == nil {
 		duplicateChecker = NewInMemoryDuplicateChecker()
 	}
+	if _, ok := duplicateChecker.(*NearDuplicateChecker); !ok && config.Duplicates.SimilarityThreshold > 0 {
+		duplicateChecker = NewNearDuplicateChecker(duplicateChecker, nil, config.Duplicates.SimilarityThreshold)
+	}
 	if paperStore == nil {
 		paperStore = NewInMemoryPaperStore()
 	}
 	
+	registry := NewPolicyRegistry()
+	registry.Register(NewAuthPolicy(config.Auth))
+	registry.Register(NewAccessListPolicy(nil, config.AccessList))
+	registry.Register(NewRateLimitPolicy(rateLimiter, config.RateLimitConfig()))
+	registry.Register(NewMetadataPolicy(config.Kinds))
+	registry.Register(NewDuplicatePolicy(duplicateChecker, nil))
+	registry.Register(NewReviewIntegrityPolicy(paperStore))
+	registry.Register(NewNoticePolicy(paperStore, config.Notices))
+	registry.Register(NewCitationPolicy(paperStore, nil, config.Citations))
+	
 	return &PolicyEngine{
+		config:           config,
+		registry:         registry,
 		rateLimiter:      rateLimiter,
 		duplicateChecker: duplicateChecker,
 		paperStore:       paperStore,
+		order:            append([]string(nil), config.Checks...),
 	}
 }
 
-// ValidateEvent runs all policy checks on an academic event
-func (pe *PolicyEngine) ValidateEvent(ctx context.Context, event *nostr.Event) error {
-	// 1. Check rate limits first (least expensive)
-	if err := CheckRateLimit(ctx, event, pe.rateLimiter); err != nil {
-		return fmt.Errorf("rate limit policy: %w", err)
+// RegisterPolicy adds a custom policy stage. It only runs once it is
+// included in the stage order, either through the config file's checks
+// list or SetOrder.
+func (pe *PolicyEngine) RegisterPolicy(policy Policy) error {
+	return pe.registry.Register(policy)
+}
+
+// ReplacePolicy swaps a registered stage for another with the same name,
+// for example to back a built-in stage with a persistent store
+func (pe *PolicyEngine) ReplacePolicy(policy Policy) error {
+	return pe.registry.Replace(policy)
+}
+
+// SetOrder sets which registered stages run and in what order
+func (pe *PolicyEngine) SetOrder(names []string) error {
+	seen := make(map[string]bool, len(names))
+	for _, name := range names {
+		if _, ok := pe.registry.Get(name); !ok {
+			return fmt.Errorf("policy stage %q is not registered (registered stages: %s)",
+				name, strings.Join(pe.registry.Names(), ", "))
+		}
+		if seen[name] {
+			return fmt.Errorf("policy stage %q listed more than once", name)
+		}
+		seen[name] = true
 	}
 	
-	// 2. Validate event structure and metadata
-	if err := RequireMinimalMetadata(event); err != nil {
-		return fmt.Errorf("metadata policy: %w", err)
+	pe.mu.Lock()
+	pe.order = append([]string(nil), names...)
+	pe.mu.Unlock()
+	return nil
+}
+
+// Order returns the names of the stages that run, in order
+func (pe *PolicyEngine) Order() []string {
+	pe.mu.RLock()
+	defer pe.mu.RUnlock()
+	return append([]string(nil), pe.order...)
+}
+
+// stages resolves the configured order to registered policies
+func (pe *PolicyEngine) stages() ([]Policy, error) {
+	order := pe.Order()
+	stages := make([]Policy, 0, len(order))
+	for _, name := range order {
+		policy, ok := pe.registry.Get(name)
+		if !ok {
+			return nil, fmt.Errorf("policy stage %q is not registered", name)
+		}
+		stages = append(stages, policy)
 	}
-	
-	// 3. Check for duplicates (papers and data only)
-	if event.Kind == AcademicPaperKind || event.Kind == AcademicDataKind {
-		if err := PreventDuplicatePapers(ctx, event, pe.duplicateChecker); err != nil {
-			return fmt.Errorf("duplicate prevention: %w", err)
+	return stages, nil
+}
+
+// WriteAccessStages decide who may write to the archive and how often,
+// whatever is written. Events kept alongside academic content, such as
+// deletion requests, are checked by these stages only.
+var WriteAccessStages = []string{PolicyAuth, PolicyAccessList, PolicyRateLimit}
+
+// selectStages returns the configured stages that are among names, in the
+// configured order
+func (pe *PolicyEngine) selectStages(names []string) ([]Policy, error) {
+	stages, err := pe.stages()
+	if err != nil {
+		return nil, err
+	}
+	selected := stages[:0]
+	for _, stage := range stages {
+		for _, name := range names {
+			if stage.Name() == name {
+				selected = append(selected, stage)
+				break
+			}
+		}
+	}
+	return selected, nil
+}
+
+// ValidateEvent runs all policy checks on an academic event.
+// Multiple violations are returned together as a *MultiError.
+func (pe *PolicyEngine) ValidateEvent(ctx context.Context, event *nostr.Event) error {
+	stages, err := pe.stages()
+	if err != nil {
+		return err
+	}
+	return pe.validateStages(ctx, event, stages)
+}
+
+// ValidateStages runs the named stages that are enabled on an event, in the
+// configured order. As with ValidateEvent, state held by the stages is
+// released when the event is rejected.
+func (pe *PolicyEngine) ValidateStages(ctx context.Context, event *nostr.Event, names ...string) error {
+	stages, err := pe.selectStages(names)
+	if err != nil {
+		return err
+	}
+	return pe.validateStages(ctx, event, stages)
+}
+
+// validateStages runs the stages that apply to an event
+func (pe *PolicyEngine) validateStages(ctx context.Context, event *nostr.Event, stages []Policy) error {
+	// Run every applicable stage so all violations are reported together
+	var errs []error
+	for _, stage := range stages {
+		if !appliesTo(stage, event.Kind) {
+			continue
+		}
+		if err := stage.Validate(ctx, event); err != nil {
+			errs = append(errs, err)
 		}
 	}
 	
-	// 4. Validate review integrity (reviews only)
-	if event.Kind == AcademicReviewKind {
-		if err := ValidateReviewIntegrity(ctx, event, pe.paperStore); err != nil {
-			return fmt.Errorf("review policy: %w", err)
+	if len(errs) > 0 {
+		// Rejected events must not keep any quota they reserved
+		if err := pe.rollbackStages(ctx, event, stages); err != nil {
+			errs = append(errs, err)
 		}
 	}
 	
-	return nil
+	return joinViolations(errs)
+}
+
+// RollbackEvent releases state held for an event that passed validation
+// but was not stored, such as reserved rate limit quota
+func (pe *PolicyEngine) RollbackEvent(ctx context.Context, event *nostr.Event) error {
+	stages, err := pe.stages()
+	if err != nil {
+		return err
+	}
+	return pe.rollbackStages(ctx, event, stages)
+}
+
+// rollbackStages calls Rollback on every applicable stage that supports it
+func (pe *PolicyEngine) rollbackStages(ctx context.Context, event *nostr.Event, stages []Policy) error {
+	var errs []error
+	for _, stage := range stages {
+		rollback, ok := stage.(RollbackPolicy)
+		if !ok || !appliesTo(stage, event.Kind) {
+			continue
+		}
+		if err := rollback.Rollback(ctx, event); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
 }
 
 // PostProcessEvent handles post-storage operations
@@ -74,68 +231,170 @@ func (pe *PolicyEngine) PostProcessEvent(ctx context.Context, event *nostr.Event
 		store.StoreEvent(event)
 	}
 	
-	// Store content hash for duplicate detection
-	if event.Kind == AcademicPaperKind || event.Kind == AcademicDataKind {
-		hasher := &DefaultContentHasher{}
-		hash := hasher.GenerateHash(event)
-		if err := pe.duplicateChecker.StoreHash(ctx, event, hash); err != nil {
-			return fmt.Errorf("failed to store content hash: %w", err)
+	stages, err := pe.stages()
+	if err != nil {
+		return err
+	}
+	return postProcessStages(ctx, event, stages)
+}
+
+// PostProcessStages runs the post-storage operations of the named stages
+// that are enabled, for an event validated with ValidateStages
+func (pe *PolicyEngine) PostProcessStages(ctx context.Context, event *nostr.Event, names ...string) error {
+	stages, err := pe.selectStages(names)
+	if err != nil {
+		return err
+	}
+	return postProcessStages(ctx, event, stages)
+}
+
+// postProcessStages calls PostProcess on every applicable stage
+func postProcessStages(ctx context.Context, event *nostr.Event, stages []Policy) error {
+	var errs []error
+	for _, stage := range stages {
+		if !appliesTo(stage, event.Kind) {
+			continue
+		}
+		if err := stage.PostProcess(ctx, event); err != nil {
+			errs = append(errs, err)
 		}
 	}
 	
-	return nil
+	return errors.Join(errs...)
+}
+
+// PaperStatus reports a paper's retractions, errata and expressions of
+// concern, or nil when the paper is not in the archive
+func (pe *PolicyEngine) PaperStatus(ctx context.Context, paperID string) (*PaperStatus, error) {
+	return GetPaperStatus(ctx, pe.paperStore, paperID, pe.config.Notices.Editors)
+}
+
+// VersionHistory lists the versions archived at a NIP-33 address, or nil
+// when nothing has been published there
+func (pe *PolicyEngine) VersionHistory(ctx context.Context, address string) (*VersionHistory, error) {
+	return GetVersionHistory(ctx, pe.paperStore, address)
+}
+
+// VersionAt returns the version that was current at an address at a point
+// in time, or nil when there was none
+func (pe *PolicyEngine) VersionAt(ctx context.Context, address string, at nostr.Timestamp) (*nostr.Event, error) {
+	return GetVersionAt(ctx, pe.paperStore, address, at)
 }
 
 // GetPolicyInfo returns human-readable policy information
 func (pe *PolicyEngine) GetPolicyInfo() map[string]interface{} {
-	config := DefaultRateLimitConfig()
+	enabled := make(map[string]int)
+	for i, name := range pe.Order() {
+		enabled[name] = i + 1
+	}
+	
+	stages := []map[string]interface{}{}
+	for _, name := range pe.registry.Names() {
+		policy, _ := pe.registry.Get(name)
+		stage := map[string]interface{}{
+			"name":     name,
+			"kinds":    policy.Kinds(),
+			"enabled":  enabled[name] > 0,
+			"settings": policy.Settings(),
+		}
+		if position, ok := enabled[name]; ok {
+			stage["position"] = position
+		}
+		stages = append(stages, stage)
+	}
+	
+	var rateLimits map[string]interface{}
+	if policy, ok := pe.registry.Get(PolicyRateLimit); ok {
+		rateLimits = policy.Settings()
+	}
+	
+	contentRequirements := map[string]interface{}{}
+	if policy, ok := pe.registry.Get(PolicyMetadata); ok {
+		contentRequirements = policy.Settings()
+	}
+	if enabled[PolicyReviewIntegrity] > 0 {
+		reviews, _ := contentRequirements["reviews"].([]string)
+		contentRequirements["reviews"] = append(reviews, "no self-reviews", "structured feedback")
+	}
+	
+	duplicatePrevention := "Disabled"
+	if enabled[PolicyDuplicates] > 0 {
+		duplicatePrevention = "Active for papers and research data"
+		if threshold := pe.config.Duplicates.SimilarityThreshold; threshold > 0 {
+			duplicatePrevention += fmt.Sprintf(", and for papers whose title and abstract are %.0f%% similar to an archived paper", 100*threshold)
+		}
+	}
+	
+	codes := make(map[string]string, len(errorCodes))
+	for _, code := range errorCodes {
+		codes[string(code)] = code.Prefix()
+	}
 	
 	policies := map[string]interface{}{
-		"rate_limits": map[string]interface{}{
-			"general": fmt.Sprintf("%d events per %v", config.EventsPerWindow, config.WindowDuration),
-			"papers": fmt.Sprintf("%d per %v", 
-				config.KindLimits[AcademicPaperKind].EventsPerWindow,
-				config.KindLimits[AcademicPaperKind].WindowDuration),
-			"reviews": fmt.Sprintf("%d per %v",
-				config.KindLimits[AcademicReviewKind].EventsPerWindow,
-				config.KindLimits[AcademicReviewKind].WindowDuration),
-			"data": fmt.Sprintf("%d per %v",
-				config.KindLimits[AcademicDataKind].EventsPerWindow,
-				config.KindLimits[AcademicDataKind].WindowDuration),
-			"discussions": fmt.Sprintf("%d per %v",
-				config.KindLimits[AcademicDiscussionKind].EventsPerWindow,
-				config.KindLimits[AcademicDiscussionKind].WindowDuration),
+		"error_reporting": map[string]interface{}{
+			"behavior": "Every violation found for an event is reported in a single OK message",
+			"format":   "<prefix>: [<code>:<field>,<code>:<field>] <message>; <message>",
+			"codes":    codes,
 		},
-		"content_requirements": map[string]interface{}{
-			"papers": []string{
-				"title (min 10 chars)",
-				"abstract (min 50 chars)", 
-				"subject tag",
-				"at least one author",
-			},
-			"reviews": []string{
-				"reference to paper",
-				"substantial content (100+ chars)",
-				"no self-reviews",
-				"structured feedback",
-			},
-			"citations": []string{
-				"reference to cited paper",
-				"context (min 20 chars)",
-			},
-			"data": []string{
-				"data-type tag",
-				"description (min 30 chars)",
-				"reference to related paper",
-			},
-			"discussions": []string{
-				"reference to paper or parent",
-				"content (min 50 chars)",
-			},
-		},
-		"duplicate_prevention": "Active for papers and research data",
-		"retention_policy": "Permanent - no deletions allowed",
+		"checks":               pe.Order(),
+		"stages":               stages,
+		"rate_limits":          rateLimits,
+		"content_requirements": contentRequirements,
+		"duplicate_prevention": duplicatePrevention,
+		"retention_policy":     "Permanent - no deletions allowed",
+	}
+	if difficulties := pe.powDifficulties(); len(difficulties) > 0 {
+		policies["proof_of_work"] = map[string]interface{}{
+			"description": "Events whose ID meets the kind's NIP-13 difficulty, committed to in the nonce tag, are exempt from that kind's rate limit",
+			"difficulty":  difficulties,
+		}
+	}
+	if pe.config.Reputation.Enabled {
+		policies["reputation_tiers"] = pe.config.Reputation.Tiers
 	}
 	
 	return policies
-}
\ No newline at end of file
+}
+
+// powDifficulties returns the proof-of-work threshold of each kind that accepts one
+func (pe *PolicyEngine) powDifficulties() map[string]int {
+	difficulties := make(map[string]int)
+	for kind, limit := range pe.config.RateLimitConfig().KindLimits {
+		if limit.PowDifficulty > 0 {
+			difficulties[EventTypeKey(kind)] = limit.PowDifficulty
+		}
+	}
+	return difficulties
+}
+
+// describeKindRules lists the configured requirements for a kind
+func describeKindRules(rules KindRules) []string {
+	requirements := []string{}
+	described := make(map[string]bool)
+	
+	for _, tag := range rules.RequiredTags {
+		if min, ok := rules.MinTagLengths[tag]; ok {
+			requirements = append(requirements, fmt.Sprintf("%s tag (min %d chars)", tag, min))
+		} else {
+			requirements = append(requirements, fmt.Sprintf("%s tag", tag))
+		}
+		described[tag] = true
+	}
+	
+	optional := make([]string, 0, len(rules.MinTagLengths))
+	for tag := range rules.MinTagLengths {
+		if !described[tag] {
+			optional = append(optional, tag)
+		}
+	}
+	sort.Strings(optional)
+	for _, tag := range optional {
+		requirements = append(requirements, fmt.Sprintf("%s tag if present (min %d chars)", tag, rules.MinTagLengths[tag]))
+	}
+	
+	if rules.MinContentLength > 0 {
+		requirements = append(requirements, fmt.Sprintf("content (min %d chars)", rules.MinContentLength))
+	}
+	
+	return requirements
+}
@@ -2,6 +2,7 @@ package policies
 
 import (
 	"context"
+	"strings"
 	"testing"
 	"time"
 
@@ -34,6 +35,7 @@ func TestPolicyEngine(t *testing.T) {
 			PubKey: "author1",
 			Kind:   AcademicPaperKind,
 			Tags: nostr.Tags{
+				{"d", "policies-1"},
 				{"title", "A Comprehensive Study of Policy Engines"},
 				{"abstract", "This paper presents a detailed analysis of policy engines in distributed systems, focusing on their implementation and performance characteristics."},
 				{"subject", "Computer Science"},
@@ -62,6 +64,7 @@ func TestPolicyEngine(t *testing.T) {
 				PubKey: "ratelimited",
 				Kind:   AcademicPaperKind,
 				Tags: nostr.Tags{
+					{"d", "policies-2"},
 					{"title", "Paper Number " + string(rune(i))},
 					{"abstract", "This is a sufficiently long abstract for paper number " + string(rune(i)) + " to meet the minimum requirements."},
 					{"subject", "Testing"},
@@ -89,6 +92,7 @@ func TestPolicyEngine(t *testing.T) {
 			PubKey: "author2",
 			Kind:   AcademicPaperKind,
 			Tags: nostr.Tags{
+				{"d", "policies-3"},
 				{"title", "Unique Paper Title"},
 				{"abstract", "This is a unique abstract that has never been submitted before and meets all the requirements."},
 				{"subject", "Testing"},
@@ -112,6 +116,7 @@ func TestPolicyEngine(t *testing.T) {
 			PubKey: "author3",
 			Kind:   AcademicPaperKind,
 			Tags: nostr.Tags{
+				{"d", "policies-4"},
 				{"title", "Unique Paper Title"}, // Same title
 				{"abstract", "This is a unique abstract that has never been submitted before and meets all the requirements."}, // Same abstract
 				{"subject", "Different Subject"}, // Different subject doesn't matter
@@ -136,6 +141,7 @@ func TestPolicyEngine(t *testing.T) {
 			PubKey: "paper_author",
 			Kind:   AcademicPaperKind,
 			Tags: nostr.Tags{
+				{"d", "policies-5"},
 				{"title", "Paper for Review Testing"},
 				{"abstract", "This paper will be used to test review integrity checks and ensure authors cannot review their own work."},
 				{"subject", "Testing"},
@@ -149,6 +155,7 @@ func TestPolicyEngine(t *testing.T) {
 			PubKey: "paper_author", // Same as paper author
 			Kind:   AcademicReviewKind,
 			Tags: nostr.Tags{
+				{"d", "policies-6"},
 				{"e", "paper_for_review"},
 				{"content", "This is an excellent paper with groundbreaking results. The methodology is perfect and there are no flaws whatsoever."},
 				{"methodology-assessment", "Perfect"},
@@ -170,6 +177,7 @@ func TestPolicyEngine(t *testing.T) {
 			PubKey: "author4",
 			Kind:   AcademicPaperKind,
 			Tags: nostr.Tags{
+				{"d", "policies-7"},
 				{"title", "Short"}, // Too short
 				{"abstract", "Also too short"}, // Too short
 				{"subject", "Testing"},
@@ -237,4 +245,109 @@ func TestGetPolicyInfo(t *testing.T) {
 	if !ok || retention == "" {
 		t.Error("retention_policy info missing")
 	}
-}
\ No newline at end of file
+}
+
+func TestRejectedEventsDoNotConsumeRateLimits(t *testing.T) {
+	ctx := context.Background()
+	rateLimiter := NewMemoryRateLimiter(&RateLimitConfig{
+		EventsPerWindow: 10,
+		WindowDuration:  time.Minute,
+		KindLimits: map[int]KindLimit{
+			AcademicPaperKind: {EventsPerWindow: 1, WindowDuration: time.Minute},
+		},
+	})
+	engine := NewPolicyEngine(rateLimiter, nil, nil)
+
+	newPaper := func(id, abstract string) *nostr.Event {
+		return &nostr.Event{
+			ID:     id,
+			PubKey: "new_author",
+			Kind:   AcademicPaperKind,
+			Tags: nostr.Tags{
+				{"d", "policies-8"},
+				{"title", "Reservation Based Rate Limiting"},
+				{"abstract", abstract},
+				{"subject", "Computer Science"},
+				{"author", "New Author"},
+			},
+			CreatedAt: nostr.Timestamp(time.Now().Unix()),
+		}
+	}
+	abstract := "This paper shows that malformed submissions should not use up the daily quota of new authors."
+
+	// Rejected by the metadata stage: the reserved quota is released
+	if err := engine.ValidateEvent(ctx, newPaper("malformed", "Too short")); err == nil {
+		t.Fatal("Expected malformed paper to be rejected")
+	}
+
+	// Passed validation but failed to store: the caller rolls back
+	failed := newPaper("store_failed", abstract)
+	if err := engine.ValidateEvent(ctx, failed); err != nil {
+		t.Fatalf("Expected paper to pass validation, got: %v", err)
+	}
+	if err := engine.RollbackEvent(ctx, failed); err != nil {
+		t.Fatalf("Rollback failed: %v", err)
+	}
+
+	// The single allowed paper is still available
+	stored := newPaper("stored", abstract+" Revised.")
+	if err := engine.ValidateEvent(ctx, stored); err != nil {
+		t.Fatalf("Expected quota to be available, got: %v", err)
+	}
+	if err := engine.PostProcessEvent(ctx, stored); err != nil {
+		t.Fatalf("Post process failed: %v", err)
+	}
+
+	// Stored events consume quota
+	if err := engine.ValidateEvent(ctx, newPaper("over_limit", abstract+" Again.")); err == nil {
+		t.Error("Expected rate limit error after a stored paper")
+	}
+}
+
+func TestValidateWriteAccessStages(t *testing.T) {
+	ctx := context.Background()
+	author := strings.Repeat("a", 64)
+	blocked := strings.Repeat("b", 64)
+
+	config := DefaultConfig()
+	config.Auth.Mode = AuthModeRequired
+	rateLimiter := NewMemoryRateLimiter(&RateLimitConfig{EventsPerWindow: 1, WindowDuration: time.Minute})
+	engine := NewPolicyEngineWithConfig(config, rateLimiter, nil, nil)
+	accessList := NewInMemoryAccessList()
+	if err := engine.ReplacePolicy(NewAccessListPolicy(accessList, config.AccessList)); err != nil {
+		t.Fatalf("Failed to replace access list stage: %v", err)
+	}
+	accessList.Add(ctx, AccessEntry{List: AccessBlock, Pubkey: blocked, Kind: AllKinds, Reason: "spam"})
+
+	deletion := func(id, pubkey string) *nostr.Event {
+		return &nostr.Event{
+			ID:        id,
+			PubKey:    pubkey,
+			Kind:      5,
+			CreatedAt: nostr.Timestamp(time.Now().Unix()),
+			Tags:      nostr.Tags{{"e", strings.Repeat("c", 64)}},
+		}
+	}
+
+	// Authentication is required, but content rules for academic kinds are not applied
+	if err := engine.ValidateStages(ctx, deletion("unauthed", author), WriteAccessStages...); err == nil || !contains(OKMessage(err), "auth-required:") {
+		t.Errorf("Expected auth-required, got: %v", err)
+	}
+	if err := engine.ValidateStages(WithAuthedPubkey(ctx, blocked), deletion("blocked", blocked), WriteAccessStages...); err == nil || !contains(OKMessage(err), "blocked:") {
+		t.Errorf("Expected blocked pubkey to be rejected, got: %v", err)
+	}
+
+	authed := WithAuthedPubkey(ctx, author)
+	first := deletion("first", author)
+	if err := engine.ValidateStages(authed, first, WriteAccessStages...); err != nil {
+		t.Fatalf("Expected deletion to pass the write access stages, got: %v", err)
+	}
+	if err := engine.PostProcessStages(authed, first, WriteAccessStages...); err != nil {
+		t.Fatalf("Post process failed: %v", err)
+	}
+
+	// The rejected requests released their quota; the stored one used it up
+	if err := engine.ValidateStages(authed, deletion("second", author), WriteAccessStages...); err == nil || !contains(OKMessage(err), "rate-limited:") {
+		t.Errorf("Expected rate limit error, got: %v", err)
+	}
+}
@@ -0,0 +1,61 @@
+package policies
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/nbd-wtf/go-nostr"
+	"github.com/nbd-wtf/go-nostr/nip13"
+)
+
+type proofOfWorkKey struct{}
+
+// WithProofOfWork records the event's NIP-13 difficulty for the rate limiter
+func WithProofOfWork(ctx context.Context, difficulty int) context.Context {
+	return context.WithValue(ctx, proofOfWorkKey{}, difficulty)
+}
+
+// ProofOfWork returns the difficulty recorded with WithProofOfWork
+func ProofOfWork(ctx context.Context) int {
+	difficulty, _ := ctx.Value(proofOfWorkKey{}).(int)
+	return difficulty
+}
+
+// EventProofOfWork returns the NIP-13 difficulty an event can be credited
+// with. Only work the author committed to in the nonce tag counts, so a
+// lucky low-effort ID does not earn an exemption.
+func EventProofOfWork(event *nostr.Event) int {
+	nonce := event.Tags.GetFirst([]string{"nonce", ""})
+	if nonce == nil || len(*nonce) < 3 {
+		return 0
+	}
+
+	target, err := strconv.Atoi((*nonce)[2])
+	if err != nil || target <= 0 {
+		return 0
+	}
+
+	difficulty := nip13.Difficulty(event.ID)
+	if difficulty > target {
+		return target
+	}
+	return difficulty
+}
+
+// ExemptByProofOfWork reports whether the difficulty meets the kind's
+// proof-of-work threshold
+func (l KindLimit) ExemptByProofOfWork(difficulty int) bool {
+	return l.PowDifficulty > 0 && difficulty >= l.PowDifficulty
+}
+
+// MinPowDifficulty returns the lowest proof-of-work threshold configured for
+// any kind, or zero when no kind accepts proof-of-work
+func (c *RateLimitConfig) MinPowDifficulty() int {
+	min := 0
+	for _, limit := range c.KindLimits {
+		if limit.PowDifficulty > 0 && (min == 0 || limit.PowDifficulty < min) {
+			min = limit.PowDifficulty
+		}
+	}
+	return min
+}
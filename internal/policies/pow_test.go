@@ -0,0 +1,90 @@
+package policies
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/nbd-wtf/go-nostr"
+	"github.com/nbd-wtf/go-nostr/nip13"
+)
+
+func minePaper(t *testing.T, title string, difficulty int) *nostr.Event {
+	t.Helper()
+	paper := &nostr.Event{
+		PubKey:    "bulk_uploader",
+		Kind:      AcademicPaperKind,
+		CreatedAt: nostr.Timestamp(time.Now().Unix()),
+		Tags: nostr.Tags{
+			{"title", title},
+			{"abstract", "A paper from the department back catalogue, migrated to the archive in bulk with proof-of-work."},
+			{"subject", "History"},
+			{"author", "Department Archivist"},
+		},
+	}
+	if difficulty == 0 {
+		paper.ID = paper.GetID()
+		return paper
+	}
+	mined, err := nip13.Generate(paper, difficulty, 10*time.Second)
+	if err != nil {
+		t.Fatalf("Failed to mine proof-of-work: %v", err)
+	}
+	mined.ID = mined.GetID()
+	return mined
+}
+
+func TestEventProofOfWork(t *testing.T) {
+	paper := minePaper(t, "Proof of Work Commitments", 8)
+	if got := EventProofOfWork(paper); got != 8 {
+		t.Errorf("Expected committed difficulty 8, got %d", got)
+	}
+
+	// Work without a committed target is not credited
+	uncommitted := *paper
+	uncommitted.Tags = nostr.Tags{{"nonce", "1"}}
+	if got := EventProofOfWork(&uncommitted); got != 0 {
+		t.Errorf("Expected no credit without a target, got %d", got)
+	}
+
+	// A target higher than the actual work only earns the actual difficulty
+	overclaimed := *paper
+	overclaimed.ID = "00ff" + paper.ID[4:]
+	overclaimed.Tags = nostr.Tags{{"nonce", "1", "30"}}
+	if got := EventProofOfWork(&overclaimed); got != 8 {
+		t.Errorf("Expected actual difficulty 8, got %d", got)
+	}
+}
+
+func TestProofOfWorkExemptsKindLimit(t *testing.T) {
+	ctx := context.Background()
+	config := &RateLimitConfig{
+		EventsPerWindow: 100,
+		WindowDuration:  time.Hour,
+		KindLimits: map[int]KindLimit{
+			AcademicPaperKind: {EventsPerWindow: 1, WindowDuration: 24 * time.Hour, PowDifficulty: 8},
+		},
+	}
+	limiter := NewMemoryRateLimiter(config)
+
+	if err := CheckRateLimit(ctx, minePaper(t, "First Paper Without Work", 0), limiter); err != nil {
+		t.Fatalf("First paper failed: %v", err)
+	}
+
+	// The daily quota is used up for papers without proof-of-work
+	if _, err := ReserveRateLimit(ctx, minePaper(t, "Second Paper Without Work", 0), limiter); err == nil {
+		t.Error("Expected rate limit error without proof-of-work")
+	}
+
+	// Papers with enough proof-of-work are not charged against the kind limit
+	for i := 0; i < 3; i++ {
+		paper := minePaper(t, "Back Catalogue Paper "+string(rune('A'+i)), 8)
+		if _, err := ReserveRateLimit(ctx, paper, limiter); err != nil {
+			t.Errorf("Paper %d with proof-of-work was rate limited: %v", i+1, err)
+		}
+	}
+
+	if got := config.MinPowDifficulty(); got != 8 {
+		t.Errorf("Expected lowest difficulty 8, got %d", got)
+	}
+}
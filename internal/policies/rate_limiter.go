@@ -2,7 +2,9 @@ package policies
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"math"
 	"sync"
 	"time"
 
@@ -11,10 +13,92 @@ import (
 
 // RateLimiter manages rate limiting per pubkey
 type RateLimiter interface {
+	// AllowRequest checks the limits and consumes quota immediately
 	AllowRequest(ctx context.Context, pubkey string, eventKind int) error
+	// Reserve checks the limits and holds quota until the reservation is
+	// committed or rolled back. Held quota counts against the limits.
+	Reserve(ctx context.Context, pubkey string, eventKind int) (Reservation, error)
+	// Status reports the pubkey's usage of the general and per-kind limits
+	// without consuming quota
+	Status(ctx context.Context, pubkey string) (*RateLimitStatus, error)
 	Reset(pubkey string)
 }
 
+// Reservation is quota held for an event that is still being validated or stored
+type Reservation interface {
+	// Commit consumes the held quota
+	Commit(ctx context.Context) error
+	// Rollback releases the held quota
+	Rollback(ctx context.Context) error
+}
+
+// LimitStatus is the usage of one limit by a pubkey
+type LimitStatus struct {
+	// Scope is "kind" for a kind-specific limit or "general" when only the
+	// general limit applies
+	Scope     string `json:"scope"`
+	Mode      string `json:"mode"`
+	Limit     int    `json:"limit"`
+	Window    string `json:"window"`
+	Used      int    `json:"used"`
+	Remaining int    `json:"remaining"`
+	// NextSlotAt is when the next used slot frees up; nil when nothing is used
+	NextSlotAt *time.Time `json:"next_slot_at,omitempty"`
+}
+
+// RateLimitStatus is a pubkey's usage of the general limit and of the limit
+// that applies to each academic kind
+type RateLimitStatus struct {
+	Pubkey  string                 `json:"pubkey"`
+	General LimitStatus            `json:"general"`
+	Kinds   map[string]LimitStatus `json:"kinds"`
+}
+
+// NewRateLimitStatus creates an empty status for a pubkey
+func NewRateLimitStatus(pubkey string) *RateLimitStatus {
+	return &RateLimitStatus{Pubkey: pubkey, Kinds: make(map[string]LimitStatus)}
+}
+
+// WindowStatus describes a sliding-window limit with used entries in the
+// window, the oldest of which was recorded at oldest
+func WindowStatus(scope string, limit KindLimit, used int, oldest time.Time) LimitStatus {
+	status := LimitStatus{
+		Scope:     scope,
+		Mode:      RateLimitModeWindow,
+		Limit:     limit.EventsPerWindow,
+		Window:    limit.WindowDuration.String(),
+		Used:      used,
+		Remaining: limit.EventsPerWindow - used,
+	}
+	if status.Remaining < 0 {
+		status.Remaining = 0
+	}
+	if used > 0 && !oldest.IsZero() {
+		next := oldest.Add(limit.WindowDuration)
+		status.NextSlotAt = &next
+	}
+	return status
+}
+
+// BucketStatus describes a token-bucket limit from the bucket's state at now
+func BucketStatus(limit KindLimit, bucket TokenBucket, now time.Time) LimitStatus {
+	bucket.refill(limit, now)
+	available := int(bucket.Tokens)
+	status := LimitStatus{
+		Scope:     "kind",
+		Mode:      RateLimitModeTokenBucket,
+		Limit:     limit.BurstSize(),
+		Window:    limit.WindowDuration.String(),
+		Used:      limit.BurstSize() - available,
+		Remaining: available,
+	}
+	if status.Used > 0 {
+		next := now.Add(time.Duration((float64(available+1) - bucket.Tokens) * float64(limit.tokenInterval())))
+		status.NextSlotAt = &next
+	}
+	return status
+}
+
 // RateLimitConfig defines rate limit parameters
 type RateLimitConfig struct {
 	// Events per time window
@@ -25,10 +109,103 @@ type RateLimitConfig struct {
 	KindLimits map[int]KindLimit
 }
 
+// Rate limit modes for KindLimit.Mode
+const (
+	// RateLimitModeWindow counts events in a sliding window (the default)
+	RateLimitModeWindow = "window"
+	// RateLimitModeTokenBucket refills EventsPerWindow tokens per
+	// WindowDuration into a bucket holding up to Burst tokens
+	RateLimitModeTokenBucket = "token_bucket"
+)
+
 // KindLimit defines limits for specific event kinds
 type KindLimit struct {
 	EventsPerWindow int
 	WindowDuration  time.Duration
+	// Mode selects sliding-window or token-bucket limiting; empty means window
+	Mode string
+	// Burst is the token bucket capacity; zero means EventsPerWindow
+	Burst int
+	// PowDifficulty exempts events with at least this committed NIP-13
+	// difficulty from the kind limit; zero disables the exemption
+	PowDifficulty int
+}
+
+// IsTokenBucket reports whether the limit uses token-bucket mode
+func (l KindLimit) IsTokenBucket() bool {
+	return l.Mode == RateLimitModeTokenBucket
+}
+
+// BurstSize returns the token bucket capacity
+func (l KindLimit) BurstSize() int {
+	if l.Burst > 0 {
+		return l.Burst
+	}
+	return l.EventsPerWindow
+}
+
+// tokenInterval is the time it takes to refill one token
+func (l KindLimit) tokenInterval() time.Duration {
+	return l.WindowDuration / time.Duration(l.EventsPerWindow)
+}
+
+// String describes the limit for policy info
+func (l KindLimit) String() string {
+	description := fmt.Sprintf("%d per %v", l.EventsPerWindow, l.WindowDuration)
+	if l.IsTokenBucket() {
+		description += fmt.Sprintf(" (token bucket, burst %d)", l.BurstSize())
+	}
+	if l.PowDifficulty > 0 {
+		description += fmt.Sprintf(", exempt with proof-of-work difficulty %d", l.PowDifficulty)
+	}
+	return description
+}
+
+// TokenBucket is the state of one token bucket
+type TokenBucket struct {
+	Tokens   float64
+	Refilled time.Time
+}
+
+// NewTokenBucket returns a full bucket for the limit
+func NewTokenBucket(limit KindLimit, now time.Time) TokenBucket {
+	return TokenBucket{Tokens: float64(limit.BurstSize()), Refilled: now}
+}
+
+// Take refills the bucket for the time elapsed since it was last refilled
+// and removes one token. If the bucket is empty it returns false and the
+// time until the next token is available.
+func (b *TokenBucket) Take(limit KindLimit, now time.Time) (bool, time.Duration) {
+	b.refill(limit, now)
+	if b.Tokens < 1 {
+		return false, time.Duration((1 - b.Tokens) * float64(limit.tokenInterval()))
+	}
+	b.Tokens--
+	return true, 0
+}
+
+// refill adds the tokens accumulated since the bucket was last refilled
+func (b *TokenBucket) refill(limit KindLimit, now time.Time) {
+	if elapsed := now.Sub(b.Refilled); elapsed > 0 {
+		b.Tokens += float64(elapsed) / float64(limit.tokenInterval())
+		if burst := float64(limit.BurstSize()); b.Tokens > burst {
+			b.Tokens = burst
+		}
+		b.Refilled = now
+	}
+}
+
+// Return puts back a token taken by a reservation that was rolled back
+func (b *TokenBucket) Return(limit KindLimit) {
+	b.Tokens++
+	if burst := float64(limit.BurstSize()); b.Tokens > burst {
+		b.Tokens = burst
+	}
+}
+
+// GeneralLimit returns the general limit in KindLimit form
+func (c *RateLimitConfig) GeneralLimit() KindLimit {
+	return KindLimit{EventsPerWindow: c.EventsPerWindow, WindowDuration: c.WindowDuration}
 }
 
 // DefaultRateLimitConfig returns sensible defaults for academic content
@@ -59,9 +236,12 @@ func DefaultRateLimitConfig() *RateLimitConfig {
 
 // MemoryRateLimiter implements in-memory rate limiting
 type MemoryRateLimiter struct {
-	config   *RateLimitConfig
-	mu       sync.RWMutex
-	requests map[string]*userRequests
+	config     *RateLimitConfig
+	reputation *ReputationLimits
+	mu         sync.RWMutex
+	requests   map[string]*userRequests
+	// now is the clock, replaced in tests
+	now func() time.Time
 }
 
 type userRequests struct {
@@ -72,6 +252,7 @@ type userRequests struct {
 
 type kindRequests struct {
 	timestamps []time.Time
+	bucket     *TokenBucket
 }
 
 // NewMemoryRateLimiter creates a new in-memory rate limiter
@@ -83,6 +264,7 @@ func NewMemoryRateLimiter(config *RateLimitConfig) *MemoryRateLimiter {
 	limiter := &MemoryRateLimiter{
 		config:   config,
 		requests: make(map[string]*userRequests),
+		now:      time.Now,
 	}
 	
 	// Start cleanup goroutine
@@ -91,8 +273,27 @@ func NewMemoryRateLimiter(config *RateLimitConfig) *MemoryRateLimiter {
 	return limiter
 }
 
+// SetReputation scales kind-specific limits by each pubkey's reputation tier
+func (rl *MemoryRateLimiter) SetReputation(reputation *ReputationLimits) {
+	rl.reputation = reputation
+}
+
 // AllowRequest checks if a request from a pubkey is allowed
 func (rl *MemoryRateLimiter) AllowRequest(ctx context.Context, pubkey string, eventKind int) error {
+	reservation, err := rl.Reserve(ctx, pubkey, eventKind)
+	if err != nil {
+		return err
+	}
+	return reservation.Commit(ctx)
+}
+
+// Reserve holds one slot of the general and kind-specific limits for a pubkey
+func (rl *MemoryRateLimiter) Reserve(ctx context.Context, pubkey string, eventKind int) (Reservation, error) {
+	kindLimits, err := rl.reputation.Apply(ctx, pubkey, rl.config)
+	if err != nil {
+		return nil, err
+	}
+	
 	rl.mu.Lock()
 	user, exists := rl.requests[pubkey]
 	if !exists {
@@ -107,17 +308,18 @@ func (rl *MemoryRateLimiter) AllowRequest(ctx context.Context, pubkey string, ev
 	user.mu.Lock()
 	defer user.mu.Unlock()
 	
-	now := time.Now()
+	now := rl.now()
 	
 	// Check general rate limit
 	user.timestamps = filterTimestamps(user.timestamps, now, rl.config.WindowDuration)
 	if len(user.timestamps) >= rl.config.EventsPerWindow {
-		return fmt.Errorf("rate limit exceeded: maximum %d events per %v. Please wait before submitting more content",
-			rl.config.EventsPerWindow, rl.config.WindowDuration)
+		return nil, RateLimitError(rl.config.EventsPerWindow, rl.config.WindowDuration, len(user.timestamps))
 	}
 	
-	// Check kind-specific rate limit
-	if kindLimit, hasLimit := rl.config.KindLimits[eventKind]; hasLimit {
+	reservation := &memoryReservation{user: user, timestamp: now}
+	
+	// Check kind-specific rate limit; sufficient proof-of-work is exempt
+	if kindLimit, hasLimit := kindLimits[eventKind]; hasLimit && !kindLimit.ExemptByProofOfWork(ProofOfWork(ctx)) {
 		if user.kindCounts[eventKind] == nil {
 			user.kindCounts[eventKind] = &kindRequests{
 				timestamps: make([]time.Time, 0),
@@ -125,23 +327,127 @@ func (rl *MemoryRateLimiter) AllowRequest(ctx context.Context, pubkey string, ev
 		}
 		
 		kindReqs := user.kindCounts[eventKind]
-		kindReqs.timestamps = filterTimestamps(kindReqs.timestamps, now, kindLimit.WindowDuration)
-		
-		if len(kindReqs.timestamps) >= kindLimit.EventsPerWindow {
-			return fmt.Errorf("rate limit exceeded for %s: maximum %d per %v. Academic content requires careful review - please pace your submissions",
-				getEventTypeName(eventKind), kindLimit.EventsPerWindow, kindLimit.WindowDuration)
+		if kindLimit.IsTokenBucket() {
+			// Token buckets keep a constant amount of state per pubkey and kind
+			if kindReqs.bucket == nil {
+				bucket := NewTokenBucket(kindLimit, now)
+				kindReqs.bucket = &bucket
+			}
+			if ok, retryAfter := kindReqs.bucket.Take(kindLimit, now); !ok {
+				return nil, KindTokenBucketError(eventKind, kindLimit, *kindReqs.bucket, retryAfter)
+			}
+		} else {
+			kindReqs.timestamps = filterTimestamps(kindReqs.timestamps, now, kindLimit.WindowDuration)
+			
+			if len(kindReqs.timestamps) >= kindLimit.EventsPerWindow {
+				return nil, KindRateLimitError(eventKind, kindLimit.EventsPerWindow, kindLimit.WindowDuration, len(kindReqs.timestamps))
+			}
+			
+			// Add timestamp for kind-specific tracking
+			kindReqs.timestamps = append(kindReqs.timestamps, now)
 		}
-		
-		// Add timestamp for kind-specific tracking
-		kindReqs.timestamps = append(kindReqs.timestamps, now)
+		reservation.kind = kindReqs
+		reservation.kindLimit = kindLimit
 	}
 	
 	// Add timestamp for general tracking
 	user.timestamps = append(user.timestamps, now)
 	
+	return reservation, nil
+}
+
+// memoryReservation holds the timestamps recorded by Reserve
+type memoryReservation struct {
+	user      *userRequests
+	kind      *kindRequests
+	kindLimit KindLimit
+	timestamp time.Time
+	done      bool
+}
+
+// Commit keeps the recorded timestamps
+func (r *memoryReservation) Commit(ctx context.Context) error {
+	r.user.mu.Lock()
+	defer r.user.mu.Unlock()
+	r.done = true
+	return nil
+}
+
+// Rollback removes the recorded timestamps so the slot can be reused
+func (r *memoryReservation) Rollback(ctx context.Context) error {
+	r.user.mu.Lock()
+	defer r.user.mu.Unlock()
+	
+	if r.done {
+		return nil
+	}
+	r.done = true
+	
+	r.user.timestamps = removeTimestamp(r.user.timestamps, r.timestamp)
+	if r.kind != nil {
+		if r.kindLimit.IsTokenBucket() {
+			r.kind.bucket.Return(r.kindLimit)
+		} else {
+			r.kind.timestamps = removeTimestamp(r.kind.timestamps, r.timestamp)
+		}
+	}
 	return nil
 }
 
+// Status reports the pubkey's usage without consuming quota
+func (rl *MemoryRateLimiter) Status(ctx context.Context, pubkey string) (*RateLimitStatus, error) {
+	kindLimits, err := rl.reputation.Apply(ctx, pubkey, rl.config)
+	if err != nil {
+		return nil, err
+	}
+	
+	rl.mu.RLock()
+	user := rl.requests[pubkey]
+	rl.mu.RUnlock()
+	if user == nil {
+		user = &userRequests{kindCounts: make(map[int]*kindRequests)}
+	}
+	
+	user.mu.Lock()
+	defer user.mu.Unlock()
+	
+	now := rl.now()
+	status := NewRateLimitStatus(pubkey)
+	general := filterTimestamps(user.timestamps, now, rl.config.WindowDuration)
+	status.General = WindowStatus("general", rl.config.GeneralLimit(), len(general), oldestTimestamp(general))
+	
+	for _, kind := range AcademicKinds {
+		limit, hasLimit := kindLimits[kind]
+		kindReqs := user.kindCounts[kind]
+		switch {
+		case !hasLimit:
+			status.Kinds[EventTypeKey(kind)] = status.General
+		case limit.IsTokenBucket():
+			bucket := NewTokenBucket(limit, now)
+			if kindReqs != nil && kindReqs.bucket != nil {
+				bucket = *kindReqs.bucket
+			}
+			status.Kinds[EventTypeKey(kind)] = BucketStatus(limit, bucket, now)
+		default:
+			var timestamps []time.Time
+			if kindReqs != nil {
+				timestamps = filterTimestamps(kindReqs.timestamps, now, limit.WindowDuration)
+			}
+			status.Kinds[EventTypeKey(kind)] = WindowStatus("kind", limit, len(timestamps), oldestTimestamp(timestamps))
+		}
+	}
+	
+	return status, nil
+}
+
+// oldestTimestamp returns the first timestamp, or the zero time if there are none
+func oldestTimestamp(timestamps []time.Time) time.Time {
+	if len(timestamps) == 0 {
+		return time.Time{}
+	}
+	return timestamps[0]
+}
+
 // Reset clears rate limit data for a pubkey
 func (rl *MemoryRateLimiter) Reset(pubkey string) {
 	rl.mu.Lock()
@@ -156,7 +462,7 @@ func (rl *MemoryRateLimiter) cleanup() {
 	
 	for range ticker.C {
 		rl.mu.Lock()
-		now := time.Now()
+		now := rl.now()
 		
 		// Remove users with no recent activity
 		for pubkey, user := range rl.requests {
@@ -196,6 +502,37 @@ func filterTimestamps(timestamps []time.Time, now time.Time, window time.Duratio
 	return filtered
 }
 
+// RateLimitError reports that the general limit of a pubkey has been reached
+func RateLimitError(limit int, window time.Duration, count int) *PolicyError {
+	return policyErrorf(CodeRateLimited, "", "rate limit exceeded: maximum %d events per %v. Please wait before submitting more content",
+		limit, window).withLimit(limit, count)
+}
+
+// KindRateLimitError reports that the limit for one kind has been reached
+func KindRateLimitError(kind, limit int, window time.Duration, count int) *PolicyError {
+	return policyErrorf(CodeRateLimited, "kind", "rate limit exceeded for %s: maximum %d per %v. Academic content requires careful review - please pace your submissions",
+		getEventTypeName(kind), limit, window).withLimit(limit, count)
+}
+
+// KindTokenBucketError reports that the token bucket for one kind is empty.
+// The tokens used so far are reported as the actual value; they fall short of
+// the burst by the fraction of a token refilled since the last submission.
+func KindTokenBucketError(kind int, limit KindLimit, bucket TokenBucket, retryAfter time.Duration) *PolicyError {
+	used := math.Round((float64(limit.BurstSize())-bucket.Tokens)*100) / 100
+	return policyErrorf(CodeRateLimited, "kind", "rate limit exceeded for %s: burst of %d used, refilling at %d per %v. Next submission allowed in %v",
+		getEventTypeName(kind), limit.BurstSize(), limit.EventsPerWindow, limit.WindowDuration, retryAfter.Round(time.Second)).withLimit(limit.BurstSize(), used)
+}
+
+// removeTimestamp removes one occurrence of ts from timestamps
+func removeTimestamp(timestamps []time.Time, ts time.Time) []time.Time {
+	for i, t := range timestamps {
+		if t.Equal(ts) {
+			return append(timestamps[:i], timestamps[i+1:]...)
+		}
+	}
+	return timestamps
+}
+
 // getEventTypeName returns human-readable event type names
 func getEventTypeName(kind int) string {
 	switch kind {
@@ -209,11 +546,42 @@ func getEventTypeName(kind int) string {
 		return "research data"
 	case AcademicDiscussionKind:
 		return "discussions"
+	case AcademicRetractionKind:
+		return "retractions"
+	case AcademicErratumKind:
+		return "errata"
+	case AcademicConcernKind:
+		return "expressions of concern"
 	default:
 		return "events"
 	}
 }
 
+// EventTypeKey returns the short key used for a kind in policy info and
+// status reports, such as "papers" or "reviews"
+func EventTypeKey(kind int) string {
+	switch kind {
+	case AcademicPaperKind:
+		return "papers"
+	case AcademicCitationKind:
+		return "citations"
+	case AcademicReviewKind:
+		return "reviews"
+	case AcademicDataKind:
+		return "data"
+	case AcademicDiscussionKind:
+		return "discussions"
+	case AcademicRetractionKind:
+		return "retractions"
+	case AcademicErratumKind:
+		return "errata"
+	case AcademicConcernKind:
+		return "concerns"
+	default:
+		return fmt.Sprintf("kind_%d", kind)
+	}
+}
+
 // CheckRateLimit is a convenience function for rate limiting
 func CheckRateLimit(ctx context.Context, event *nostr.Event, limiter RateLimiter) error {
 	if limiter == nil {
@@ -221,4 +589,57 @@ func CheckRateLimit(ctx context.Context, event *nostr.Event, limiter RateLimiter
 	}
 	
 	return limiter.AllowRequest(ctx, event.PubKey, event.Kind)
-}
\ No newline at end of file
+}
+
+// ReserveRateLimit holds quota for an event until it is committed or rolled back.
+// The event's proof-of-work is passed to the limiter through the context.
+// When the connection authenticated as another pubkey (such as a co-author
+// publishing for the author) quota is reserved for both identities.
+func ReserveRateLimit(ctx context.Context, event *nostr.Event, limiter RateLimiter) (Reservation, error) {
+	if limiter == nil {
+		return noopReservation{}, nil
+	}
+	
+	ctx = WithProofOfWork(ctx, EventProofOfWork(event))
+	reservation, err := limiter.Reserve(ctx, event.PubKey, event.Kind)
+	if err != nil {
+		return nil, err
+	}
+	
+	authed := AuthedPubkey(ctx)
+	if authed == "" || authed == event.PubKey {
+		return reservation, nil
+	}
+	
+	authedReservation, err := limiter.Reserve(ctx, authed, event.Kind)
+	if err != nil {
+		reservation.Rollback(ctx)
+		return nil, err
+	}
+	return multiReservation{reservation, authedReservation}, nil
+}
+
+// multiReservation commits or rolls back several reservations together
+type multiReservation []Reservation
+
+func (m multiReservation) Commit(ctx context.Context) error {
+	var errs []error
+	for _, r := range m {
+		errs = append(errs, r.Commit(ctx))
+	}
+	return errors.Join(errs...)
+}
+
+func (m multiReservation) Rollback(ctx context.Context) error {
+	var errs []error
+	for _, r := range m {
+		errs = append(errs, r.Rollback(ctx))
+	}
+	return errors.Join(errs...)
+}
+
+// noopReservation is returned when no limiter is configured
+type noopReservation struct{}
+
+func (noopReservation) Commit(ctx context.Context) error   { return nil }
+func (noopReservation) Rollback(ctx context.Context) error { return nil }
\ No newline at end of file
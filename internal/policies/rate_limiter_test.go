@@ -2,6 +2,7 @@ package policies
 
 import (
 	"context"
+	"errors"
 	"testing"
 	"time"
 
@@ -196,4 +197,178 @@ func TestCheckRateLimit(t *testing.T) {
 	if err == nil {
 		t.Error("Expected rate limit error")
 	}
-}
\ No newline at end of file
+}
+
+func TestRateLimitReservations(t *testing.T) {
+	ctx := context.Background()
+	limiter := NewMemoryRateLimiter(&RateLimitConfig{
+		EventsPerWindow: 10,
+		WindowDuration:  time.Minute,
+		KindLimits: map[int]KindLimit{
+			AcademicPaperKind: {EventsPerWindow: 1, WindowDuration: time.Minute},
+		},
+	})
+
+	reservation, err := limiter.Reserve(ctx, "reserver", AcademicPaperKind)
+	if err != nil {
+		t.Fatalf("First reservation failed: %v", err)
+	}
+
+	// Held quota counts against the limit
+	if _, err := limiter.Reserve(ctx, "reserver", AcademicPaperKind); err == nil {
+		t.Error("Expected rate limit error while quota is reserved")
+	}
+
+	// Rolling back frees the slot
+	reservation.Rollback(ctx)
+	reservation, err = limiter.Reserve(ctx, "reserver", AcademicPaperKind)
+	if err != nil {
+		t.Fatalf("Reservation after rollback failed: %v", err)
+	}
+
+	// Committed quota stays consumed, and a late rollback is ignored
+	reservation.Commit(ctx)
+	reservation.Rollback(ctx)
+	if _, err := limiter.Reserve(ctx, "reserver", AcademicPaperKind); err == nil {
+		t.Error("Expected rate limit error after commit")
+	}
+}
+
+func TestTokenBucketRateLimit(t *testing.T) {
+	ctx := context.Background()
+	limiter := NewMemoryRateLimiter(&RateLimitConfig{
+		EventsPerWindow: 100,
+		WindowDuration:  time.Hour,
+		KindLimits: map[int]KindLimit{
+			AcademicDataKind: {
+				EventsPerWindow: 10,
+				WindowDuration:  time.Second,
+				Mode:            RateLimitModeTokenBucket,
+				Burst:           3,
+			},
+		},
+	})
+	now := time.Now()
+	limiter.now = func() time.Time { return now }
+	pubkey := "batch_uploader"
+
+	// The whole burst is available at once
+	for i := 0; i < 3; i++ {
+		if err := limiter.AllowRequest(ctx, pubkey, AcademicDataKind); err != nil {
+			t.Fatalf("Burst request %d failed: %v", i+1, err)
+		}
+	}
+
+	// Then requests are throttled to the refill rate
+	err := limiter.AllowRequest(ctx, pubkey, AcademicDataKind)
+	if err == nil || !contains(err.Error(), "burst of 3 used") {
+		t.Fatalf("Expected token bucket error, got: %v", err)
+	}
+
+	// Part of a token has refilled, which the error reports as usage
+	now = now.Add(40 * time.Millisecond)
+	err = limiter.AllowRequest(ctx, pubkey, AcademicDataKind)
+	var policyErr *PolicyError
+	if !errors.As(err, &policyErr) || policyErr.Limit != 3 || policyErr.Actual != 2.6 {
+		t.Fatalf("Expected 2.6 of 3 tokens used, got: %#v", err)
+	}
+
+	// One token refills every 100ms
+	now = now.Add(80 * time.Millisecond)
+	if err := limiter.AllowRequest(ctx, pubkey, AcademicDataKind); err != nil {
+		t.Errorf("Request after refill failed: %v", err)
+	}
+	if err := limiter.AllowRequest(ctx, pubkey, AcademicDataKind); err == nil {
+		t.Error("Expected only one token to have refilled")
+	}
+
+	// Rolled back reservations return their token
+	now = now.Add(100 * time.Millisecond)
+	reservation, err := limiter.Reserve(ctx, pubkey, AcademicDataKind)
+	if err != nil {
+		t.Fatalf("Reservation failed: %v", err)
+	}
+	reservation.Rollback(ctx)
+	if err := limiter.AllowRequest(ctx, pubkey, AcademicDataKind); err != nil {
+		t.Errorf("Request after rollback failed: %v", err)
+	}
+}
+
+func TestTokenBucket(t *testing.T) {
+	limit := KindLimit{EventsPerWindow: 2, WindowDuration: time.Minute, Mode: RateLimitModeTokenBucket}
+	now := time.Now()
+	bucket := NewTokenBucket(limit, now)
+
+	// Burst defaults to the events per window
+	for i := 0; i < 2; i++ {
+		if ok, _ := bucket.Take(limit, now); !ok {
+			t.Fatalf("Take %d failed", i+1)
+		}
+	}
+
+	ok, retryAfter := bucket.Take(limit, now)
+	if ok || retryAfter != 30*time.Second {
+		t.Errorf("Expected empty bucket with 30s wait, got ok=%v wait=%v", ok, retryAfter)
+	}
+
+	// Refills never exceed the burst size
+	if ok, _ := bucket.Take(limit, now.Add(time.Hour)); !ok || bucket.Tokens != 1 {
+		t.Errorf("Expected bucket refilled to burst, got %v tokens", bucket.Tokens)
+	}
+}
+
+func TestRateLimitStatus(t *testing.T) {
+	ctx := context.Background()
+	limiter := NewMemoryRateLimiter(&RateLimitConfig{
+		EventsPerWindow: 100,
+		WindowDuration:  time.Hour,
+		KindLimits: map[int]KindLimit{
+			AcademicPaperKind: {EventsPerWindow: 5, WindowDuration: 24 * time.Hour},
+			AcademicDataKind:  {EventsPerWindow: 10, WindowDuration: 24 * time.Hour, Mode: RateLimitModeTokenBucket, Burst: 4},
+		},
+	})
+	pubkey := "status_author"
+
+	// Unknown pubkeys have their full quota
+	status, err := limiter.Status(ctx, pubkey)
+	if err != nil {
+		t.Fatalf("Status failed: %v", err)
+	}
+	if papers := status.Kinds["papers"]; papers.Remaining != 5 || papers.NextSlotAt != nil {
+		t.Errorf("Expected 5 papers remaining and no next slot, got %+v", papers)
+	}
+
+	before := time.Now()
+	limiter.AllowRequest(ctx, pubkey, AcademicPaperKind)
+	limiter.AllowRequest(ctx, pubkey, AcademicPaperKind)
+	limiter.AllowRequest(ctx, pubkey, AcademicDataKind)
+
+	status, err = limiter.Status(ctx, pubkey)
+	if err != nil {
+		t.Fatalf("Status failed: %v", err)
+	}
+
+	papers := status.Kinds["papers"]
+	if papers.Scope != "kind" || papers.Limit != 5 || papers.Used != 2 || papers.Remaining != 3 {
+		t.Errorf("Unexpected paper status: %+v", papers)
+	}
+	if papers.NextSlotAt == nil || papers.NextSlotAt.Before(before.Add(24*time.Hour)) {
+		t.Errorf("Expected next paper slot a day after the first submission, got %v", papers.NextSlotAt)
+	}
+
+	data := status.Kinds["data"]
+	if data.Mode != RateLimitModeTokenBucket || data.Limit != 4 || data.Used != 1 || data.Remaining != 3 {
+		t.Errorf("Unexpected data status: %+v", data)
+	}
+
+	// Citations have no kind limit, so the general limit applies
+	if citations := status.Kinds["citations"]; citations.Scope != "general" || citations.Used != 3 {
+		t.Errorf("Expected general limit for citations, got %+v", citations)
+	}
+
+	// Status does not consume quota
+	again, _ := limiter.Status(ctx, pubkey)
+	if again.General.Used != 3 {
+		t.Errorf("Expected status to leave usage unchanged, got %d", again.General.Used)
+	}
+}
@@ -0,0 +1,117 @@
+package policies
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/nbd-wtf/go-nostr"
+)
+
+// Policy is a named validation stage run by the PolicyEngine
+type Policy interface {
+	// Name identifies the stage in configuration and policy info
+	Name() string
+	// Kinds lists the event kinds the stage applies to; nil means every kind
+	Kinds() []int
+	// Validate rejects events that violate the policy
+	Validate(ctx context.Context, event *nostr.Event) error
+	// PostProcess runs after an accepted event has been stored
+	PostProcess(ctx context.Context, event *nostr.Event) error
+	// Settings describes the stage's configuration for the /policies endpoint
+	Settings() map[string]interface{}
+}
+
+// RollbackPolicy is implemented by stages that hold state between Validate
+// and PostProcess, such as reserved rate limit quota. Rollback releases that
+// state when the event is rejected by another stage or fails to be stored.
+type RollbackPolicy interface {
+	Policy
+	Rollback(ctx context.Context, event *nostr.Event) error
+}
+
+// PolicyRegistry holds the policy stages known to an engine
+type PolicyRegistry struct {
+	mu       sync.RWMutex
+	policies map[string]Policy
+	names    []string
+}
+
+// NewPolicyRegistry creates an empty registry
+func NewPolicyRegistry() *PolicyRegistry {
+	return &PolicyRegistry{
+		policies: make(map[string]Policy),
+	}
+}
+
+// Register adds a policy stage; names must be unique
+func (r *PolicyRegistry) Register(policy Policy) error {
+	if policy == nil {
+		return fmt.Errorf("cannot register nil policy")
+	}
+
+	name := policy.Name()
+	if name == "" {
+		return fmt.Errorf("cannot register policy without a name")
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.policies[name]; exists {
+		return fmt.Errorf("policy %q is already registered", name)
+	}
+
+	r.policies[name] = policy
+	r.names = append(r.names, name)
+	return nil
+}
+
+// Replace swaps the policy registered under the same name
+func (r *PolicyRegistry) Replace(policy Policy) error {
+	if policy == nil {
+		return fmt.Errorf("cannot register nil policy")
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	name := policy.Name()
+	if _, exists := r.policies[name]; !exists {
+		return fmt.Errorf("policy %q is not registered", name)
+	}
+
+	r.policies[name] = policy
+	return nil
+}
+
+// Get returns the policy registered under name
+func (r *PolicyRegistry) Get(name string) (Policy, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	policy, ok := r.policies[name]
+	return policy, ok
+}
+
+// Names returns registered policy names in registration order
+func (r *PolicyRegistry) Names() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return append([]string(nil), r.names...)
+}
+
+// appliesTo reports whether a policy should run for the given kind
+func appliesTo(policy Policy, kind int) bool {
+	kinds := policy.Kinds()
+	if kinds == nil {
+		return true
+	}
+	for _, k := range kinds {
+		if k == kind {
+			return true
+		}
+	}
+	return false
+}
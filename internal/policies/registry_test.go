@@ -0,0 +1,161 @@
+package policies
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/nbd-wtf/go-nostr"
+)
+
+// institutionPolicy is a custom stage that requires an affiliation tag on papers
+type institutionPolicy struct {
+	processed []string
+}
+
+func (p *institutionPolicy) Name() string { return "institution" }
+func (p *institutionPolicy) Kinds() []int { return []int{AcademicPaperKind} }
+
+func (p *institutionPolicy) Validate(ctx context.Context, event *nostr.Event) error {
+	if event.Tags.GetFirst([]string{"affiliation", ""}) == nil {
+		return fmt.Errorf("institution policy: missing affiliation tag")
+	}
+	return nil
+}
+
+func (p *institutionPolicy) PostProcess(ctx context.Context, event *nostr.Event) error {
+	p.processed = append(p.processed, event.ID)
+	return nil
+}
+
+func (p *institutionPolicy) Settings() map[string]interface{} {
+	return map[string]interface{}{"required": "affiliation"}
+}
+
+func TestPolicyRegistry(t *testing.T) {
+	registry := NewPolicyRegistry()
+
+	if err := registry.Register(&institutionPolicy{}); err != nil {
+		t.Fatalf("Register failed: %v", err)
+	}
+	if err := registry.Register(&institutionPolicy{}); err == nil {
+		t.Error("Expected error registering a duplicate name")
+	}
+	if err := registry.Register(nil); err == nil {
+		t.Error("Expected error registering nil policy")
+	}
+
+	if _, ok := registry.Get("institution"); !ok {
+		t.Error("Registered policy not found")
+	}
+	if names := registry.Names(); len(names) != 1 || names[0] != "institution" {
+		t.Errorf("Unexpected names: %v", names)
+	}
+
+	replacement := &institutionPolicy{}
+	if err := registry.Replace(replacement); err != nil {
+		t.Fatalf("Replace failed: %v", err)
+	}
+	if policy, _ := registry.Get("institution"); policy != replacement {
+		t.Error("Expected the replacement to be registered")
+	}
+	if err := NewPolicyRegistry().Replace(replacement); err == nil {
+		t.Error("Expected error replacing an unregistered policy")
+	}
+}
+
+func TestPolicyEngineCustomStages(t *testing.T) {
+	ctx := context.Background()
+
+	paper := &nostr.Event{
+		ID:        "custom_stage_paper",
+		PubKey:    "custom_author",
+		Kind:      AcademicPaperKind,
+		CreatedAt: nostr.Timestamp(time.Now().Unix()),
+		Tags: nostr.Tags{
+			{"d", "registry-1"},
+			{"title", "Custom Policy Stages in Practice"},
+			{"abstract", "This paper describes how institutions can extend relay validation with their own policy stages."},
+			{"subject", "Computer Science"},
+			{"author", "Custom Author"},
+		},
+	}
+
+	t.Run("registered stage does not run until ordered", func(t *testing.T) {
+		engine := NewPolicyEngine(nil, nil, nil)
+		if err := engine.RegisterPolicy(&institutionPolicy{}); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := engine.ValidateEvent(ctx, paper); err != nil {
+			t.Errorf("Unordered custom stage should not run: %v", err)
+		}
+	})
+
+	t.Run("ordered stage runs and post-processes", func(t *testing.T) {
+		engine := NewPolicyEngine(nil, nil, nil)
+		custom := &institutionPolicy{}
+		if err := engine.RegisterPolicy(custom); err != nil {
+			t.Fatal(err)
+		}
+		if err := engine.SetOrder([]string{"institution", PolicyMetadata}); err != nil {
+			t.Fatal(err)
+		}
+
+		err := engine.ValidateEvent(ctx, paper)
+		if err == nil || !contains(err.Error(), "institution policy") {
+			t.Errorf("Expected institution policy error, got: %v", err)
+		}
+
+		affiliated := *paper
+		affiliated.Tags = append(nostr.Tags{{"affiliation", "Example University"}}, paper.Tags...)
+		if err := engine.ValidateEvent(ctx, &affiliated); err != nil {
+			t.Errorf("Affiliated paper failed: %v", err)
+		}
+		if err := engine.PostProcessEvent(ctx, &affiliated); err != nil {
+			t.Errorf("Post process failed: %v", err)
+		}
+		if len(custom.processed) != 1 {
+			t.Errorf("Expected custom stage to post-process once, got %d", len(custom.processed))
+		}
+	})
+
+	t.Run("stage only runs for its kinds", func(t *testing.T) {
+		engine := NewPolicyEngine(nil, nil, nil)
+		engine.RegisterPolicy(&institutionPolicy{})
+		engine.SetOrder([]string{"institution"})
+
+		discussion := &nostr.Event{Kind: AcademicDiscussionKind, PubKey: "someone"}
+		if err := engine.ValidateEvent(ctx, discussion); err != nil {
+			t.Errorf("Paper-only stage should not run for discussions: %v", err)
+		}
+	})
+
+	t.Run("unknown stage in order is rejected", func(t *testing.T) {
+		engine := NewPolicyEngine(nil, nil, nil)
+		if err := engine.SetOrder([]string{"not_registered"}); err == nil {
+			t.Error("Expected error for unregistered stage")
+		}
+		if err := engine.SetOrder([]string{PolicyMetadata, PolicyMetadata}); err == nil {
+			t.Error("Expected error for repeated stage")
+		}
+	})
+
+	t.Run("policy info lists every registered stage", func(t *testing.T) {
+		engine := NewPolicyEngine(nil, nil, nil)
+		engine.RegisterPolicy(&institutionPolicy{})
+
+		stages := engine.GetPolicyInfo()["stages"].([]map[string]interface{})
+		if len(stages) != len(builtinChecks)+1 {
+			t.Fatalf("Expected %d stages, got %d", len(builtinChecks)+1, len(stages))
+		}
+		last := stages[len(stages)-1]
+		if last["name"] != "institution" || last["enabled"] != false {
+			t.Errorf("Unexpected custom stage info: %v", last)
+		}
+		if stages[0]["position"] != 1 {
+			t.Errorf("Expected first built-in stage at position 1, got %v", stages[0]["position"])
+		}
+	})
+}
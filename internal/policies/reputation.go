@@ -0,0 +1,260 @@
+package policies
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+)
+
+// Reputation summarizes a pubkey's history in the archive
+type Reputation struct {
+	// Papers is the number of accepted papers
+	Papers int `json:"papers"`
+	// ReviewsReceived counts reviews by others of the pubkey's papers
+	ReviewsReceived int `json:"reviews_received"`
+	// FirstSeen is when the pubkey's first stored event was created
+	FirstSeen time.Time `json:"first_seen,omitempty"`
+}
+
+// AccountAge returns how long the pubkey has been publishing to the archive
+func (r *Reputation) AccountAge(now time.Time) time.Duration {
+	if r.FirstSeen.IsZero() || r.FirstSeen.After(now) {
+		return 0
+	}
+	return now.Sub(r.FirstSeen)
+}
+
+// ReputationProvider looks up the reputation of a pubkey
+type ReputationProvider interface {
+	Reputation(ctx context.Context, pubkey string) (*Reputation, error)
+}
+
+// ReputationTier scales kind-specific rate limits for pubkeys meeting its requirements
+type ReputationTier struct {
+	Name               string   `json:"name"`
+	MinPapers          int      `json:"min_papers,omitempty"`
+	MinReviewsReceived int      `json:"min_reviews_received,omitempty"`
+	MinAccountAge      Duration `json:"min_account_age,omitempty"`
+	// Multiplier is applied to EventsPerWindow and Burst of every kind limit
+	Multiplier float64 `json:"multiplier"`
+}
+
+// qualifies reports whether a reputation meets the tier's requirements
+func (t ReputationTier) qualifies(rep *Reputation, now time.Time) bool {
+	return rep.Papers >= t.MinPapers &&
+		rep.ReviewsReceived >= t.MinReviewsReceived &&
+		rep.AccountAge(now) >= time.Duration(t.MinAccountAge)
+}
+
+// ReputationConfig configures reputation-weighted rate limits
+type ReputationConfig struct {
+	Enabled bool `json:"enabled"`
+	// CacheTTL is how long a computed reputation is reused
+	CacheTTL Duration `json:"cache_ttl"`
+	// Tiers are ordered from lowest to highest; a pubkey gets the last tier
+	// whose requirements it meets. The first tier must have no requirements.
+	Tiers ReputationTiers `json:"tiers"`
+}
+
+// ReputationTiers is the ordered list of tiers
+type ReputationTiers []ReputationTier
+
+// UnmarshalJSON decodes into a fresh slice so tiers in a config file never
+// inherit fields from the default tier at the same position
+func (t *ReputationTiers) UnmarshalJSON(data []byte) error {
+	var tiers []ReputationTier
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&tiers); err != nil {
+		return err
+	}
+	*t = tiers
+	return nil
+}
+
+// DefaultReputationConfig returns the built-in tiers, disabled by default
+func DefaultReputationConfig() ReputationConfig {
+	return ReputationConfig{
+		Enabled:  false,
+		CacheTTL: Duration(10 * time.Minute),
+		Tiers: ReputationTiers{
+			{Name: "new", Multiplier: 0.6},
+			{Name: "established", MinPapers: 3, MinAccountAge: Duration(30 * 24 * time.Hour), Multiplier: 1},
+			{Name: "trusted", MinPapers: 10, MinReviewsReceived: 10, MinAccountAge: Duration(180 * 24 * time.Hour), Multiplier: 3},
+		},
+	}
+}
+
+// validate checks the tiers for values the rate limiter cannot apply
+func (c ReputationConfig) validate() []error {
+	var errs []error
+
+	if c.Enabled && len(c.Tiers) == 0 {
+		errs = append(errs, fmt.Errorf("reputation.tiers: at least one tier is required when enabled"))
+	}
+	if c.CacheTTL < 0 {
+		errs = append(errs, fmt.Errorf("reputation.cache_ttl: must not be negative"))
+	}
+
+	seen := make(map[string]bool)
+	for i, tier := range c.Tiers {
+		if tier.Name == "" {
+			errs = append(errs, fmt.Errorf("reputation.tiers.%d.name: must not be empty", i))
+		} else if seen[tier.Name] {
+			errs = append(errs, fmt.Errorf("reputation.tiers.%d.name: %q listed more than once", i, tier.Name))
+		}
+		seen[tier.Name] = true
+
+		if tier.Multiplier <= 0 {
+			errs = append(errs, fmt.Errorf("reputation.tiers.%d.multiplier: must be positive", i))
+		}
+		if tier.MinPapers < 0 || tier.MinReviewsReceived < 0 || tier.MinAccountAge < 0 {
+			errs = append(errs, fmt.Errorf("reputation.tiers.%d: requirements must not be negative", i))
+		}
+		if i == 0 && (tier.MinPapers > 0 || tier.MinReviewsReceived > 0 || tier.MinAccountAge > 0) {
+			errs = append(errs, fmt.Errorf("reputation.tiers.0: the first tier must have no requirements so every pubkey has a tier"))
+		}
+	}
+
+	return errs
+}
+
+// ReputationLimits scales kind-specific rate limits by the tier of each
+// pubkey. A nil *ReputationLimits leaves limits unchanged.
+type ReputationLimits struct {
+	provider ReputationProvider
+	config   ReputationConfig
+
+	mu    sync.Mutex
+	cache map[string]cachedReputation
+}
+
+type cachedReputation struct {
+	reputation *Reputation
+	expires    time.Time
+}
+
+// maxCachedReputations bounds the cache before expired entries are purged
+const maxCachedReputations = 10000
+
+// NewReputationLimits creates reputation-weighted limits from a provider
+func NewReputationLimits(provider ReputationProvider, config ReputationConfig) *ReputationLimits {
+	return &ReputationLimits{
+		provider: provider,
+		config:   config,
+		cache:    make(map[string]cachedReputation),
+	}
+}
+
+// Reputation returns the pubkey's reputation, reusing recent lookups
+func (r *ReputationLimits) Reputation(ctx context.Context, pubkey string) (*Reputation, error) {
+	now := time.Now()
+
+	r.mu.Lock()
+	cached, ok := r.cache[pubkey]
+	r.mu.Unlock()
+	if ok && now.Before(cached.expires) {
+		return cached.reputation, nil
+	}
+
+	rep, err := r.provider.Reputation(ctx, pubkey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up reputation: %w", err)
+	}
+
+	r.mu.Lock()
+	if len(r.cache) >= maxCachedReputations {
+		for key, entry := range r.cache {
+			if now.After(entry.expires) {
+				delete(r.cache, key)
+			}
+		}
+	}
+	r.cache[pubkey] = cachedReputation{reputation: rep, expires: now.Add(time.Duration(r.config.CacheTTL))}
+	r.mu.Unlock()
+
+	return rep, nil
+}
+
+// Tier returns the highest tier whose requirements the reputation meets
+func (r *ReputationLimits) Tier(rep *Reputation) ReputationTier {
+	tier := ReputationTier{Name: "default", Multiplier: 1}
+	now := time.Now()
+	for _, t := range r.config.Tiers {
+		if t.qualifies(rep, now) {
+			tier = t
+		}
+	}
+	return tier
+}
+
+// Apply returns the kind limits of config scaled for the pubkey's tier
+func (r *ReputationLimits) Apply(ctx context.Context, pubkey string, config *RateLimitConfig) (map[int]KindLimit, error) {
+	if r == nil {
+		return config.KindLimits, nil
+	}
+
+	rep, err := r.Reputation(ctx, pubkey)
+	if err != nil {
+		return nil, err
+	}
+	return ScaleKindLimits(config.KindLimits, r.Tier(rep).Multiplier), nil
+}
+
+// ScaleKindLimits multiplies the events per window and burst of every limit,
+// never going below one event
+func ScaleKindLimits(limits map[int]KindLimit, multiplier float64) map[int]KindLimit {
+	if multiplier == 1 {
+		return limits
+	}
+
+	scaled := make(map[int]KindLimit, len(limits))
+	for kind, limit := range limits {
+		limit.EventsPerWindow = scaleLimit(limit.EventsPerWindow, multiplier)
+		if limit.Burst > 0 {
+			limit.Burst = scaleLimit(limit.Burst, multiplier)
+		}
+		scaled[kind] = limit
+	}
+	return scaled
+}
+
+func scaleLimit(n int, multiplier float64) int {
+	scaled := int(math.Round(float64(n) * multiplier))
+	if scaled < 1 {
+		return 1
+	}
+	return scaled
+}
+
+// ReputationStatus reports a pubkey's reputation, tier and effective limits
+type ReputationStatus struct {
+	Pubkey     string            `json:"pubkey"`
+	Reputation *Reputation       `json:"reputation"`
+	Tier       ReputationTier    `json:"tier"`
+	Limits     map[string]string `json:"limits"`
+}
+
+// Status describes the pubkey's current tier and the limits that apply to it
+func (r *ReputationLimits) Status(ctx context.Context, pubkey string, config *RateLimitConfig) (*ReputationStatus, error) {
+	rep, err := r.Reputation(ctx, pubkey)
+	if err != nil {
+		return nil, err
+	}
+
+	tier := r.Tier(rep)
+	status := &ReputationStatus{
+		Pubkey:     pubkey,
+		Reputation: rep,
+		Tier:       tier,
+		Limits:     make(map[string]string),
+	}
+	for kind, limit := range ScaleKindLimits(config.KindLimits, tier.Multiplier) {
+		status.Limits[EventTypeKey(kind)] = limit.String()
+	}
+	return status, nil
+}
@@ -0,0 +1,106 @@
+package policies
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+type staticReputationProvider struct {
+	reputations map[string]*Reputation
+	lookups     int
+}
+
+func (p *staticReputationProvider) Reputation(ctx context.Context, pubkey string) (*Reputation, error) {
+	p.lookups++
+	if rep, ok := p.reputations[pubkey]; ok {
+		return rep, nil
+	}
+	if pubkey == "unreachable" {
+		return nil, errors.New("connection refused")
+	}
+	return &Reputation{}, nil
+}
+
+func TestReputationWeightedRateLimits(t *testing.T) {
+	ctx := context.Background()
+	provider := &staticReputationProvider{reputations: map[string]*Reputation{
+		"established_researcher": {
+			Papers:          25,
+			ReviewsReceived: 40,
+			FirstSeen:       time.Now().Add(-365 * 24 * time.Hour),
+		},
+	}}
+	reputation := NewReputationLimits(provider, DefaultReputationConfig())
+
+	limiter := NewMemoryRateLimiter(&RateLimitConfig{
+		EventsPerWindow: 100,
+		WindowDuration:  time.Hour,
+		KindLimits: map[int]KindLimit{
+			AcademicPaperKind: {EventsPerWindow: 5, WindowDuration: 24 * time.Hour},
+		},
+	})
+	limiter.SetReputation(reputation)
+
+	submit := func(pubkey string) int {
+		accepted := 0
+		for i := 0; i < 20; i++ {
+			if limiter.AllowRequest(ctx, pubkey, AcademicPaperKind) == nil {
+				accepted++
+			}
+		}
+		return accepted
+	}
+
+	if got := submit("fresh_key"); got != 3 {
+		t.Errorf("Expected fresh keys to be limited to 3 papers, got %d", got)
+	}
+	if got := submit("established_researcher"); got != 15 {
+		t.Errorf("Expected trusted researchers to get 15 papers, got %d", got)
+	}
+	if provider.lookups != 2 {
+		t.Errorf("Expected reputations to be cached, got %d lookups", provider.lookups)
+	}
+
+	if err := limiter.AllowRequest(ctx, "unreachable", AcademicPaperKind); err == nil {
+		t.Error("Expected provider errors to be reported")
+	}
+
+	status, err := reputation.Status(ctx, "established_researcher", limiter.config)
+	if err != nil {
+		t.Fatalf("Status failed: %v", err)
+	}
+	if status.Tier.Name != "trusted" || status.Limits["papers"] != "15 per 24h0m0s" {
+		t.Errorf("Unexpected status: %+v", status)
+	}
+}
+
+func TestReputationTiers(t *testing.T) {
+	reputation := NewReputationLimits(&staticReputationProvider{}, DefaultReputationConfig())
+
+	tests := []struct {
+		name       string
+		reputation Reputation
+		tier       string
+	}{
+		{"new key", Reputation{}, "new"},
+		{"papers but young account", Reputation{Papers: 5, FirstSeen: time.Now().Add(-24 * time.Hour)}, "new"},
+		{"established", Reputation{Papers: 3, FirstSeen: time.Now().Add(-60 * 24 * time.Hour)}, "established"},
+		{"trusted", Reputation{Papers: 10, ReviewsReceived: 10, FirstSeen: time.Now().Add(-200 * 24 * time.Hour)}, "trusted"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := reputation.Tier(&tt.reputation).Name; got != tt.tier {
+				t.Errorf("Expected tier %s, got %s", tt.tier, got)
+			}
+		})
+	}
+
+	// Scaling never drops a limit below one event
+	scaled := ScaleKindLimits(map[int]KindLimit{AcademicPaperKind: {EventsPerWindow: 1, WindowDuration: time.Hour}}, 0.1)
+	if scaled[AcademicPaperKind].EventsPerWindow != 1 {
+		t.Errorf("Expected minimum of 1, got %d", scaled[AcademicPaperKind].EventsPerWindow)
+	}
+}
@@ -3,6 +3,7 @@ package policies
 import (
 	"context"
 	"fmt"
+	"sort"
 
 	"github.com/nbd-wtf/go-nostr"
 )
@@ -11,6 +12,11 @@ import (
 type PaperAuthorStore interface {
 	GetPaperAuthors(ctx context.Context, paperID string) ([]string, error)
 	GetEvent(ctx context.Context, id string) (*nostr.Event, error)
+	// GetVersions returns every event archived at a NIP-33 address, in any order
+	GetVersions(ctx context.Context, kind int, pubkey, d string) ([]*nostr.Event, error)
+	// GetNotices returns the retractions, errata and expressions of concern
+	// that reference a paper by id or address
+	GetNotices(ctx context.Context, paper *nostr.Event) ([]*nostr.Event, error)
 }
 
 // ValidateReviewIntegrity ensures reviews are not from paper authors (conflict of interest)
@@ -29,7 +35,7 @@ func ValidateReviewIntegrity(ctx context.Context, event *nostr.Event, store Pape
 	}
 
 	if paperID == "" {
-		return fmt.Errorf("review integrity check failed: no paper reference found")
+		return policyErrorf(CodeMissingReference, "e", "review integrity check failed: no paper reference found")
 	}
 
 	// Get the paper event
@@ -39,33 +45,36 @@ func ValidateReviewIntegrity(ctx context.Context, event *nostr.Event, store Pape
 	}
 
 	if paperEvent == nil {
-		return fmt.Errorf("review integrity check failed: referenced paper not found")
+		return policyErrorf(CodeUnknownReference, "e", "review integrity check failed: referenced paper not found")
 	}
 
+	var errs []error
+
 	// Check if reviewer is paper author (conflict of interest)
 	if paperEvent.PubKey == event.PubKey {
-		return fmt.Errorf("review integrity violation: authors cannot review their own papers (conflict of interest)")
-	}
-
-	// Check co-authors
-	authors, err := store.GetPaperAuthors(ctx, paperID)
-	if err != nil {
-		// If we can't get authors, extract from paper event
-		authors = extractAuthorsFromEvent(paperEvent)
-	}
+		errs = append(errs, policyErrorf(CodeConflictOfInterest, "pubkey", "review integrity violation: authors cannot review their own papers (conflict of interest)"))
+	} else {
+		// Check co-authors
+		authors, err := store.GetPaperAuthors(ctx, paperID)
+		if err != nil {
+			// If we can't get authors, extract from paper event
+			authors = extractAuthorsFromEvent(paperEvent)
+		}
 
-	for _, authorPubkey := range authors {
-		if authorPubkey == event.PubKey {
-			return fmt.Errorf("review integrity violation: co-authors cannot review their own papers (conflict of interest)")
+		for _, authorPubkey := range authors {
+			if authorPubkey == event.PubKey {
+				errs = append(errs, policyErrorf(CodeConflictOfInterest, "pubkey", "review integrity violation: co-authors cannot review their own papers (conflict of interest)"))
+				break
+			}
 		}
 	}
 
 	// Validate review has substantial content
 	if err := validateReviewQuality(event); err != nil {
-		return err
+		errs = append(errs, err)
 	}
 
-	return nil
+	return joinViolations(errs)
 }
 
 // extractAuthorsFromEvent gets author pubkeys from paper tags
@@ -130,7 +139,8 @@ func validateReviewQuality(event *nostr.Event) error {
 	}
 	
 	if structuredElements < 2 {
-		return fmt.Errorf("review quality insufficient: please include at least 2 of the following: methodology-assessment, strengths, weaknesses, or recommendation tags")
+		return policyErrorf(CodeInsufficientFeedback, "", "review quality insufficient: please include at least 2 of the following: methodology-assessment, strengths, weaknesses, or recommendation tags").
+			withLimit(2, structuredElements)
 	}
 	
 	return nil
@@ -173,6 +183,38 @@ func (s *InMemoryPaperStore) GetPaperAuthors(ctx context.Context, paperID string
 	return authors, nil
 }
 
+// GetVersions returns the stored events at an address
+func (s *InMemoryPaperStore) GetVersions(ctx context.Context, kind int, pubkey, d string) ([]*nostr.Event, error) {
+	var versions []*nostr.Event
+	for _, event := range s.events {
+		if event.Kind != kind || event.PubKey != pubkey {
+			continue
+		}
+		if tag := event.Tags.GetFirst([]string{"d", ""}); tag != nil && (*tag)[1] == d {
+			versions = append(versions, event)
+		}
+	}
+	return versions, nil
+}
+
+// GetNotices returns the stored notices referencing a paper, oldest first
+func (s *InMemoryPaperStore) GetNotices(ctx context.Context, paper *nostr.Event) ([]*nostr.Event, error) {
+	address := EventAddress(paper)
+
+	var notices []*nostr.Event
+	for _, event := range s.events {
+		if !IsNoticeKind(event.Kind) {
+			continue
+		}
+		id, a := PaperReference(event)
+		if id == paper.ID || (address != "" && a == address) {
+			notices = append(notices, event)
+		}
+	}
+	sort.Slice(notices, func(i, j int) bool { return notices[i].CreatedAt < notices[j].CreatedAt })
+	return notices, nil
+}
+
 // StoreEvent stores an event (for testing)
 func (s *InMemoryPaperStore) StoreEvent(event *nostr.Event) {
 	s.events[event.ID] = event
@@ -0,0 +1,313 @@
+package policies
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"github.com/nbd-wtf/go-nostr"
+)
+
+const (
+	// FingerprintSize is the number of MinHash values in a paper fingerprint
+	FingerprintSize = 128
+	// FingerprintBands is the number of locality-sensitive hashing bands a
+	// fingerprint is split into. Papers sharing any band are compared; with 32
+	// bands of 4 values, papers at least 50% similar are found with 87%
+	// probability and papers at least 70% similar almost always.
+	FingerprintBands = 32
+	// MinSimilarityThreshold is the lowest similarity threshold accepted.
+	// Below it, similar papers often share no band and are never compared.
+	MinSimilarityThreshold = 0.5
+	// shingleSize is the number of consecutive words in a shingle
+	shingleSize = 3
+)
+
+// Fingerprint is a MinHash signature of a paper's title and abstract. The
+// share of positions two fingerprints agree on estimates the Jaccard
+// similarity of their word shingles.
+type Fingerprint []uint64
+
+// FingerprintPaper fingerprints the title and abstract of a paper, normalized
+// with NormalizeText as of NormalizationVersion and ignoring punctuation. It
+// returns nil when both are empty.
+func FingerprintPaper(event *nostr.Event) Fingerprint {
+	var text []string
+	for _, tag := range event.Tags {
+		if len(tag) >= 2 && (tag[0] == "title" || tag[0] == "abstract") {
+			text = append(text, tag[1])
+		}
+	}
+
+	shingles := wordShingles(strings.Join(text, " "))
+	if len(shingles) == 0 {
+		return nil
+	}
+
+	fingerprint := make(Fingerprint, FingerprintSize)
+	for i := range fingerprint {
+		fingerprint[i] = ^uint64(0)
+	}
+	for _, shingle := range shingles {
+		for i := range fingerprint {
+			// Each position uses a differently seeded hash of the shingle
+			if h := mix64(shingle ^ uint64(i+1)*0x9e3779b97f4a7c15); h < fingerprint[i] {
+				fingerprint[i] = h
+			}
+		}
+	}
+	return fingerprint
+}
+
+// wordShingles hashes each run of shingleSize consecutive words of the
+// normalized text. Text shorter than a shingle becomes a single shingle.
+func wordShingles(text string) []uint64 {
+	words := strings.FieldsFunc(NormalizeText(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	if len(words) == 0 {
+		return nil
+	}
+
+	size := shingleSize
+	if len(words) < size {
+		size = len(words)
+	}
+	shingles := make([]uint64, 0, len(words)-size+1)
+	for i := 0; i+size <= len(words); i++ {
+		hasher := fnv.New64a()
+		hasher.Write([]byte(strings.Join(words[i:i+size], " ")))
+		shingles = append(shingles, hasher.Sum64())
+	}
+	return shingles
+}
+
+// mix64 is the splitmix64 finalizer
+func mix64(x uint64) uint64 {
+	x ^= x >> 30
+	x *= 0xbf58476d1ce4e5b9
+	x ^= x >> 27
+	x *= 0x94d049bb133111eb
+	x ^= x >> 31
+	return x
+}
+
+// Similarity estimates how similar two fingerprinted papers are, from 0 to 1
+func (f Fingerprint) Similarity(other Fingerprint) float64 {
+	if len(f) == 0 || len(f) != len(other) {
+		return 0
+	}
+	same := 0
+	for i := range f {
+		if f[i] == other[i] {
+			same++
+		}
+	}
+	return float64(same) / float64(len(f))
+}
+
+// Bands hashes each locality-sensitive hashing band of the fingerprint
+func (f Fingerprint) Bands() []uint64 {
+	if len(f) != FingerprintSize {
+		return nil
+	}
+	rows := FingerprintSize / FingerprintBands
+	bands := make([]uint64, FingerprintBands)
+	for band := range bands {
+		hasher := fnv.New64a()
+		hasher.Write(f[band*rows : (band+1)*rows].Bytes())
+		bands[band] = hasher.Sum64()
+	}
+	return bands
+}
+
+// Bytes encodes the fingerprint for storage
+func (f Fingerprint) Bytes() []byte {
+	data := make([]byte, 8*len(f))
+	for i, value := range f {
+		binary.BigEndian.PutUint64(data[8*i:], value)
+	}
+	return data
+}
+
+// ParseFingerprint decodes a fingerprint encoded with Bytes
+func ParseFingerprint(data []byte) (Fingerprint, error) {
+	if len(data) != 8*FingerprintSize {
+		return nil, fmt.Errorf("fingerprint must be %d bytes, got %d", 8*FingerprintSize, len(data))
+	}
+	fingerprint := make(Fingerprint, FingerprintSize)
+	for i := range fingerprint {
+		fingerprint[i] = binary.BigEndian.Uint64(data[8*i:])
+	}
+	return fingerprint, nil
+}
+
+// StoredFingerprint is the fingerprint of an archived paper
+type StoredFingerprint struct {
+	EventID     string
+	Address     string
+	Fingerprint Fingerprint
+	StoredAt    time.Time
+}
+
+// FingerprintStore keeps paper fingerprints
+type FingerprintStore interface {
+	StoreFingerprint(ctx context.Context, stored StoredFingerprint) error
+	// FingerprintCandidates returns the stored fingerprints sharing at least
+	// one band with a fingerprint
+	FingerprintCandidates(ctx context.Context, fingerprint Fingerprint) ([]StoredFingerprint, error)
+}
+
+// NearDuplicateChecker extends an exact duplicate checker with papers whose
+// title and abstract are at least threshold similar to an archived paper, so
+// that changing a comma or a word does not get a paper past the check. As
+// with exact duplicates, earlier versions at the same address are ignored.
+type NearDuplicateChecker struct {
+	exact     DuplicateChecker
+	store     FingerprintStore
+	threshold float64
+}
+
+// NewNearDuplicateChecker creates a near-duplicate checker. A nil store keeps
+// fingerprints in memory.
+func NewNearDuplicateChecker(exact DuplicateChecker, store FingerprintStore, threshold float64) *NearDuplicateChecker {
+	if store == nil {
+		store = NewInMemoryFingerprintStore()
+	}
+	return &NearDuplicateChecker{exact: exact, store: store, threshold: threshold}
+}
+
+// Threshold returns the similarity at which papers are near-duplicates
+func (c *NearDuplicateChecker) Threshold() float64 {
+	return c.threshold
+}
+
+// IsDuplicate returns the exact duplicates of an event or, when it has none,
+// the papers it is a near-duplicate of
+func (c *NearDuplicateChecker) IsDuplicate(ctx context.Context, event *nostr.Event) ([]DuplicateMatch, error) {
+	if matches, err := c.exact.IsDuplicate(ctx, event); err != nil || len(matches) > 0 {
+		return matches, err
+	}
+	return c.FindSimilar(ctx, event)
+}
+
+// StoreHash stores the content hash and, for papers, the fingerprint
+func (c *NearDuplicateChecker) StoreHash(ctx context.Context, event *nostr.Event, hash string) error {
+	if err := c.exact.StoreHash(ctx, event, hash); err != nil {
+		return err
+	}
+	if event.Kind != AcademicPaperKind {
+		return nil
+	}
+	fingerprint := FingerprintPaper(event)
+	if fingerprint == nil {
+		return nil
+	}
+	return c.store.StoreFingerprint(ctx, StoredFingerprint{
+		EventID:     event.ID,
+		Address:     EventAddress(event),
+		Fingerprint: fingerprint,
+		StoredAt:    time.Now(),
+	})
+}
+
+// FindSimilar returns the archived papers at other addresses that are at
+// least threshold similar to a paper, most similar first
+func (c *NearDuplicateChecker) FindSimilar(ctx context.Context, event *nostr.Event) ([]DuplicateMatch, error) {
+	if event.Kind != AcademicPaperKind {
+		return nil, nil
+	}
+	fingerprint := FingerprintPaper(event)
+	if fingerprint == nil {
+		return nil, nil
+	}
+
+	candidates, err := c.store.FingerprintCandidates(ctx, fingerprint)
+	if err != nil {
+		return nil, err
+	}
+
+	address := EventAddress(event)
+	var matches []DuplicateMatch
+	for _, candidate := range candidates {
+		if address != "" && candidate.Address == address {
+			continue
+		}
+		if score := fingerprint.Similarity(candidate.Fingerprint); score >= c.threshold {
+			matches = append(matches, DuplicateMatch{
+				EventID:  candidate.EventID,
+				Address:  candidate.Address,
+				Score:    score,
+				StoredAt: candidate.StoredAt,
+			})
+		}
+	}
+	sort.Slice(matches, func(i, j int) bool {
+		if matches[i].Score != matches[j].Score {
+			return matches[i].Score > matches[j].Score
+		}
+		return matches[i].EventID < matches[j].EventID
+	})
+	return matches, nil
+}
+
+// InMemoryFingerprintStore keeps fingerprints in memory, indexed by band
+type InMemoryFingerprintStore struct {
+	mu           sync.RWMutex
+	fingerprints map[string]StoredFingerprint
+	// bands maps a band number and hash to the ids of the papers sharing it
+	bands map[[2]uint64][]string
+}
+
+// NewInMemoryFingerprintStore creates an empty fingerprint store
+func NewInMemoryFingerprintStore() *InMemoryFingerprintStore {
+	return &InMemoryFingerprintStore{
+		fingerprints: make(map[string]StoredFingerprint),
+		bands:        make(map[[2]uint64][]string),
+	}
+}
+
+// StoreFingerprint stores a paper's fingerprint
+func (s *InMemoryFingerprintStore) StoreFingerprint(ctx context.Context, stored StoredFingerprint) error {
+	bands := stored.Fingerprint.Bands()
+	if bands == nil {
+		return errors.New("cannot store a fingerprint of the wrong size")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.fingerprints[stored.EventID]; ok {
+		return nil
+	}
+	s.fingerprints[stored.EventID] = stored
+	for band, hash := range bands {
+		key := [2]uint64{uint64(band), hash}
+		s.bands[key] = append(s.bands[key], stored.EventID)
+	}
+	return nil
+}
+
+// FingerprintCandidates returns the stored fingerprints sharing a band
+func (s *InMemoryFingerprintStore) FingerprintCandidates(ctx context.Context, fingerprint Fingerprint) ([]StoredFingerprint, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	seen := make(map[string]bool)
+	var candidates []StoredFingerprint
+	for band, hash := range fingerprint.Bands() {
+		for _, id := range s.bands[[2]uint64{uint64(band), hash}] {
+			if !seen[id] {
+				seen[id] = true
+				candidates = append(candidates, s.fingerprints[id])
+			}
+		}
+	}
+	return candidates, nil
+}
@@ -0,0 +1,98 @@
+package policies
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/nbd-wtf/go-nostr"
+)
+
+const similarityAbstract = "We measure how consensus latency in permissioned blockchains grows with the number of validators, " +
+	"and show that batching proposals keeps commit times flat up to two hundred nodes across three regions."
+
+func similarityPaper(id, pubkey, d, title, abstract string) *nostr.Event {
+	return &nostr.Event{
+		ID:     id,
+		PubKey: pubkey,
+		Kind:   AcademicPaperKind,
+		Tags: nostr.Tags{
+			{"d", d},
+			{"title", title},
+			{"abstract", abstract},
+			{"author", "Jane Doe"},
+		},
+	}
+}
+
+func TestFingerprintSimilarity(t *testing.T) {
+	original := FingerprintPaper(similarityPaper("a", "author", "p", "Scaling Consensus in Permissioned Blockchains", similarityAbstract))
+
+	tests := []struct {
+		name     string
+		title    string
+		abstract string
+		min, max float64
+	}{
+		{"punctuation and case only", "Scaling consensus, in permissioned blockchains!", strings.ToUpper(similarityAbstract), 1, 1},
+		{"latex markup", `Scaling \textbf{Consensus} in Permissioned Blockchains`,
+			strings.Replace(similarityAbstract, "two hundred", `two~hundred`, 1), 1, 1},
+		{"one word changed", "Scaling Consensus in Permissioned Blockchains",
+			strings.Replace(similarityAbstract, "three regions", "four regions", 1), 0.8, 1},
+		{"unrelated paper", "Soil Microbiome Diversity After Wildfires",
+			"Soil samples from burned and unburned plots show a lasting shift in fungal diversity over five years of recovery.", 0, 0.1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			other := FingerprintPaper(similarityPaper("b", "author", "q", tt.title, tt.abstract))
+			score := original.Similarity(other)
+			if score < tt.min || score > tt.max {
+				t.Errorf("Expected similarity between %.2f and %.2f, got %.2f", tt.min, tt.max, score)
+			}
+		})
+	}
+
+	parsed, err := ParseFingerprint(original.Bytes())
+	if err != nil || original.Similarity(parsed) != 1 {
+		t.Errorf("Expected the fingerprint to survive encoding, got %v", err)
+	}
+	if FingerprintPaper(&nostr.Event{Kind: AcademicPaperKind}) != nil {
+		t.Error("Expected no fingerprint for a paper without title or abstract")
+	}
+}
+
+func TestNearDuplicateChecker(t *testing.T) {
+	ctx := context.Background()
+	checker := NewNearDuplicateChecker(NewInMemoryDuplicateChecker(), nil, 0.8)
+
+	original := similarityPaper("original", "author", "consensus", "Scaling Consensus in Permissioned Blockchains", similarityAbstract)
+	if err := checker.StoreHash(ctx, original, ""); err != nil {
+		t.Fatalf("Failed to store hash: %v", err)
+	}
+
+	edited := strings.Replace(similarityAbstract, "three regions", "four regions", 1)
+	err := PreventDuplicatePapers(ctx, similarityPaper("copy", "plagiarist", "consensus", "Scaling Consensus in Permissioned Blockchains", edited), checker)
+	var policyErr *PolicyError
+	if !errors.As(err, &policyErr) || policyErr.Code != CodeDuplicate {
+		t.Fatalf("Expected a near-duplicate to be rejected, got: %v", err)
+	}
+	if !strings.Contains(err.Error(), "original") || !strings.Contains(err.Error(), "% similar") {
+		t.Errorf("Expected the rejection to name the original and its score, got: %v", err)
+	}
+
+	matches, err := checker.FindSimilar(ctx, similarityPaper("copy", "plagiarist", "consensus", "Scaling Consensus in Permissioned Blockchains", edited))
+	if err != nil || len(matches) != 1 || matches[0].EventID != "original" || matches[0].Score < 0.8 {
+		t.Errorf("Expected one match for the original, got %+v (%v)", matches, err)
+	}
+
+	if err := PreventDuplicatePapers(ctx, similarityPaper("v2", "author", "consensus", "Scaling Consensus in Permissioned Blockchains", edited), checker); err != nil {
+		t.Errorf("A revision at the same address should not be a near-duplicate: %v", err)
+	}
+	unrelated := similarityPaper("soil", "author", "soil", "Soil Microbiome Diversity After Wildfires",
+		"Soil samples from burned and unburned plots show a lasting shift in fungal diversity over five years of recovery.")
+	if err := PreventDuplicatePapers(ctx, unrelated, checker); err != nil {
+		t.Errorf("An unrelated paper should be accepted: %v", err)
+	}
+}
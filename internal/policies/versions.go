@@ -0,0 +1,122 @@
+package policies
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/nbd-wtf/go-nostr"
+)
+
+// EventAddress returns the NIP-33 address of an academic event, or "" when it
+// has no d tag. Every event published at the same address is a new version of
+// the same paper, review or dataset.
+func EventAddress(event *nostr.Event) string {
+	d := event.Tags.GetFirst([]string{"d", ""})
+	if d == nil {
+		return ""
+	}
+	return fmt.Sprintf("%d:%s:%s", event.Kind, event.PubKey, (*d)[1])
+}
+
+// SortVersions orders the events at one address oldest first. Events with the
+// same timestamp are ordered as NIP-01 resolves them, the lowest id being the
+// newer one.
+func SortVersions(events []*nostr.Event) {
+	sort.Slice(events, func(i, j int) bool {
+		if events[i].CreatedAt != events[j].CreatedAt {
+			return events[i].CreatedAt < events[j].CreatedAt
+		}
+		return events[i].ID > events[j].ID
+	})
+}
+
+// Version is one published version of an addressable academic event
+type Version struct {
+	Number    int       `json:"version"`
+	ID        string    `json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// VersionHistory lists every version archived at an address. The archive
+// keeps superseded versions rather than replacing them as NIP-33 relays do.
+type VersionHistory struct {
+	Address  string    `json:"address"`
+	Latest   string    `json:"latest"`
+	Versions []Version `json:"versions"`
+}
+
+// NewVersionHistory numbers the events at an address from 1, oldest first
+func NewVersionHistory(address string, events []*nostr.Event) *VersionHistory {
+	SortVersions(events)
+
+	history := &VersionHistory{Address: address, Versions: []Version{}}
+	for i, event := range events {
+		history.Versions = append(history.Versions, Version{
+			Number:    i + 1,
+			ID:        event.ID,
+			CreatedAt: event.CreatedAt.Time(),
+		})
+		history.Latest = event.ID
+	}
+	return history
+}
+
+// GetVersionHistory looks up the versions archived at an address. It returns
+// nil when the address is malformed or nothing has been published at it.
+func GetVersionHistory(ctx context.Context, store PaperAuthorStore, address string) (*VersionHistory, error) {
+	kind, pubkey, d, ok := ParseAddress(address)
+	if !ok || !IsAcademicKind(kind) {
+		return nil, nil
+	}
+
+	events, err := store.GetVersions(ctx, kind, pubkey, d)
+	if err != nil {
+		return nil, err
+	}
+	if len(events) == 0 {
+		return nil, nil
+	}
+	return NewVersionHistory(address, events), nil
+}
+
+// VersionAt returns the version that was current at a point in time: the
+// newest one created at or before it, or nil when the address had no version
+// yet
+func VersionAt(events []*nostr.Event, at nostr.Timestamp) *nostr.Event {
+	SortVersions(events)
+
+	var current *nostr.Event
+	for _, event := range events {
+		if event.CreatedAt > at {
+			break
+		}
+		current = event
+	}
+	return current
+}
+
+// GetVersionAt looks up the version current at an address at a point in time
+func GetVersionAt(ctx context.Context, store PaperAuthorStore, address string, at nostr.Timestamp) (*nostr.Event, error) {
+	kind, pubkey, d, ok := ParseAddress(address)
+	if !ok || !IsAcademicKind(kind) {
+		return nil, nil
+	}
+
+	events, err := store.GetVersions(ctx, kind, pubkey, d)
+	if err != nil {
+		return nil, err
+	}
+	return VersionAt(events, at), nil
+}
+
+// latestVersion returns the current version at an address, or nil
+func latestVersion(ctx context.Context, store PaperAuthorStore, kind int, pubkey, d string) (*nostr.Event, error) {
+	events, err := store.GetVersions(ctx, kind, pubkey, d)
+	if err != nil || len(events) == 0 {
+		return nil, err
+	}
+	SortVersions(events)
+	return events[len(events)-1], nil
+}
@@ -0,0 +1,96 @@
+package policies
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/nbd-wtf/go-nostr"
+)
+
+func TestVersionHistory(t *testing.T) {
+	author := strings.Repeat("a", 64)
+
+	store := NewInMemoryPaperStore()
+	version := func(id string, createdAt nostr.Timestamp, d string) *nostr.Event {
+		event := &nostr.Event{
+			ID:        id,
+			PubKey:    author,
+			Kind:      AcademicPaperKind,
+			CreatedAt: createdAt,
+			Tags:      nostr.Tags{{"d", d}},
+		}
+		store.StoreEvent(event)
+		return event
+	}
+	v2 := version("version_2", 200, "consensus-study")
+	version("version_1", 100, "consensus-study")
+	version("other_paper", 300, "other-study")
+
+	engine := NewPolicyEngineWithConfig(nil, nil, nil, store)
+	ctx := context.Background()
+
+	history, err := engine.VersionHistory(ctx, EventAddress(v2))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if history == nil || len(history.Versions) != 2 {
+		t.Fatalf("Expected two versions, got %+v", history)
+	}
+	if history.Versions[0].ID != "version_1" || history.Versions[0].Number != 1 {
+		t.Errorf("Expected the oldest version first, got %+v", history.Versions[0])
+	}
+	if history.Latest != "version_2" || history.Versions[1].Number != 2 {
+		t.Errorf("Expected version_2 to be the latest, got %+v", history)
+	}
+
+	// Notices addressing the paper resolve to its latest version
+	latest, err := ResolvePaper(ctx, &nostr.Event{Tags: nostr.Tags{{"a", EventAddress(v2)}}}, store)
+	if err != nil || latest == nil || latest.ID != "version_2" {
+		t.Errorf("Expected the address to resolve to version_2, got %v (%v)", latest, err)
+	}
+
+	for _, tt := range []struct {
+		at   nostr.Timestamp
+		want string
+	}{
+		{50, ""},
+		{100, "version_1"},
+		{199, "version_1"},
+		{250, "version_2"},
+	} {
+		event, err := engine.VersionAt(ctx, EventAddress(v2), tt.at)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		got := ""
+		if event != nil {
+			got = event.ID
+		}
+		if got != tt.want {
+			t.Errorf("Expected %q at %d, got %q", tt.want, tt.at, got)
+		}
+	}
+
+	for _, address := range []string{
+		"31428:" + author + ":missing",
+		"1:" + author + ":consensus-study",
+		"not-an-address",
+	} {
+		if history, _ := engine.VersionHistory(ctx, address); history != nil {
+			t.Errorf("Expected no history for %q, got %+v", address, history)
+		}
+	}
+}
+
+func TestSortVersionsBreaksTiesByID(t *testing.T) {
+	events := []*nostr.Event{
+		{ID: "aaaa", CreatedAt: 100},
+		{ID: "bbbb", CreatedAt: 100},
+	}
+	SortVersions(events)
+	// NIP-01 keeps the lowest id when timestamps are equal, so it is the newer version
+	if events[1].ID != "aaaa" {
+		t.Errorf("Expected the lowest id to be the latest version, got %s", events[1].ID)
+	}
+}